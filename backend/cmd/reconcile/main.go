@@ -0,0 +1,68 @@
+// Command reconcile checks the like, upvote, view and comment counters on
+// posts, reels and comments against what they count, and with -fix corrects
+// the ones out of step. Results are printed and recorded in the moderation
+// log, like the scheduled reconciliation.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/devthreads/backend/config"
+	"github.com/devthreads/backend/internal/database"
+	"github.com/devthreads/backend/internal/reconcile"
+	"github.com/devthreads/backend/internal/repository"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "correct the counters that are out of step")
+	batch := flag.Int("batch", 0, "documents to check at a time (default COUNTER_RECONCILE_BATCH)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+	cfg := config.Load()
+	if *batch <= 0 {
+		*batch = cfg.CounterReconcileBatch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	db, err := database.Connect(ctx, cfg.MongoURI)
+	cancel()
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer db.Disconnect(context.Background())
+
+	reconciler := reconcile.New(
+		repository.NewPostRepository(db.DB),
+		repository.NewReelRepository(db.DB),
+		repository.NewCommentRepository(db.DB),
+		repository.NewEngagementRepository(db.DB),
+		repository.NewModerationLogRepository(db.DB),
+		*batch,
+	)
+
+	report, err := reconciler.Run(context.Background(), *fix)
+	if err != nil {
+		log.Printf("Reconciliation failed: %v", err)
+	}
+
+	for _, d := range report.Discrepancies {
+		fmt.Printf("%s %s %s: stored %d, counted %d\n", d.Target, d.ID.Hex(), d.Field, d.Stored, d.Actual)
+	}
+	if report.Found > len(report.Discrepancies) {
+		fmt.Printf("... and %d more\n", report.Found-len(report.Discrepancies))
+	}
+	fmt.Println(report.Summary())
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
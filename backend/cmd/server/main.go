@@ -4,7 +4,7 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
@@ -13,14 +13,24 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/devthreads/backend/config"
 	"github.com/devthreads/backend/graph/generated"
+	"github.com/devthreads/backend/graph/model"
 	"github.com/devthreads/backend/graph/resolver"
 	"github.com/devthreads/backend/internal/auth"
 	"github.com/devthreads/backend/internal/database"
+	"github.com/devthreads/backend/internal/jobs"
+	"github.com/devthreads/backend/internal/lockout"
+	"github.com/devthreads/backend/internal/mailer"
 	"github.com/devthreads/backend/internal/middleware"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// githubStateCookie holds the nonce of the pending GitHub OAuth state
+	githubStateCookie = "github_oauth_state"
 )
 
 func main() {
@@ -31,6 +41,9 @@ func main() {
 
 	// Initialize configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	// Connect to MongoDB
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -45,18 +58,128 @@ func main() {
 	log.Println("Connected to MongoDB successfully")
 
 	// Initialize services
-	authService := auth.NewService(cfg.JWTSecret, cfg.JWTAccessExpiry, cfg.JWTRefreshExpiry)
+	var signingKeys *auth.KeySet
+	if cfg.JWTKeysDir != "" {
+		signingKeys, err = auth.LoadKeySet(cfg.JWTKeysDir, cfg.JWTActiveKeyID)
+		if err != nil {
+			log.Fatalf("Failed to load JWT signing keys: %v", err)
+		}
+		log.Printf("Signing access tokens with key %s", cfg.JWTActiveKeyID)
+	}
+
+	authService := auth.NewService(cfg.JWTSecret, signingKeys, cfg.JWTAccessExpiry, cfg.JWTRefreshExpiry)
+
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	// Login lockout counters are shared through Redis when it is configured
+	var lockoutStore lockout.Store = lockout.NewMemoryStore()
+	if cfg.RedisURL != "" {
+		redisOptions, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		redisClient := redis.NewClient(redisOptions)
+		defer redisClient.Close()
+
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		log.Println("Connected to Redis successfully")
+
+		lockoutStore = lockout.NewRedisStore(redisClient)
+	}
 
 	// Initialize resolver with dependencies
-	resolverRoot := &resolver.Resolver{
-		DB:          db,
-		AuthService: authService,
-		Config:      cfg,
+	resolverRoot := resolver.NewResolver(db, authService, mail, lockoutStore, cfg)
+
+	if err := resolverRoot.RefreshTokenRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create refresh token indexes: %v", err)
+	}
+	if err := resolverRoot.UserRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create user indexes: %v", err)
+	}
+	if err := resolverRoot.AccountTokenRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create account token indexes: %v", err)
+	}
+	if err := resolverRoot.PersonalAccessTokenRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create personal access token indexes: %v", err)
+	}
+	if err := resolverRoot.SessionRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create session indexes: %v", err)
+	}
+	if err := resolverRoot.SecurityEventRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create security event indexes: %v", err)
+	}
+	if err := resolverRoot.RoleRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create role indexes: %v", err)
+	}
+	if err := resolverRoot.NotificationRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create notification indexes: %v", err)
+	}
+	if err := resolverRoot.DataExportRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create data export indexes: %v", err)
+	}
+	if err := resolverRoot.PostRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create post indexes: %v", err)
+	}
+	if err := resolverRoot.PostRevisionRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create post revision indexes: %v", err)
+	}
+	if err := resolverRoot.ReelRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create reel indexes: %v", err)
+	}
+	if err := resolverRoot.CommentRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create comment indexes: %v", err)
+	}
+	if err := resolverRoot.ModerationLogRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create moderation log indexes: %v", err)
+	}
+	if err := resolverRoot.EngagementRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create engagement indexes: %v", err)
+	}
+	if err := resolverRoot.FollowRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create follow indexes: %v", err)
+	}
+	if err := resolverRoot.TimelineRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create timeline indexes: %v", err)
+	}
+	if err := resolverRoot.TrendingRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatalf("Failed to create trending indexes: %v", err)
+	}
+	if err := resolverRoot.UserRepo.MigrateRoles(ctx); err != nil {
+		log.Fatalf("Failed to migrate user roles: %v", err)
+	}
+
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.Start(jobsCtx,
+		resolverRoot.DataExportJob(),
+		resolverRoot.AccountPurgeJob(),
+		resolverRoot.HotScoreJob(),
+		resolverRoot.ContentPurgeJob(),
+	)
+	if cfg.CounterReconcileInterval > 0 {
+		jobs.Start(jobsCtx, resolverRoot.CounterReconcileJob())
+	}
+
+	// Origins allowed to call the API from a browser
+	allowedOrigins := map[string]bool{cfg.FrontendURL: true}
+	if cfg.Environment == "development" {
+		allowedOrigins["http://localhost:3000"] = true
 	}
 
 	// Create GraphQL server
 	srv := handler.New(generated.NewExecutableSchema(generated.Config{
 		Resolvers: resolverRoot,
+		Directives: generated.DirectiveRoot{
+			Auth:          resolverRoot.AuthDirective,
+			HasRole:       resolverRoot.HasRoleDirective,
+			HasPermission: resolverRoot.HasPermissionDirective,
+		},
 	}))
 
 	// Add transports
@@ -66,9 +189,13 @@ func main() {
 		KeepAlivePingInterval: 10 * time.Second,
 		Upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for development
+				// Browsers always send an Origin; other clients cannot be
+				// used for cross-site hijacking
+				origin := r.Header.Get("Origin")
+				return origin == "" || allowedOrigins[origin]
 			},
 		},
+		InitFunc: middleware.WebsocketInitFunc(authService, resolverRoot.PersonalAccessTokenRepo, resolverRoot.UserState, resolverRoot.SessionState, cfg.UserStateCacheTTL),
 	})
 
 	// Add extensions
@@ -80,9 +207,17 @@ func main() {
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Client IPs feed the login lockout, so only trust forwarding headers
+	// from known proxies
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	r.Use(middleware.ClientInfoMiddleware())
+
 	// CORS configuration
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{cfg.FrontendURL, "http://localhost:3000"},
+		AllowOriginFunc:  func(origin string) bool { return allowedOrigins[origin] },
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
@@ -95,22 +230,94 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok", "timestamp": time.Now().Unix()})
 	})
 
+	// Public keys for verifying access tokens in other services
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, authService.JWKS())
+	})
+
+	// Data export downloads. The link is sent to the user as a notification
+	// and carries a token instead of requiring a session.
+	r.GET("/exports/:id", func(c *gin.Context) {
+		path, err := resolverRoot.DataExportFile(c.Request.Context(), c.Param("id"), c.Query("token"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "export not found or expired"})
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.FileAttachment(path, "devthreads-export.zip")
+	})
+
 	// GitHub OAuth routes
+	secureCookies := cfg.Environment == "production"
+
 	r.GET("/auth/github", func(c *gin.Context) {
-		url := authService.GetGithubOAuthURL(cfg.GithubClientID, cfg.GithubRedirectURL)
-		c.Redirect(http.StatusTemporaryRedirect, url)
+		state, nonce, err := authService.GenerateOAuthState("")
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to start GitHub login"})
+			return
+		}
+
+		// Bind the state to this browser so a callback started elsewhere is rejected
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(githubStateCookie, nonce, int((10 * time.Minute).Seconds()), "/auth/github", "", secureCookies, true)
+
+		c.Redirect(http.StatusTemporaryRedirect, resolverRoot.GithubOAuth.AuthCodeURL(state))
 	})
 
 	r.GET("/auth/github/callback", func(c *gin.Context) {
+		callbackURL := cfg.FrontendURL + "/auth/callback#"
+
 		code := c.Query("code")
-		if code == "" {
-			c.JSON(400, gin.H{"error": "code parameter is required"})
+		state := c.Query("state")
+		if code == "" || state == "" {
+			c.Redirect(http.StatusTemporaryRedirect, callbackURL+url.Values{"error": {"missing code or state"}}.Encode())
 			return
 		}
 
-		// Handle GitHub OAuth callback
-		// This will be implemented in the resolver
-		c.Redirect(http.StatusTemporaryRedirect, cfg.FrontendURL+"/auth/callback?code="+code)
+		parsed, err := authService.ParseOAuthState(state)
+		if err != nil {
+			c.Redirect(http.StatusTemporaryRedirect, callbackURL+url.Values{"error": {"invalid state"}}.Encode())
+			return
+		}
+
+		// States from githubLinkUrl are issued to a user, and linkGithub only
+		// accepts them from that user's session
+		if parsed.UserID != "" {
+			c.Redirect(http.StatusTemporaryRedirect, cfg.FrontendURL+"/settings/github#"+url.Values{
+				"code":  {code},
+				"state": {state},
+			}.Encode())
+			return
+		}
+
+		nonce, _ := c.Cookie(githubStateCookie)
+		c.SetCookie(githubStateCookie, "", -1, "/auth/github", "", secureCookies, true)
+		verified, err := authService.VerifyOAuthState(state, nonce)
+		if err != nil {
+			c.Redirect(http.StatusTemporaryRedirect, callbackURL+url.Values{"error": {"invalid state"}}.Encode())
+			return
+		}
+
+		result, err := resolverRoot.LoginWithGithub(c.Request.Context(), code, verified)
+		if err != nil {
+			log.Printf("GitHub login failed: %v", err)
+			c.Redirect(http.StatusTemporaryRedirect, callbackURL+url.Values{"error": {"github login failed"}}.Encode())
+			return
+		}
+
+		// Tokens travel in the fragment so they never reach server logs
+		switch payload := result.(type) {
+		case *model.AuthPayload:
+			c.Redirect(http.StatusTemporaryRedirect, callbackURL+url.Values{
+				"access_token":  {payload.AccessToken},
+				"refresh_token": {payload.RefreshToken},
+			}.Encode())
+		case *model.MfaChallenge:
+			c.Redirect(http.StatusTemporaryRedirect, callbackURL+url.Values{
+				"mfa_challenge": {payload.ChallengeToken},
+			}.Encode())
+		}
 	})
 
 	// GraphQL playground (development only)
@@ -120,8 +327,9 @@ func main() {
 	}
 
 	// GraphQL endpoint with authentication middleware
-	r.POST("/graphql", middleware.AuthMiddleware(authService), gin.WrapH(srv))
-	r.GET("/graphql", middleware.AuthMiddleware(authService), gin.WrapH(srv))
+	authMiddleware := middleware.AuthMiddleware(authService, resolverRoot.PersonalAccessTokenRepo, resolverRoot.UserState, resolverRoot.SessionState)
+	r.POST("/graphql", authMiddleware, gin.WrapH(srv))
+	r.GET("/graphql", authMiddleware, gin.WrapH(srv))
 
 	// Start server
 	port := cfg.Port
@@ -1,10 +1,25 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// DefaultJWTSecret is the placeholder secret used when JWT_SECRET is unset
+const DefaultJWTSecret = "your-super-secret-jwt-key"
+
+// placeholderJWTSecrets are published in this repository's defaults and
+// examples, so the server refuses to run with them in production
+var placeholderJWTSecrets = map[string]bool{
+	DefaultJWTSecret: true,
+	"your-super-secret-jwt-key-change-this-in-production": true,
+	"your-super-secret-jwt-key-change-in-production":      true,
+}
+
 type Config struct {
 	// Server
 	Port        string
@@ -18,11 +33,24 @@ type Config struct {
 	JWTSecret        string
 	JWTAccessExpiry  time.Duration
 	JWTRefreshExpiry time.Duration
+	// Asymmetric access token signing. When JWTKeysDir is empty access
+	// tokens are signed with HS256 and JWTSecret.
+	JWTKeysDir     string
+	JWTActiveKeyID string
+
+	// How long a user's ban, role and token version, and whether a session is
+	// still active, are cached per instance
+	UserStateCacheTTL time.Duration
+
+	// Admin accounts must sign in with two-factor authentication
+	RequireAdminMFA bool
 
 	// GitHub OAuth
 	GithubClientID     string
 	GithubClientSecret string
 	GithubRedirectURL  string
+	GithubOAuthURL     string
+	GithubAPIURL       string
 
 	// Cloudinary
 	CloudinaryCloudName string
@@ -32,12 +60,84 @@ type Config struct {
 	// Frontend
 	FrontendURL string
 
+	// Public base URL of this server, used in links to it such as data
+	// export downloads
+	PublicURL string
+
+	// Account data. Export archives are written to DataExportDir and can be
+	// downloaded for DataExportExpiry; deleted accounts are purged after
+	// AccountDeletionGrace.
+	DataExportDir        string
+	DataExportExpiry     time.Duration
+	AccountDeletionGrace time.Duration
+
+	// Deleted posts, reels and comments can be restored by their author for
+	// ContentUndoWindow and are purged for good after ContentRetention
+	ContentUndoWindow time.Duration
+	ContentRetention  time.Duration
+
+	// Proxies whose X-Forwarded-For header is trusted for client IPs. Empty
+	// means the connection's remote address is always used.
+	TrustedProxies []string
+
+	// Mail. The log and file drivers write reset and verification links
+	// where anyone reading them can use them, so production requires smtp.
+	MailDriver   string
+	MailFrom     string
+	MailFilePath string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
 	// Redis (optional)
 	RedisURL string
 
 	// Rate Limiting
 	RateLimitRequests int
 	RateLimitDuration time.Duration
+
+	// Login lockout. Accounts and IPs are locked after too many failures
+	// within the window, for BaseLock doubling with each further failure.
+	LoginAccountMaxFailures int
+	LoginIPMaxFailures      int
+	LoginFailureWindow      time.Duration
+	LoginLockoutBase        time.Duration
+	LoginLockoutMax         time.Duration
+
+	// Trending. Hot scores weigh engagement against age with these weights
+	// and gravity, and are refreshed every TrendingRefreshInterval. The
+	// windows are how far back the TODAY, WEEK and MONTH rankings reach.
+	// Each refresh snapshots the ranking; TRENDING cursors keep working for
+	// TrendingSnapshotRetention after their snapshot was replaced.
+	TrendingLikeWeight        float64
+	TrendingUpvoteWeight      float64
+	TrendingCommentWeight     float64
+	TrendingViewWeight        float64
+	TrendingGravity           float64
+	TrendingRefreshInterval   time.Duration
+	TrendingWindowToday       time.Duration
+	TrendingWindowWeek        time.Duration
+	TrendingWindowMonth       time.Duration
+	TrendingSnapshotRetention time.Duration
+
+	// Counter reconciliation recomputes the like, upvote, view and comment
+	// counters every CounterReconcileInterval, CounterReconcileBatch
+	// documents at a time, and corrects them if CounterReconcileFix is set.
+	// An interval of 0 leaves it to the reconcile command.
+	CounterReconcileInterval time.Duration
+	CounterReconcileBatch    int
+	CounterReconcileFix      bool
+
+	// Votes. One voter's votes move one author's reputation at most
+	// VoteReputationLimit times per VoteReputationWindow, and upvotes not at
+	// all while the author has reached the limit voting on the voter.
+	VoteReputationLimit  int
+	VoteReputationWindow time.Duration
+
+	// ReactionEmoji are the emoji posts, reels and comments can be reacted
+	// to with, in the order their counts are listed
+	ReactionEmoji []string
 }
 
 func Load() *Config {
@@ -46,22 +146,92 @@ func Load() *Config {
 		Environment:         getEnv("ENV", "development"),
 		MongoURI:            getEnv("MONGODB_URI", "mongodb://localhost:27017/devthreads"),
 		MongoDatabase:       getEnv("MONGODB_DATABASE", "devthreads"),
-		JWTSecret:           getEnv("JWT_SECRET", "your-super-secret-jwt-key"),
+		JWTSecret:           getEnv("JWT_SECRET", DefaultJWTSecret),
 		JWTAccessExpiry:     parseDuration(getEnv("JWT_ACCESS_EXPIRY", "15m")),
 		JWTRefreshExpiry:    parseDuration(getEnv("JWT_REFRESH_EXPIRY", "168h")),
+		JWTKeysDir:          getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKeyID:      getEnv("JWT_ACTIVE_KEY_ID", ""),
+		UserStateCacheTTL:   parseDuration(getEnv("USER_STATE_CACHE_TTL", "30s")),
+		RequireAdminMFA:     getEnv("REQUIRE_ADMIN_MFA", "false") == "true",
 		GithubClientID:      getEnv("GITHUB_CLIENT_ID", ""),
 		GithubClientSecret:  getEnv("GITHUB_CLIENT_SECRET", ""),
 		GithubRedirectURL:   getEnv("GITHUB_REDIRECT_URL", "http://localhost:8080/auth/github/callback"),
+		GithubOAuthURL:      getEnv("GITHUB_OAUTH_URL", "https://github.com"),
+		GithubAPIURL:        getEnv("GITHUB_API_URL", "https://api.github.com"),
 		CloudinaryCloudName: getEnv("CLOUDINARY_CLOUD_NAME", ""),
 		CloudinaryAPIKey:    getEnv("CLOUDINARY_API_KEY", ""),
 		CloudinaryAPISecret: getEnv("CLOUDINARY_API_SECRET", ""),
 		FrontendURL:         getEnv("FRONTEND_URL", "http://localhost:3000"),
+		TrustedProxies:      splitList(getEnv("TRUSTED_PROXIES", "")),
+		MailDriver:          getEnv("MAIL_DRIVER", "log"),
+		MailFrom:            getEnv("MAIL_FROM", "DevThreads <no-reply@devthreads.local>"),
+		MailFilePath:        getEnv("MAIL_FILE_PATH", "mail.log"),
+		SMTPHost:            getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:            getEnv("SMTP_PORT", "1025"),
+		SMTPUsername:        getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
 		RedisURL:            getEnv("REDIS_URL", ""),
 		RateLimitRequests:   100,
 		RateLimitDuration:   time.Minute,
+
+		LoginAccountMaxFailures: getEnvInt("LOGIN_ACCOUNT_MAX_FAILURES", 5),
+		LoginIPMaxFailures:      getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginFailureWindow:      parseDuration(getEnv("LOGIN_FAILURE_WINDOW", "15m")),
+		LoginLockoutBase:        parseDuration(getEnv("LOGIN_LOCKOUT_BASE", "1m")),
+		LoginLockoutMax:         parseDuration(getEnv("LOGIN_LOCKOUT_MAX", "1h")),
+
+		PublicURL:            getEnv("PUBLIC_URL", "http://localhost:8080"),
+		DataExportDir:        getEnv("DATA_EXPORT_DIR", "exports"),
+		DataExportExpiry:     parseDuration(getEnv("DATA_EXPORT_EXPIRY", "7d")),
+		AccountDeletionGrace: parseDuration(getEnv("ACCOUNT_DELETION_GRACE", "14d")),
+		ContentUndoWindow:    parseDuration(getEnv("CONTENT_UNDO_WINDOW", "30m")),
+		ContentRetention:     parseDuration(getEnv("CONTENT_RETENTION", "30d")),
+
+		TrendingLikeWeight:        getEnvFloat("TRENDING_LIKE_WEIGHT", 1),
+		TrendingUpvoteWeight:      getEnvFloat("TRENDING_UPVOTE_WEIGHT", 1),
+		TrendingCommentWeight:     getEnvFloat("TRENDING_COMMENT_WEIGHT", 2),
+		TrendingViewWeight:        getEnvFloat("TRENDING_VIEW_WEIGHT", 0.01),
+		TrendingGravity:           getEnvFloat("TRENDING_GRAVITY", 1.8),
+		TrendingRefreshInterval:   parseDuration(getEnv("TRENDING_REFRESH_INTERVAL", "10m")),
+		TrendingWindowToday:       parseDuration(getEnv("TRENDING_WINDOW_TODAY", "24h")),
+		TrendingWindowWeek:        parseDuration(getEnv("TRENDING_WINDOW_WEEK", "7d")),
+		TrendingWindowMonth:       parseDuration(getEnv("TRENDING_WINDOW_MONTH", "30d")),
+		TrendingSnapshotRetention: parseDuration(getEnv("TRENDING_SNAPSHOT_RETENTION", "1h")),
+
+		CounterReconcileInterval: parseDuration(getEnv("COUNTER_RECONCILE_INTERVAL", "24h")),
+		CounterReconcileBatch:    getEnvInt("COUNTER_RECONCILE_BATCH", 500),
+		CounterReconcileFix:      getEnv("COUNTER_RECONCILE_FIX", "true") == "true",
+
+		VoteReputationLimit:  getEnvInt("VOTE_REPUTATION_LIMIT", 5),
+		VoteReputationWindow: parseDuration(getEnv("VOTE_REPUTATION_WINDOW", "24h")),
+
+		ReactionEmoji: splitList(getEnv("REACTION_EMOJI", "👍,❤️,🎉,🚀,👀,🤔")),
 	}
 }
 
+// Validate reports settings that are unsafe to run with
+func (c *Config) Validate() error {
+	if c.Environment == "production" && placeholderJWTSecrets[c.JWTSecret] {
+		return errors.New("JWT_SECRET must be set to a private value in production")
+	}
+	if c.Environment == "production" && c.MailDriver != "smtp" {
+		return errors.New("MAIL_DRIVER must be smtp in production; the log and file drivers expose account links")
+	}
+	if c.TrendingSnapshotRetention < c.TrendingRefreshInterval {
+		return errors.New("TRENDING_SNAPSHOT_RETENTION must be at least TRENDING_REFRESH_INTERVAL")
+	}
+	if c.JWTKeysDir != "" && c.JWTActiveKeyID == "" {
+		return errors.New("JWT_ACTIVE_KEY_ID must be set when JWT_KEYS_DIR is")
+	}
+	for _, emoji := range c.ReactionEmoji {
+		// Reaction counts are stored under the emoji as field names
+		if strings.Contains(emoji, ".") || strings.HasPrefix(emoji, "$") {
+			return fmt.Errorf("REACTION_EMOJI entry %q cannot contain '.' or start with '$'", emoji)
+		}
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -69,7 +239,39 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// splitList parses a comma-separated list, ignoring empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDuration accepts Go durations plus a whole-day suffix such as "7d"
 func parseDuration(s string) time.Duration {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Duration(n) * 24 * time.Hour
+		}
+	}
+
 	d, err := time.ParseDuration(s)
 	if err != nil {
 		return 15 * time.Minute
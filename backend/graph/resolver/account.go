@@ -0,0 +1,248 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/mailer"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+	minPasswordLength    = 8
+)
+
+// RequestPasswordReset mails a reset link. It reports success whether or not
+// the address is registered so it cannot be used to probe for accounts.
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	user, _ := r.UserRepo.FindByEmail(ctx, email)
+	if user == nil {
+		return true, nil
+	}
+
+	token, err := r.createAccountToken(ctx, user, models.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return false, err
+	}
+
+	link := r.Config.FrontendURL + "/reset-password?" + url.Values{"token": {token}}.Encode()
+	err = r.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your DevThreads password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in one hour.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Username, link,
+		),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %s: %v", user.ID.Hex(), err)
+	}
+
+	return true, nil
+}
+
+// ResetPassword sets a new password using a mailed reset token and signs the
+// user out everywhere
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	if err := validatePassword(newPassword); err != nil {
+		return false, err
+	}
+
+	stored, err := r.AccountTokenRepo.Consume(ctx, r.AuthService.HashToken(token), models.TokenPurposePasswordReset)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := r.UserRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	hashedPassword, err := r.AuthService.HashPassword(newPassword)
+	if err != nil {
+		return false, err
+	}
+
+	update := bson.M{"password": hashedPassword}
+	// Following the link proves the user controls the address it was sent to
+	if stored.Email == user.Email {
+		update["email_verified"] = true
+	}
+	if err := r.UserRepo.UpdateAndRevokeTokens(ctx, user.ID, update); err != nil {
+		return false, err
+	}
+	r.UserState.Invalidate(user.ID)
+
+	if _, err := r.endAllSessions(ctx, user.ID, primitive.NilObjectID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ChangePassword replaces the caller's password, signs out every other
+// device and returns fresh tokens for the current one, since the change
+// invalidates the access token used to make it
+func (r *mutationResolver) ChangePassword(ctx context.Context, currentPassword string, newPassword string) (*model.AuthPayload, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return nil, errors.New("token is not bound to a session")
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Password == "" {
+		return nil, errors.New("account has no password, use password reset to set one")
+	}
+	if !r.AuthService.CheckPassword(currentPassword, user.Password) {
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := r.AuthService.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.UserRepo.UpdateAndRevokeTokens(ctx, user.ID, bson.M{"password": hashedPassword}); err != nil {
+		return nil, err
+	}
+	r.UserState.Invalidate(user.ID)
+
+	if _, err := r.endAllSessions(ctx, user.ID, sessionID); err != nil {
+		return nil, err
+	}
+
+	// Replace the current session's refresh token too, so one captured
+	// before the change cannot be used to get past it
+	if err := r.RefreshTokenRepo.RevokeFamily(ctx, user.ID, sessionID); err != nil {
+		return nil, err
+	}
+
+	user, err = r.UserRepo.FindByID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, _, err := r.generateTokens(ctx, user, sessionID, claims.MFA)
+	return payload, err
+}
+
+// SendVerificationEmail mails the signed-in user a link to confirm their email
+func (r *mutationResolver) SendVerificationEmail(ctx context.Context) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if user.EmailVerified {
+		return false, errors.New("email is already verified")
+	}
+
+	if err := r.sendVerificationEmail(ctx, user); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// VerifyEmail confirms the email address a verification token was sent to
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (bool, error) {
+	stored, err := r.AccountTokenRepo.Consume(ctx, r.AuthService.HashToken(token), models.TokenPurposeEmailVerification)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := r.UserRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	// The user changed their address after the link was sent
+	if stored.Email != user.Email {
+		return false, errors.New("invalid or expired token")
+	}
+
+	if err := r.UserRepo.Update(ctx, user.ID, bson.M{"email_verified": true}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := r.createAccountToken(ctx, user, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := r.Config.FrontendURL + "/verify-email?" + url.Values{"token": {token}}.Encode()
+	return r.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your DevThreads email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address by opening the link below. It expires in 24 hours.\n\n%s\n",
+			user.Username, link,
+		),
+	})
+}
+
+// createAccountToken replaces any outstanding token of the same purpose and
+// returns the new plaintext token
+func (r *mutationResolver) createAccountToken(ctx context.Context, user *models.User, purpose string, ttl time.Duration) (string, error) {
+	if err := r.AccountTokenRepo.InvalidateForUser(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := r.AuthService.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = r.AccountTokenRepo.Create(ctx, &models.AccountToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: r.AuthService.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return nil
+}
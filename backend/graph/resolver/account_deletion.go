@@ -0,0 +1,220 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/devthreads/backend/internal/jobs"
+	"github.com/devthreads/backend/internal/mailer"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// accountPurgeBatch is how many accounts one run of the purge job deletes
+const accountPurgeBatch = 100
+
+// DeleteAccount schedules the caller's account for deletion and signs them out
+// everywhere. The account is purged once the grace period has passed unless
+// the user signs in again and cancels.
+func (r *mutationResolver) DeleteAccount(ctx context.Context, password *string) (bool, error) {
+	user, err := r.sessionUser(ctx)
+	if err != nil {
+		return false, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return false, errors.New("account deletion is already scheduled")
+	}
+
+	if user.Password != "" {
+		if password == nil || !r.AuthService.CheckPassword(*password, user.Password) {
+			return false, errors.New("incorrect password")
+		}
+	}
+
+	deleteAt := time.Now().Add(r.Config.AccountDeletionGrace)
+	if err := r.UserRepo.UpdateAndRevokeTokens(ctx, user.ID, bson.M{"deletion_scheduled_at": deleteAt}); err != nil {
+		return false, err
+	}
+	r.UserState.Invalidate(user.ID)
+
+	if _, err := r.endAllSessions(ctx, user.ID, primitive.NilObjectID); err != nil {
+		return false, err
+	}
+
+	err = r.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your DevThreads account will be deleted",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour account and everything you posted will be deleted on %s.\n\nTo keep your account, sign in before then and cancel the deletion.\n",
+			user.Username, deleteAt.UTC().Format("2 January 2006"),
+		),
+	})
+	if err != nil {
+		log.Printf("Failed to send account deletion email to user %s: %v", user.ID.Hex(), err)
+	}
+
+	return true, nil
+}
+
+// CancelAccountDeletion keeps an account that is scheduled for deletion
+func (r *mutationResolver) CancelAccountDeletion(ctx context.Context) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	cancelled, err := r.UserRepo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !cancelled {
+		return false, errors.New("account deletion is not scheduled")
+	}
+
+	return true, nil
+}
+
+// AccountPurgeJob deletes accounts whose grace period has passed
+func (r *Resolver) AccountPurgeJob() jobs.Job {
+	return jobs.Job{
+		Name:     "account-purge",
+		Interval: time.Hour,
+		Run:      r.purgeDeletedAccounts,
+	}
+}
+
+func (r *Resolver) purgeDeletedAccounts(ctx context.Context) error {
+	users, err := r.UserRepo.FindDueForDeletion(ctx, time.Now(), accountPurgeBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := r.purgeAccount(ctx, user.ID); err != nil {
+			log.Printf("Failed to purge account %s: %v", user.ID.Hex(), err)
+			continue
+		}
+		log.Printf("Purged account %s", user.ID.Hex())
+	}
+	return nil
+}
+
+// purgeAccount removes everything belonging to the user. Posts, reels and
+// comments are blanked and marked deleted rather than removed, so threads
+// other users took part in stay intact; moderation logs are kept. Every step
+// is idempotent and the user document goes last, so a failed purge is
+// retried from the start on the next run.
+func (r *Resolver) purgeAccount(ctx context.Context, userID primitive.ObjectID) error {
+	steps := []struct {
+		name string
+		run  func(context.Context, primitive.ObjectID) error
+	}{
+		{"post revisions", r.PostRevisionRepo.DeleteAllForAuthor},
+		{"posts", r.PostRepo.AnonymizeByAuthor},
+		{"reels", r.ReelRepo.AnonymizeByAuthor},
+		{"comments", r.CommentRepo.AnonymizeByAuthor},
+		{"engagements", r.removeEngagements},
+		{"follows", r.removeFollows},
+		{"timeline", r.TimelineRepo.DeleteAllForUser},
+		{"notifications", r.NotificationRepo.DeleteAllForUser},
+		{"data exports", r.removeDataExports},
+		{"sessions", r.SessionRepo.DeleteAllForUser},
+		{"refresh tokens", r.RefreshTokenRepo.DeleteAllForUser},
+		{"personal access tokens", r.PersonalAccessTokenRepo.DeleteAllForUser},
+		{"account tokens", r.AccountTokenRepo.DeleteAllForUser},
+		{"security events", r.SecurityEventRepo.DeleteAllForUser},
+		{"user", r.UserRepo.Delete},
+	}
+
+	for _, step := range steps {
+		if err := step.run(ctx, userID); err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+
+	r.UserState.Invalidate(userID)
+	return nil
+}
+
+// removeEngagements takes back the user's likes, views, votes and reactions
+// the way undoing each of them would: the target's counters drop and votes
+// give back the reputation they moved. Every engagement is deleted before its
+// effects are reversed, so a retried purge never reverses one twice; counters
+// left out of step by a failure in between are corrected by reconciliation.
+func (r *Resolver) removeEngagements(ctx context.Context, userID primitive.ObjectID) error {
+	engagements, err := r.EngagementRepo.FindAllByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, engagement := range engagements {
+		deleted, err := r.EngagementRepo.Delete(ctx, engagement)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			r.reverseEngagement(ctx, engagement)
+		}
+	}
+	return nil
+}
+
+// reverseEngagement moves the counters and reputation a deleted engagement
+// had moved back
+func (r *Resolver) reverseEngagement(ctx context.Context, engagement *models.Engagement) {
+	var adjustCount func(ctx context.Context, id primitive.ObjectID, field string, delta int) (int, error)
+	var adjustReaction func(ctx context.Context, id primitive.ObjectID, emoji string, delta int) (map[string]int, error)
+	switch engagement.TargetType {
+	case models.TargetPost:
+		adjustCount, adjustReaction = r.PostRepo.AdjustCount, r.PostRepo.AdjustReaction
+	case models.TargetReel:
+		adjustCount, adjustReaction = r.ReelRepo.AdjustCount, r.ReelRepo.AdjustReaction
+	case models.TargetComment:
+		adjustCount, adjustReaction = r.CommentRepo.AdjustCount, r.CommentRepo.AdjustReaction
+	default:
+		return
+	}
+
+	var err error
+	switch engagement.Type {
+	case models.EngagementLike:
+		_, err = adjustCount(ctx, engagement.TargetID, "likes_count", -1)
+	case models.EngagementView:
+		_, err = adjustCount(ctx, engagement.TargetID, "views_count", -1)
+	case models.EngagementUpvote, models.EngagementDownvote:
+		_, err = adjustCount(ctx, engagement.TargetID, voteCounter(engagement.Type), -1)
+		if engagement.Reputation != 0 && engagement.TargetAuthorID != nil {
+			if err := r.UserRepo.UpdateReputation(ctx, *engagement.TargetAuthorID, -engagement.Reputation); err != nil {
+				log.Printf("Failed to update reputation of user %s: %v", engagement.TargetAuthorID.Hex(), err)
+			}
+		}
+	case models.EngagementReaction:
+		var counts map[string]int
+		counts, err = adjustReaction(ctx, engagement.TargetID, engagement.Emoji, -1)
+		if err == nil {
+			r.PubSub.Publish(reactionsTopic(engagement.TargetType, engagement.TargetID.Hex()), counts)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to take back %s of %s %s: %v",
+			engagement.Type, engagement.TargetType, engagement.TargetID.Hex(), err)
+	}
+}
+
+func (r *Resolver) removeDataExports(ctx context.Context, userID primitive.ObjectID) error {
+	exports, err := r.DataExportRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := r.removeDataExport(ctx, export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
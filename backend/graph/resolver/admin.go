@@ -0,0 +1,302 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/rbac"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// permanentBan is how long a ban without a duration lasts
+const permanentBan = 100 * 365 * 24 * time.Hour
+
+// AdminBanUser bans a user and signs them out everywhere. Their access tokens
+// stop working immediately on this instance and within the user state cache
+// TTL on others.
+func (r *mutationResolver) AdminBanUser(ctx context.Context, input model.AdminBanUserInput) (bool, error) {
+	claims, err := r.requirePermission(ctx, rbac.PermissionBanUsers)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := r.adminTarget(ctx, claims.UserID, input.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	staff, err := r.Roles.IsStaff(ctx, target.Role)
+	if err != nil {
+		return false, err
+	}
+	if staff {
+		return false, errors.New("change the user's role before banning them")
+	}
+
+	duration := permanentBan
+	if input.Duration != nil {
+		if *input.Duration <= 0 {
+			return false, errors.New("ban duration must be at least one day")
+		}
+		duration = time.Duration(*input.Duration) * 24 * time.Hour
+	}
+
+	bannedUntil := time.Now().Add(duration)
+	if err := r.UserRepo.UpdateAndRevokeTokens(ctx, target.ID, bson.M{"banned_until": bannedUntil}); err != nil {
+		return false, err
+	}
+	r.UserState.Invalidate(target.ID)
+
+	if _, err := r.endAllSessions(ctx, target.ID, primitive.NilObjectID); err != nil {
+		return false, err
+	}
+
+	r.logModeration(ctx, claims.UserID, models.ModerationBanUser, "user", target.ID, input.Reason)
+	return true, nil
+}
+
+// AdminUnbanUser lifts a ban. The user has to sign in again.
+func (r *mutationResolver) AdminUnbanUser(ctx context.Context, userID string) (bool, error) {
+	claims, err := r.requirePermission(ctx, rbac.PermissionBanUsers)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := r.adminTarget(ctx, claims.UserID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.UserRepo.Unban(ctx, target.ID); err != nil {
+		return false, err
+	}
+	r.UserState.Invalidate(target.ID)
+
+	r.logModeration(ctx, claims.UserID, models.ModerationUnbanUser, "user", target.ID, "")
+	return true, nil
+}
+
+// AdminSetUserRole assigns a built-in or custom role. The caller must hold
+// every permission of both the user's current and new role, and only admins
+// can make or unmake admins. Outstanding access tokens are revoked so the new
+// role applies to the user's next request; their sessions stay signed in and
+// pick up the role on refresh.
+func (r *mutationResolver) AdminSetUserRole(ctx context.Context, userID string, role string) (bool, error) {
+	claims, err := r.requirePermission(ctx, rbac.PermissionManageRoles)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := r.adminTarget(ctx, claims.UserID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := r.Roles.Exists(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, errors.New("role not found")
+	}
+
+	if target.Role == role {
+		return true, nil
+	}
+
+	if (role == rbac.RoleAdmin || target.Role == rbac.RoleAdmin) && claims.Role != rbac.RoleAdmin {
+		return false, errors.New("only admins can grant or revoke the admin role")
+	}
+	for _, name := range []string{target.Role, role} {
+		permissions, err := r.Roles.Permissions(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		if err := r.requireRoleOutranked(ctx, claims, permissions); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.UserRepo.UpdateAndRevokeTokens(ctx, target.ID, bson.M{"role": role}); err != nil {
+		return false, err
+	}
+	r.UserState.Invalidate(target.ID)
+
+	reason := fmt.Sprintf("role changed from %s to %s", target.Role, role)
+	r.logModeration(ctx, claims.UserID, models.ModerationChangeRole, "user", target.ID, reason)
+	return true, nil
+}
+
+// AdminDeleteComment removes a comment and its replies on behalf of a
+// moderator. The author cannot restore them.
+func (r *mutationResolver) AdminDeleteComment(ctx context.Context, commentID string, reason string) (bool, error) {
+	claims, err := r.requirePermission(ctx, rbac.PermissionModerateComments)
+	if err != nil {
+		return false, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return false, errors.New("invalid comment id")
+	}
+
+	comment, err := r.CommentRepo.FindByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if comment.Deleted {
+		return false, errors.New("comment not found")
+	}
+
+	moderatorID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	if err := r.deleteCommentThread(ctx, comment, moderatorID); err != nil {
+		return false, err
+	}
+
+	r.logModeration(ctx, claims.UserID, models.ModerationDeleteComment, "comment", comment.ID, reason)
+	return true, nil
+}
+
+// AdminUsers lists users newest first. filter matches usernames and emails
+// literally.
+func (r *queryResolver) AdminUsers(ctx context.Context, first *int, after *string, filter *string) (*model.UserConnection, error) {
+	if _, err := r.requirePermission(ctx, rbac.PermissionViewAdminDashboard); err != nil {
+		return nil, err
+	}
+
+	page, err := r.UserRepo.List(ctx, adminFilter(filter), pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.UserConnection{Edges: make([]*model.UserEdge, len(page.Edges)), PageInfo: convertPageInfo(page)}
+	for i, edge := range page.Edges {
+		connection.Edges[i] = &model.UserEdge{Cursor: edge.Cursor, Node: convertUser(edge.Node, userViewFull)}
+	}
+	return connection, nil
+}
+
+// AdminPosts lists all posts newest first, deleted ones included. filter
+// matches post content literally.
+func (r *queryResolver) AdminPosts(ctx context.Context, first *int, after *string, filter *string) (*model.PostConnection, error) {
+	if _, err := r.requirePermission(ctx, rbac.PermissionViewAdminDashboard); err != nil {
+		return nil, err
+	}
+
+	page, err := r.PostRepo.List(ctx, adminFilter(filter), pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+	return r.postConnection(ctx, page.Edges, convertPageInfo(page))
+}
+
+// AdminReels lists all reels newest first, deleted ones included
+func (r *queryResolver) AdminReels(ctx context.Context, first *int, after *string) (*model.ReelConnection, error) {
+	if _, err := r.requirePermission(ctx, rbac.PermissionViewAdminDashboard); err != nil {
+		return nil, err
+	}
+
+	page, err := r.ReelRepo.ListAll(ctx, pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+	return r.reelConnection(ctx, page)
+}
+
+// AdminModerationLogs lists moderation actions newest first
+func (r *queryResolver) AdminModerationLogs(ctx context.Context, first *int, after *string) (*model.ModerationLogConnection, error) {
+	if _, err := r.requirePermission(ctx, rbac.PermissionViewModerationLogs); err != nil {
+		return nil, err
+	}
+
+	page, err := r.ModerationLogRepo.List(ctx, pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(page.Edges))
+	for i, edge := range page.Edges {
+		ids[i] = edge.Node.AdminID
+	}
+	admins, err := r.loadAuthors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.ModerationLogConnection{Edges: []*model.ModerationLogEdge{}, PageInfo: convertPageInfo(page)}
+	for _, edge := range page.Edges {
+		entry := convertModerationLog(edge.Node)
+		if !edge.Node.AdminID.IsZero() {
+			admin, ok := admins[edge.Node.AdminID]
+			if !ok {
+				continue
+			}
+			entry.Admin = admin
+		}
+		connection.Edges = append(connection.Edges, &model.ModerationLogEdge{Cursor: edge.Cursor, Node: entry})
+	}
+	return connection, nil
+}
+
+// adminFilter turns the filter argument of admin lists into a literal
+// pattern
+func adminFilter(filter *string) string {
+	if filter == nil {
+		return ""
+	}
+	return regexp.QuoteMeta(*filter)
+}
+
+// Helper to convert models.ModerationLog to model.ModerationLog
+func convertModerationLog(l *models.ModerationLog) *model.ModerationLog {
+	var reason *string
+	if l.Reason != "" {
+		reason = &l.Reason
+	}
+	return &model.ModerationLog{
+		ID:         l.ID.Hex(),
+		Action:     model.ModerationAction(l.Action),
+		TargetType: l.TargetType,
+		TargetID:   l.TargetID.Hex(),
+		Reason:     reason,
+		CreatedAt:  l.CreatedAt,
+	}
+}
+
+// adminTarget loads the user an admin action applies to. Admins cannot act
+// on their own account, so they cannot lock themselves out.
+func (r *mutationResolver) adminTarget(ctx context.Context, adminID, userID string) (*models.User, error) {
+	if userID == adminID {
+		return nil, errors.New("admins cannot perform this action on their own account")
+	}
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	return r.UserRepo.FindByID(ctx, id)
+}
+
+// logModeration records an admin action. A failure is logged rather than
+// returned because the action itself has already been applied.
+func (r *mutationResolver) logModeration(ctx context.Context, adminID, action, targetType string, targetID primitive.ObjectID, reason string) {
+	admin, _ := primitive.ObjectIDFromHex(adminID)
+	err := r.ModerationLogRepo.Create(ctx, &models.ModerationLog{
+		AdminID:    admin,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+	})
+	if err != nil {
+		log.Printf("Failed to record moderation action %s on %s: %v", action, targetID.Hex(), err)
+	}
+}
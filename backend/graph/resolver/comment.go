@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comments returns the top-level comments of a post or reel, newest first
+func (r *queryResolver) Comments(ctx context.Context, postID *string, reelID *string, first *int, after *string) (*model.CommentConnection, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	var page *pagination.Page[models.Comment]
+
+	switch {
+	case postID != nil && reelID == nil:
+		id, err := primitive.ObjectIDFromHex(*postID)
+		if err != nil {
+			return nil, errors.New("invalid post id")
+		}
+		if _, err := r.findVisiblePost(ctx, id); err != nil {
+			return nil, err
+		}
+		page, err = r.CommentRepo.FindByPost(ctx, id, pageSize(first), pageCursor(after))
+		if err != nil {
+			return nil, err
+		}
+	case reelID != nil && postID == nil:
+		id, err := primitive.ObjectIDFromHex(*reelID)
+		if err != nil {
+			return nil, errors.New("invalid reel id")
+		}
+		if _, err := r.findVisibleReel(ctx, id); err != nil {
+			return nil, err
+		}
+		page, err = r.CommentRepo.FindByReel(ctx, id, pageSize(first), pageCursor(after))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("either postId or reelId is required")
+	}
+
+	ids := make([]primitive.ObjectID, len(page.Edges))
+	for i, edge := range page.Edges {
+		ids[i] = edge.Node.AuthorID
+	}
+	authors, err := r.loadAuthors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.CommentConnection{Edges: []*model.CommentEdge{}, PageInfo: convertPageInfo(page)}
+	for _, edge := range page.Edges {
+		author, ok := authors[edge.Node.AuthorID]
+		if !ok {
+			continue
+		}
+		comment := convertComment(edge.Node)
+		comment.Author = author
+		connection.Edges = append(connection.Edges, &model.CommentEdge{Cursor: edge.Cursor, Node: comment})
+	}
+	return connection, nil
+}
+
+// Helper to convert models.Comment to model.Comment
+func convertComment(c *models.Comment) *model.Comment {
+	hex := func(id *primitive.ObjectID) *string {
+		if id == nil {
+			return nil
+		}
+		s := id.Hex()
+		return &s
+	}
+	return &model.Comment{
+		ID:              c.ID.Hex(),
+		Content:         c.Content,
+		PostID:          hex(c.PostID),
+		ReelID:          hex(c.ReelID),
+		ParentCommentID: hex(c.ParentCommentID),
+		CreatedAt:       c.CreatedAt,
+		LikesCount:      c.LikesCount,
+		UpvotesCount:    c.UpvotesCount,
+		DownvotesCount:  c.DownvotesCount,
+		Score:           c.UpvotesCount - c.DownvotesCount,
+		Reactions:       convertReactions(c.ReactionCounts),
+	}
+}
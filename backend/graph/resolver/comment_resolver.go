@@ -0,0 +1,21 @@
+package resolver
+
+// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+)
+
+type commentResolver struct{ *Resolver }
+
+func (r *Resolver) Comment() CommentResolver {
+	return &commentResolver{r}
+}
+
+// CommentResolver interface (will be generated)
+type CommentResolver interface {
+	ViewerVote(ctx context.Context, obj *model.Comment) (*model.Vote, error)
+	ViewerReactions(ctx context.Context, obj *model.Comment) ([]string, error)
+}
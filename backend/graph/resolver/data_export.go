@@ -0,0 +1,297 @@
+package resolver
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/jobs"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// dataExportCooldown limits how often a user can request an export
+	dataExportCooldown = 24 * time.Hour
+	// dataExportStaleAfter is when an export still processing is assumed to
+	// have been abandoned by its server and is built again
+	dataExportStaleAfter = 30 * time.Minute
+)
+
+// exportMedia is a media file referenced by the user's data. Media is hosted
+// on Cloudinary, so the archive lists URLs rather than including the files.
+type exportMedia struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	RelatedID string `json:"relatedId"`
+}
+
+// RequestDataExport queues an archive of the caller's data
+func (r *mutationResolver) RequestDataExport(ctx context.Context) (*model.DataExport, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	latest, err := r.DataExportRepo.FindLatestByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status != models.DataExportFailed && time.Since(latest.CreatedAt) < dataExportCooldown {
+		return nil, errors.New("a data export was already requested in the last 24 hours")
+	}
+
+	export := &models.DataExport{UserID: userID}
+	if err := r.DataExportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+	jobs.Notify(r.exportQueued)
+
+	return convertDataExport(export), nil
+}
+
+// MyDataExports lists the caller's exports, newest first
+func (r *queryResolver) MyDataExports(ctx context.Context) ([]*model.DataExport, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	exports, err := r.DataExportRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.DataExport, len(exports))
+	for i, e := range exports {
+		result[i] = convertDataExport(e)
+	}
+	return result, nil
+}
+
+// DataExportJob builds queued exports and removes expired archives. It is
+// woken up as soon as an export is requested on this instance.
+func (r *Resolver) DataExportJob() jobs.Job {
+	return jobs.Job{
+		Name:     "data-export",
+		Interval: time.Minute,
+		Run:      r.processDataExports,
+		Wake:     r.exportQueued,
+	}
+}
+
+// DataExportFile returns the archive path of a ready export when token is its
+// download token
+func (r *Resolver) DataExportFile(ctx context.Context, id, token string) (string, error) {
+	exportID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", errors.New("export not found or expired")
+	}
+
+	export, err := r.DataExportRepo.FindDownload(ctx, exportID, r.AuthService.HashToken(token))
+	if err != nil {
+		return "", err
+	}
+	return export.FilePath, nil
+}
+
+func (r *Resolver) processDataExports(ctx context.Context) error {
+	if err := r.removeExpiredExports(ctx); err != nil {
+		log.Printf("Failed to remove expired data exports: %v", err)
+	}
+
+	for {
+		export, err := r.DataExportRepo.ClaimNext(ctx, dataExportStaleAfter)
+		if err != nil {
+			return err
+		}
+		if export == nil {
+			return nil
+		}
+		r.buildDataExport(ctx, export)
+	}
+}
+
+// buildDataExport writes the archive and notifies the user with a download
+// link, or that the export failed
+func (r *Resolver) buildDataExport(ctx context.Context, export *models.DataExport) {
+	path, err := r.writeDataExport(ctx, export)
+	if err == nil {
+		err = r.publishDataExport(ctx, export, path)
+		if err != nil {
+			os.Remove(path)
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	log.Printf("Failed to build data export %s: %v", export.ID.Hex(), err)
+	if err := r.DataExportRepo.MarkFailed(ctx, export.ID, "the export could not be built"); err != nil {
+		log.Printf("Failed to mark data export %s as failed: %v", export.ID.Hex(), err)
+	}
+	r.notify(ctx, export.UserID, models.NotificationDataExportFailed,
+		"Your data export could not be created. Please request a new one.", &export.ID)
+}
+
+func (r *Resolver) publishDataExport(ctx context.Context, export *models.DataExport, path string) error {
+	token, err := r.AuthService.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(r.Config.DataExportExpiry)
+	if err := r.DataExportRepo.MarkReady(ctx, export.ID, path, r.AuthService.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := r.Config.PublicURL + "/exports/" + export.ID.Hex() + "?" + url.Values{"token": {token}}.Encode()
+	r.notify(ctx, export.UserID, models.NotificationDataExportReady, fmt.Sprintf(
+		"Your data export is ready. Download it before %s: %s",
+		expiresAt.UTC().Format("2 January 2006 15:04 MST"), link,
+	), &export.ID)
+	return nil
+}
+
+// writeDataExport writes a zip archive with one JSON file per collection and
+// returns its path. The archive is written under a temporary name and renamed,
+// so a partial file is never served.
+func (r *Resolver) writeDataExport(ctx context.Context, export *models.DataExport) (string, error) {
+	user, err := r.UserRepo.FindByID(ctx, export.UserID)
+	if err != nil {
+		return "", err
+	}
+	posts, err := r.PostRepo.FindAllByAuthor(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	revisions, err := r.PostRevisionRepo.FindAllByAuthor(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	reels, err := r.ReelRepo.FindAllByAuthor(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	comments, err := r.CommentRepo.FindAllByAuthor(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	engagements, err := r.EngagementRepo.FindAllByUser(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	notifications, err := r.NotificationRepo.FindAllByUser(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"users.json", []*models.User{user}},
+		{"posts.json", posts},
+		{"post_revisions.json", revisions},
+		{"reels.json", reels},
+		{"comments.json", comments},
+		{"engagements.json", engagements},
+		{"notifications.json", notifications},
+		{"media.json", exportMediaOf(user, reels)},
+	}
+
+	if err := os.MkdirAll(r.Config.DataExportDir, 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(r.Config.DataExportDir, "export-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	for _, f := range files {
+		w, err := archive.Create(f.name)
+		if err != nil {
+			return "", err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return "", err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(r.Config.DataExportDir, export.ID.Hex()+".zip")
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func exportMediaOf(user *models.User, reels []*models.Reel) []exportMedia {
+	media := []exportMedia{}
+	if user.AvatarURL != "" {
+		media = append(media, exportMedia{Type: "avatar", URL: user.AvatarURL, RelatedID: user.ID.Hex()})
+	}
+	for _, reel := range reels {
+		if reel.VideoURL != "" {
+			media = append(media, exportMedia{Type: "reel_video", URL: reel.VideoURL, RelatedID: reel.ID.Hex()})
+		}
+		if reel.ThumbnailURL != "" {
+			media = append(media, exportMedia{Type: "reel_thumbnail", URL: reel.ThumbnailURL, RelatedID: reel.ID.Hex()})
+		}
+	}
+	return media
+}
+
+func (r *Resolver) removeExpiredExports(ctx context.Context) error {
+	exports, err := r.DataExportRepo.FindExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := r.removeDataExport(ctx, export); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDataExport deletes the archive and then the export record
+func (r *Resolver) removeDataExport(ctx context.Context, export *models.DataExport) error {
+	if export.FilePath != "" {
+		if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return r.DataExportRepo.Delete(ctx, export.ID)
+}
+
+// Helper to convert models.DataExport to model.DataExport
+func convertDataExport(e *models.DataExport) *model.DataExport {
+	return &model.DataExport{
+		ID:          e.ID.Hex(),
+		Status:      model.DataExportStatus(e.Status),
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+}
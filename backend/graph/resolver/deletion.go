@@ -0,0 +1,417 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/jobs"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// contentPurgeBatch is how many posts, reels and comments each one run of the
+// purge job removes
+const contentPurgeBatch = 100
+
+var (
+	// errNotUndoable means the caller did not delete the content themselves,
+	// so for them it does not exist
+	errNotUndoable      = errors.New("not undoable")
+	errUndoWindowPassed = errors.New("the undo window has passed")
+)
+
+// DeletePost deletes the caller's post and its comments. The author can
+// restore it within the undo window.
+func (r *mutationResolver) DeletePost(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireScope(ctx, auth.ScopeWritePosts)
+	if err != nil {
+		return false, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid post id")
+	}
+	post, err := r.findVisiblePost(ctx, postID)
+	if err != nil {
+		return false, err
+	}
+	if post.AuthorID != userID {
+		return false, errors.New("you can only delete your own posts")
+	}
+
+	deleted, err := r.PostRepo.SoftDelete(ctx, post.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !deleted {
+		return false, errors.New("post not found")
+	}
+
+	if err := r.CommentRepo.SoftDeleteByPost(ctx, post.ID, userID); err != nil {
+		log.Printf("Failed to delete comments of post %s: %v", post.ID.Hex(), err)
+	}
+
+	// Take back the points awarded for posting
+	r.UserRepo.UpdateReputation(ctx, userID, -5)
+
+	return true, nil
+}
+
+// RestorePost undoes DeletePost, comments included
+func (r *mutationResolver) RestorePost(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireScope(ctx, auth.ScopeWritePosts)
+	if err != nil {
+		return false, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid post id")
+	}
+	post, err := r.PostRepo.FindDeleted(ctx, postID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.checkUndo(userID, post.DeletedBy, post.DeletedAt); err != nil {
+		if err == errNotUndoable {
+			return false, errors.New("post not found")
+		}
+		return false, err
+	}
+
+	restored, err := r.PostRepo.Restore(ctx, post.ID)
+	if err != nil {
+		return false, err
+	}
+	if !restored {
+		return false, errors.New("post not found")
+	}
+
+	if _, err := r.CommentRepo.RestoreDeletedWith(ctx, post.ID); err != nil {
+		log.Printf("Failed to restore comments of post %s: %v", post.ID.Hex(), err)
+	}
+
+	r.UserRepo.UpdateReputation(ctx, userID, 5)
+
+	return true, nil
+}
+
+// DeleteReel deletes the caller's reel and its comments. The author can
+// restore it within the undo window.
+func (r *mutationResolver) DeleteReel(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireScope(ctx, auth.ScopeWriteReels)
+	if err != nil {
+		return false, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	reelID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid reel id")
+	}
+	reel, err := r.findVisibleReel(ctx, reelID)
+	if err != nil {
+		return false, err
+	}
+	if reel.AuthorID != userID {
+		return false, errors.New("you can only delete your own reels")
+	}
+
+	deleted, err := r.ReelRepo.SoftDelete(ctx, reel.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	if !deleted {
+		return false, errors.New("reel not found")
+	}
+
+	if err := r.CommentRepo.SoftDeleteByReel(ctx, reel.ID, userID); err != nil {
+		log.Printf("Failed to delete comments of reel %s: %v", reel.ID.Hex(), err)
+	}
+
+	return true, nil
+}
+
+// RestoreReel undoes DeleteReel, comments included
+func (r *mutationResolver) RestoreReel(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireScope(ctx, auth.ScopeWriteReels)
+	if err != nil {
+		return false, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	reelID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid reel id")
+	}
+	reel, err := r.ReelRepo.FindDeleted(ctx, reelID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.checkUndo(userID, reel.DeletedBy, reel.DeletedAt); err != nil {
+		if err == errNotUndoable {
+			return false, errors.New("reel not found")
+		}
+		return false, err
+	}
+
+	restored, err := r.ReelRepo.Restore(ctx, reel.ID)
+	if err != nil {
+		return false, err
+	}
+	if !restored {
+		return false, errors.New("reel not found")
+	}
+
+	if _, err := r.CommentRepo.RestoreDeletedWith(ctx, reel.ID); err != nil {
+		log.Printf("Failed to restore comments of reel %s: %v", reel.ID.Hex(), err)
+	}
+
+	return true, nil
+}
+
+// DeleteComment deletes the caller's comment and the replies to it. The
+// author can restore it within the undo window.
+func (r *mutationResolver) DeleteComment(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid comment id")
+	}
+	comment, err := r.CommentRepo.FindByID(ctx, commentID)
+	if err != nil {
+		return false, err
+	}
+	if comment.AuthorID != userID {
+		return false, errors.New("you can only delete your own comments")
+	}
+
+	if err := r.deleteCommentThread(ctx, comment, userID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RestoreComment undoes DeleteComment, replies included. Comments deleted
+// along with their post or reel come back when it is restored instead.
+func (r *mutationResolver) RestoreComment(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid comment id")
+	}
+	comment, err := r.CommentRepo.FindDeleted(ctx, commentID)
+	if err != nil {
+		return false, err
+	}
+	if comment.DeletedWith != nil {
+		return false, errors.New("comment not found")
+	}
+	if err := r.checkUndo(userID, comment.DeletedBy, comment.DeletedAt); err != nil {
+		if err == errNotUndoable {
+			return false, errors.New("comment not found")
+		}
+		return false, err
+	}
+
+	// A comment cannot outlive what it was on
+	if comment.PostID != nil {
+		if _, err := r.PostRepo.FindByID(ctx, *comment.PostID); err != nil {
+			return false, err
+		}
+	} else if comment.ReelID != nil {
+		if _, err := r.ReelRepo.FindByID(ctx, *comment.ReelID); err != nil {
+			return false, err
+		}
+	}
+
+	restored, err := r.CommentRepo.Restore(ctx, comment.ID)
+	if err != nil {
+		return false, err
+	}
+	if !restored {
+		return false, errors.New("comment not found")
+	}
+
+	replies, err := r.CommentRepo.RestoreDeletedWith(ctx, comment.ID)
+	if err != nil {
+		log.Printf("Failed to restore replies to comment %s: %v", comment.ID.Hex(), err)
+	}
+	r.adjustCommentsCount(ctx, comment, int(1+replies))
+
+	return true, nil
+}
+
+// deleteCommentThread deletes a comment and every reply under it, and keeps
+// the comment count of the post or reel in step
+func (r *Resolver) deleteCommentThread(ctx context.Context, comment *models.Comment, by primitive.ObjectID) error {
+	deleted, err := r.CommentRepo.SoftDelete(ctx, []primitive.ObjectID{comment.ID}, by, nil)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errors.New("comment not found")
+	}
+
+	var replies []primitive.ObjectID
+	for parents := []primitive.ObjectID{comment.ID}; len(parents) > 0; {
+		parent := parents[0]
+		parents = parents[1:]
+
+		children, err := r.CommentRepo.FindReplies(ctx, parent)
+		if err != nil {
+			log.Printf("Failed to find replies to comment %s: %v", parent.Hex(), err)
+			continue
+		}
+		for _, child := range children {
+			replies = append(replies, child.ID)
+			parents = append(parents, child.ID)
+		}
+	}
+	if len(replies) > 0 {
+		n, err := r.CommentRepo.SoftDelete(ctx, replies, by, &comment.ID)
+		if err != nil {
+			log.Printf("Failed to delete replies to comment %s: %v", comment.ID.Hex(), err)
+		}
+		deleted += n
+	}
+
+	r.adjustCommentsCount(ctx, comment, -int(deleted))
+	return nil
+}
+
+// adjustCommentsCount adds delta to the comment count of what the comment is
+// on
+func (r *Resolver) adjustCommentsCount(ctx context.Context, comment *models.Comment, delta int) {
+	if comment.PostID != nil {
+		if _, err := r.PostRepo.AdjustCount(ctx, *comment.PostID, "comments_count", delta); err != nil {
+			log.Printf("Failed to update comment count of post %s: %v", comment.PostID.Hex(), err)
+		}
+	} else if comment.ReelID != nil {
+		if _, err := r.ReelRepo.AdjustCount(ctx, *comment.ReelID, "comments_count", delta); err != nil {
+			log.Printf("Failed to update comment count of reel %s: %v", comment.ReelID.Hex(), err)
+		}
+	}
+}
+
+// checkUndo reports whether the user may restore content they deleted at the
+// given time
+func (r *Resolver) checkUndo(userID primitive.ObjectID, deletedBy *primitive.ObjectID, deletedAt *time.Time) error {
+	if deletedBy == nil || *deletedBy != userID || deletedAt == nil {
+		return errNotUndoable
+	}
+	if time.Since(*deletedAt) > r.Config.ContentUndoWindow {
+		return errUndoWindowPassed
+	}
+	return nil
+}
+
+// ContentPurgeJob removes posts, reels and comments for good once they have
+// been deleted for longer than the retention period
+func (r *Resolver) ContentPurgeJob() jobs.Job {
+	return jobs.Job{
+		Name:     "content-purge",
+		Interval: time.Hour,
+		Run:      r.purgeDeletedContent,
+	}
+}
+
+func (r *Resolver) purgeDeletedContent(ctx context.Context) error {
+	before := time.Now().Add(-r.Config.ContentRetention)
+
+	posts, err := r.PostRepo.FindDeletedBefore(ctx, before, contentPurgeBatch)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if err := r.purgePost(ctx, post.ID); err != nil {
+			log.Printf("Failed to purge post %s: %v", post.ID.Hex(), err)
+		}
+	}
+
+	reels, err := r.ReelRepo.FindDeletedBefore(ctx, before, contentPurgeBatch)
+	if err != nil {
+		return err
+	}
+	for _, reel := range reels {
+		if err := r.purgeReel(ctx, reel.ID); err != nil {
+			log.Printf("Failed to purge reel %s: %v", reel.ID.Hex(), err)
+		}
+	}
+
+	comments, err := r.CommentRepo.FindDeletedBefore(ctx, before, contentPurgeBatch)
+	if err != nil {
+		return err
+	}
+	if len(comments) > 0 {
+		ids := make([]primitive.ObjectID, len(comments))
+		for i, comment := range comments {
+			ids[i] = comment.ID
+		}
+		if err := r.purgeComments(ctx, ids); err != nil {
+			log.Printf("Failed to purge %d comments: %v", len(ids), err)
+		}
+	}
+	return nil
+}
+
+// purgePost removes a post with its comments, revisions, engagements and
+// timeline entries. The post goes last so a failed purge is retried.
+func (r *Resolver) purgePost(ctx context.Context, postID primitive.ObjectID) error {
+	comments, err := r.CommentRepo.FindIDsByPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if err := r.purgeComments(ctx, comments); err != nil {
+		return err
+	}
+	if err := r.EngagementRepo.DeleteByTargets(ctx, models.TargetPost, []primitive.ObjectID{postID}); err != nil {
+		return err
+	}
+	if err := r.PostRevisionRepo.DeleteByPost(ctx, postID); err != nil {
+		return err
+	}
+	if err := r.TimelineRepo.DeleteByPost(ctx, postID); err != nil {
+		return err
+	}
+	return r.PostRepo.HardDelete(ctx, postID)
+}
+
+// purgeReel removes a reel with its comments and engagements
+func (r *Resolver) purgeReel(ctx context.Context, reelID primitive.ObjectID) error {
+	comments, err := r.CommentRepo.FindIDsByReel(ctx, reelID)
+	if err != nil {
+		return err
+	}
+	if err := r.purgeComments(ctx, comments); err != nil {
+		return err
+	}
+	if err := r.EngagementRepo.DeleteByTargets(ctx, models.TargetReel, []primitive.ObjectID{reelID}); err != nil {
+		return err
+	}
+	return r.ReelRepo.HardDelete(ctx, reelID)
+}
+
+// purgeComments removes comments and their engagements
+func (r *Resolver) purgeComments(ctx context.Context, ids []primitive.ObjectID) error {
+	if err := r.EngagementRepo.DeleteByTargets(ctx, models.TargetComment, ids); err != nil {
+		return err
+	}
+	return r.CommentRepo.DeleteByIDs(ctx, ids)
+}
@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/rbac"
+)
+
+// AuthDirective implements @auth
+func (r *Resolver) AuthDirective(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if _, err := auth.GetUserFromContext(ctx); err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	return next(ctx)
+}
+
+// HasRoleDirective implements @hasRole. Callers qualify with the named role
+// or any role granting every permission it does, such as admin. Roles with
+// permissions are subject to the same token scope and MFA rules as other
+// admin actions.
+func (r *Resolver) HasRoleDirective(ctx context.Context, obj interface{}, next graphql.Resolver, role string) (interface{}, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	exists, err := r.Roles.Exists(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+
+	want, err := r.Roles.Permissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Role != role {
+		missing, err := r.Roles.Missing(ctx, claims.Role, want)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			return nil, errors.New("forbidden")
+		}
+	}
+
+	if len(want) > 0 {
+		if _, err := r.requireStaff(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return next(ctx)
+}
+
+// HasPermissionDirective implements @hasPermission. Resolvers check the
+// permission again, so it is also enforced where the directive is missing.
+func (r *Resolver) HasPermissionDirective(ctx context.Context, obj interface{}, next graphql.Resolver, permission model.Permission) (interface{}, error) {
+	if _, err := r.requirePermission(ctx, rbac.Permission(permission)); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
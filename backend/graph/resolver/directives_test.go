@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devthreads/backend/config"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/rbac"
+)
+
+type staticRoles []*models.Role
+
+func (s staticRoles) FindAll(ctx context.Context) ([]*models.Role, error) {
+	return s, nil
+}
+
+func TestHasRoleDirective(t *testing.T) {
+	r := &Resolver{
+		Config: &config.Config{RequireAdminMFA: true},
+		Roles: rbac.NewRegistry(staticRoles{
+			{Name: "senior-moderator", Permissions: append(permissionNames(rbac.BuiltinPermissions(rbac.RoleModerator)), string(rbac.PermissionBanUsers))},
+			{Name: "log-reader", Permissions: []string{string(rbac.PermissionViewModerationLogs)}},
+		}, time.Minute),
+	}
+	next := func(ctx context.Context) (interface{}, error) { return true, nil }
+
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		role   string
+		ok     bool
+	}{
+		{"signed out", nil, rbac.RoleUser, false},
+		{"any user holds the user role", &auth.Claims{Role: rbac.RoleUser}, rbac.RoleUser, true},
+		{"same role", &auth.Claims{Role: rbac.RoleModerator, MFA: true}, rbac.RoleModerator, true},
+		{"admin outranks moderator", &auth.Claims{Role: rbac.RoleAdmin, MFA: true}, rbac.RoleModerator, true},
+		{"custom role holding more", &auth.Claims{Role: "senior-moderator", MFA: true}, rbac.RoleModerator, true},
+		{"custom role holding less", &auth.Claims{Role: "log-reader", MFA: true}, rbac.RoleModerator, false},
+		{"user below moderator", &auth.Claims{Role: rbac.RoleUser}, rbac.RoleModerator, false},
+		{"moderator below admin", &auth.Claims{Role: rbac.RoleModerator, MFA: true}, rbac.RoleAdmin, false},
+		{"staff role without two-factor", &auth.Claims{Role: rbac.RoleAdmin}, rbac.RoleModerator, false},
+		{"token without the admin scope", &auth.Claims{Role: rbac.RoleAdmin, MFA: true, TokenID: "pat", Scopes: []string{auth.ScopeRead}}, rbac.RoleModerator, false},
+		{"unknown role", &auth.Claims{Role: rbac.RoleAdmin, MFA: true}, "deleted", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = context.WithValue(ctx, "user", tt.claims)
+			}
+
+			_, err := r.HasRoleDirective(ctx, nil, next, tt.role)
+			if tt.ok != (err == nil) {
+				t.Fatalf("HasRoleDirective(%s) error = %v, want ok = %v", tt.role, err, tt.ok)
+			}
+		})
+	}
+}
+
+func permissionNames(permissions []rbac.Permission) []string {
+	names := make([]string, len(permissions))
+	for i, p := range permissions {
+		names[i] = string(p)
+	}
+	return names
+}
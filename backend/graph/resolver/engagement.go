@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LikePost likes or unlikes a post
+func (r *mutationResolver) LikePost(ctx context.Context, id string, liked *bool) (*model.EngagementToggle, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid post id")
+	}
+	if _, err := r.findVisiblePost(ctx, postID); err != nil {
+		return nil, err
+	}
+	return r.toggleEngagement(ctx, &models.Engagement{
+		UserID:     userID,
+		TargetType: models.TargetPost,
+		TargetID:   postID,
+		Type:       models.EngagementLike,
+	}, liked, func(delta int) (int, error) {
+		return r.PostRepo.AdjustCount(ctx, postID, "likes_count", delta)
+	})
+}
+
+// LikeReel likes or unlikes a reel
+func (r *mutationResolver) LikeReel(ctx context.Context, id string, liked *bool) (*model.EngagementToggle, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	reelID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid reel id")
+	}
+	if _, err := r.findVisibleReel(ctx, reelID); err != nil {
+		return nil, err
+	}
+	return r.toggleEngagement(ctx, &models.Engagement{
+		UserID:     userID,
+		TargetType: models.TargetReel,
+		TargetID:   reelID,
+		Type:       models.EngagementLike,
+	}, liked, func(delta int) (int, error) {
+		return r.ReelRepo.AdjustCount(ctx, reelID, "likes_count", delta)
+	})
+}
+
+// LikeComment likes or unlikes a comment
+func (r *mutationResolver) LikeComment(ctx context.Context, id string, liked *bool) (*model.EngagementToggle, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid comment id")
+	}
+	if _, err := r.findVisibleComment(ctx, commentID); err != nil {
+		return nil, err
+	}
+	return r.toggleEngagement(ctx, &models.Engagement{
+		UserID:     userID,
+		TargetType: models.TargetComment,
+		TargetID:   commentID,
+		Type:       models.EngagementLike,
+	}, liked, func(delta int) (int, error) {
+		return r.CommentRepo.AdjustCount(ctx, commentID, "likes_count", delta)
+	})
+}
+
+// toggleEngagement sets whether the user has the engagement, flipping it if
+// active is nil, and moves the target's counter with it. The unique index on
+// engagements decides concurrent calls: only the call that actually inserts
+// or removes the engagement moves the counter, so double clicks neither
+// duplicate an engagement nor drift the count. If the counter cannot be
+// updated the engagement change is undone.
+func (r *Resolver) toggleEngagement(ctx context.Context, engagement *models.Engagement, active *bool, adjust func(delta int) (int, error)) (*model.EngagementToggle, error) {
+	want := false
+	if active != nil {
+		want = *active
+	} else {
+		exists, err := r.EngagementRepo.Exists(ctx, engagement)
+		if err != nil {
+			return nil, err
+		}
+		want = !exists
+	}
+
+	delta := 0
+	if want {
+		created, err := r.EngagementRepo.Create(ctx, engagement)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			delta = 1
+		}
+	} else {
+		deleted, err := r.EngagementRepo.Delete(ctx, engagement)
+		if err != nil {
+			return nil, err
+		}
+		if deleted {
+			delta = -1
+		}
+	}
+
+	count, err := adjust(delta)
+	if err != nil {
+		r.undoEngagement(ctx, engagement, delta)
+		return nil, err
+	}
+
+	return &model.EngagementToggle{Active: want, Count: count}, nil
+}
+
+// undoEngagement reverts the change toggleEngagement made to the engagement
+// when the counter could not follow it
+func (r *Resolver) undoEngagement(ctx context.Context, engagement *models.Engagement, delta int) {
+	var err error
+	switch delta {
+	case 1:
+		_, err = r.EngagementRepo.Delete(ctx, engagement)
+	case -1:
+		_, err = r.EngagementRepo.Create(ctx, engagement)
+	}
+	if err != nil {
+		log.Printf("Failed to undo %s of %s %s by user %s: %v",
+			engagement.Type, engagement.TargetType, engagement.TargetID.Hex(), engagement.UserID.Hex(), err)
+	}
+}
@@ -0,0 +1,214 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/foryou"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Feed returns public posts by recency or hot score, or the signed-in user's
+// FOLLOWING or FOR_YOU feed
+func (r *queryResolver) Feed(ctx context.Context, filter *model.FeedFilter, window *model.TrendingWindow, first *int, after *string) (*model.PostConnection, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	var page *pagination.Page[models.Post]
+	var err error
+
+	switch {
+	case filter != nil && *filter == model.FeedFilterFollowing:
+		return r.followingFeed(ctx, pageSize(first), pageCursor(after))
+	case filter != nil && *filter == model.FeedFilterForYou:
+		page, err = r.forYouFeed(ctx, pageSize(first), pageCursor(after))
+	case filter != nil && *filter == model.FeedFilterTrending:
+		return r.trendingFeed(ctx, r.trendingWindow(window), pageSize(first), pageCursor(after))
+	default:
+		page, err = r.PostRepo.Feed(ctx, pageSize(first), pageCursor(after))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.postConnection(ctx, page.Edges, convertPageInfo(page))
+}
+
+// Post opens a post by direct link, which reaches unlisted posts too
+func (r *queryResolver) Post(ctx context.Context, id string) (*model.Post, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid post id")
+	}
+
+	post, err := r.findVisiblePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return r.convertPostWithAuthor(ctx, post)
+}
+
+// UserPosts returns a user's posts, newest first. Everyone sees the public
+// ones, followers also the FOLLOWERS_ONLY ones, and the author all of them.
+// The list is empty to callers the profile is not visible to.
+func (r *queryResolver) UserPosts(ctx context.Context, userID string, first *int, after *string) (*model.PostConnection, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	authorID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	visible, err := r.profileListsVisible(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return &model.PostConnection{Edges: []*model.PostEdge{}, PageInfo: &model.PageInfo{}}, nil
+	}
+
+	visibilities, err := r.listedBy(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.PostRepo.FindByAuthor(ctx, authorID, visibilities, pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+	return r.postConnection(ctx, page.Edges, convertPageInfo(page))
+}
+
+// followingFeed reads the caller's timeline, which holds the posts of the
+// users they follow
+func (r *queryResolver) followingFeed(ctx context.Context, first int, after string) (*model.PostConnection, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("sign in to see the FOLLOWING feed")
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	page, err := r.TimelineRepo.Find(ctx, userID, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(page.Edges))
+	for i, edge := range page.Edges {
+		ids[i] = edge.Node.PostID
+	}
+	found, err := r.PostRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*models.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	// Posts deleted or hidden since they were delivered are skipped, so a
+	// page can come back short
+	edges := make([]pagination.Edge[models.Post], 0, len(page.Edges))
+	for _, edge := range page.Edges {
+		if p, ok := byID[edge.Node.PostID]; ok && inTimelines(p.Visibility) {
+			edges = append(edges, pagination.Edge[models.Post]{Node: p, Cursor: edge.Cursor})
+		}
+	}
+
+	return r.postConnection(ctx, edges, convertPageInfo(page))
+}
+
+// trendingFeed pages through a snapshot of the hot score ranking. The posts
+// are loaded as they are now, and those deleted or no longer public are
+// skipped, so a page can come back short.
+func (r *queryResolver) trendingFeed(ctx context.Context, window time.Duration, first int, after string) (*model.PostConnection, error) {
+	page, err := r.TrendingRepo.Find(ctx, window, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(page.Edges))
+	for i, edge := range page.Edges {
+		ids[i] = edge.Node.PostID
+	}
+	found, err := r.PostRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*models.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	edges := make([]pagination.Edge[models.Post], 0, len(page.Edges))
+	for _, edge := range page.Edges {
+		if p, ok := byID[edge.Node.PostID]; ok && p.Visibility == models.VisibilityPublic {
+			edges = append(edges, pagination.Edge[models.Post]{Node: p, Cursor: edge.Cursor})
+		}
+	}
+
+	return r.postConnection(ctx, edges, convertPageInfo(page))
+}
+
+// forYouFeed ranks posts for the caller by what they engaged with and who
+// they follow, leaving out what they have seen or muted
+func (r *queryResolver) forYouFeed(ctx context.Context, first int, after string) (*pagination.Page[models.Post], error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("sign in to see the FOR_YOU feed")
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	viewer := foryou.Viewer{ID: user.ID, MutedUsers: user.MutedUsers, MutedTags: user.MutedTags}
+	return r.ForYou.Feed(ctx, viewer, first, after)
+}
+
+// ViewPost records a VIEW engagement the first time the caller sees a post
+func (r *mutationResolver) ViewPost(ctx context.Context, id string) (bool, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return false, errors.New("unauthorized")
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid post id")
+	}
+	if _, err := r.findVisiblePost(ctx, postID); err != nil {
+		return false, err
+	}
+
+	created, err := r.EngagementRepo.Create(ctx, &models.Engagement{
+		UserID:     userID,
+		TargetType: models.TargetPost,
+		TargetID:   postID,
+		Type:       models.EngagementView,
+	})
+	if err != nil {
+		return false, err
+	}
+	if !created {
+		return true, nil
+	}
+	if _, err := r.PostRepo.AdjustCount(ctx, postID, "views_count", 1); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,311 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// fanOutBatch is how many followers' timelines one insert writes to
+	fanOutBatch = 1000
+	// fanOutTimeout bounds delivering one post to every follower
+	fanOutTimeout = 10 * time.Minute
+	// timelineBackfill is how many recent posts a new follow adds to the
+	// follower's timeline
+	timelineBackfill = 20
+)
+
+// Follow makes the caller follow the user. Following someone already
+// followed succeeds without notifying them again.
+func (r *mutationResolver) Follow(ctx context.Context, userID string) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	followerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	followeeID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, errors.New("invalid user id")
+	}
+	if followeeID == followerID {
+		return false, errors.New("you cannot follow yourself")
+	}
+
+	if _, err := r.UserRepo.FindByID(ctx, followeeID); err != nil {
+		return false, err
+	}
+
+	created, err := r.FollowRepo.Create(ctx, &models.Follow{FollowerID: followerID, FolloweeID: followeeID})
+	if err != nil {
+		return false, err
+	}
+	if !created {
+		return true, nil
+	}
+
+	if err := r.UserRepo.UpdateFollowCounts(ctx, []primitive.ObjectID{followerID}, "following_count", 1); err != nil {
+		return false, err
+	}
+	if err := r.UserRepo.UpdateFollowCounts(ctx, []primitive.ObjectID{followeeID}, "followers_count", 1); err != nil {
+		return false, err
+	}
+
+	if err := r.backfillTimeline(ctx, followerID, followeeID); err != nil {
+		log.Printf("Failed to backfill timeline of user %s: %v", followerID.Hex(), err)
+	}
+
+	r.notify(ctx, followeeID, models.NotificationFollow, fmt.Sprintf("%s started following you", claims.Username), &followerID)
+
+	return true, nil
+}
+
+// Unfollow stops the caller following the user and takes the user's posts
+// out of the caller's FOLLOWING feed
+func (r *mutationResolver) Unfollow(ctx context.Context, userID string) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	followerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	followeeID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, errors.New("invalid user id")
+	}
+
+	deleted, err := r.FollowRepo.Delete(ctx, followerID, followeeID)
+	if err != nil {
+		return false, err
+	}
+	if !deleted {
+		return true, nil
+	}
+
+	if err := r.UserRepo.UpdateFollowCounts(ctx, []primitive.ObjectID{followerID}, "following_count", -1); err != nil {
+		return false, err
+	}
+	if err := r.UserRepo.UpdateFollowCounts(ctx, []primitive.ObjectID{followeeID}, "followers_count", -1); err != nil {
+		return false, err
+	}
+
+	if err := r.TimelineRepo.RemoveAuthor(ctx, followerID, followeeID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Followers lists who follows the user, most recent first
+func (r *userResolver) Followers(ctx context.Context, obj *model.User, first *int, after *string) (*model.FollowConnection, error) {
+	return r.followConnection(ctx, obj, first, after, true)
+}
+
+// Following lists who the user follows, most recent first
+func (r *userResolver) Following(ctx context.Context, obj *model.User, first *int, after *string) (*model.FollowConnection, error) {
+	return r.followConnection(ctx, obj, first, after, false)
+}
+
+// ViewerFollows reports whether the signed-in user follows obj
+func (r *userResolver) ViewerFollows(ctx context.Context, obj *model.User) (*bool, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, nil
+	}
+
+	viewerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	userID, err := primitive.ObjectIDFromHex(obj.ID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	follows, err := r.FollowRepo.Exists(ctx, viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &follows, nil
+}
+
+// followConnection pages through the followers of obj, or the users obj
+// follows. The lists are hidden like the bio when the profile is not visible
+// to the caller.
+func (r *userResolver) followConnection(ctx context.Context, obj *model.User, first *int, after *string, followers bool) (*model.FollowConnection, error) {
+	userID, err := primitive.ObjectIDFromHex(obj.ID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.FollowConnection{
+		Edges:      []*model.FollowEdge{},
+		PageInfo:   &model.PageInfo{},
+		TotalCount: user.FollowingCount,
+	}
+	if followers {
+		connection.TotalCount = user.FollowersCount
+	}
+
+	if !profileVisible(user.Privacy.ProfileVisibility, r.userViewFor(ctx, user)) {
+		return connection, nil
+	}
+
+	var page *pagination.Page[models.Follow]
+	if followers {
+		page, err = r.FollowRepo.FindFollowers(ctx, userID, pageSize(first), pageCursor(after))
+	} else {
+		page, err = r.FollowRepo.FindFollowing(ctx, userID, pageSize(first), pageCursor(after))
+	}
+	if err != nil {
+		return nil, err
+	}
+	connection.PageInfo = convertPageInfo(page)
+
+	otherID := func(f *models.Follow) primitive.ObjectID {
+		if followers {
+			return f.FollowerID
+		}
+		return f.FolloweeID
+	}
+
+	ids := make([]primitive.ObjectID, len(page.Edges))
+	for i, edge := range page.Edges {
+		ids[i] = otherID(edge.Node)
+	}
+	users, err := r.UserRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	for _, edge := range page.Edges {
+		u, ok := byID[otherID(edge.Node)]
+		if !ok {
+			continue
+		}
+		connection.Edges = append(connection.Edges, &model.FollowEdge{
+			Cursor:     edge.Cursor,
+			Node:       convertUser(u, r.userViewFor(ctx, u)),
+			FollowedAt: edge.Node.CreatedAt,
+		})
+	}
+
+	return connection, nil
+}
+
+// queueFanOut delivers a new post to its author's followers in the
+// background, so creating a post does not wait on every follower's timeline
+func (r *Resolver) queueFanOut(ctx context.Context, post *models.Post) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fanOutTimeout)
+		defer cancel()
+
+		if err := r.fanOutPost(ctx, post); err != nil {
+			log.Printf("Failed to deliver post %s to followers: %v", post.ID.Hex(), err)
+		}
+	}()
+}
+
+// fanOutPost adds the post to the timeline of each of its author's followers,
+// a batch of followers at a time
+func (r *Resolver) fanOutPost(ctx context.Context, post *models.Post) error {
+	after := ""
+	for {
+		page, err := r.FollowRepo.FindFollowers(ctx, post.AuthorID, fanOutBatch, after)
+		if err != nil {
+			return err
+		}
+
+		entries := make([]*models.TimelineEntry, len(page.Edges))
+		for i, edge := range page.Edges {
+			entries[i] = &models.TimelineEntry{
+				UserID:    edge.Node.FollowerID,
+				PostID:    post.ID,
+				AuthorID:  post.AuthorID,
+				CreatedAt: post.CreatedAt,
+			}
+		}
+		if err := r.TimelineRepo.Add(ctx, entries); err != nil {
+			return err
+		}
+
+		if !page.HasNextPage {
+			return nil
+		}
+		after = *page.EndCursor()
+	}
+}
+
+// backfillTimeline adds the followee's recent posts for followers to a new
+// follower's timeline, so the FOLLOWING feed is not empty until they post
+func (r *Resolver) backfillTimeline(ctx context.Context, followerID, followeeID primitive.ObjectID) error {
+	page, err := r.PostRepo.FindByAuthor(ctx, followeeID, audienceFollower.listed(), timelineBackfill, "")
+	if err != nil {
+		return err
+	}
+
+	var entries []*models.TimelineEntry
+	for _, p := range page.Nodes() {
+		entries = append(entries, &models.TimelineEntry{
+			UserID:    followerID,
+			PostID:    p.ID,
+			AuthorID:  followeeID,
+			CreatedAt: p.CreatedAt,
+		})
+	}
+	return r.TimelineRepo.Add(ctx, entries)
+}
+
+// removeFollows deletes the follows made by and of the user, a batch at a
+// time, and updates the counts of everyone on the other side
+func (r *Resolver) removeFollows(ctx context.Context, userID primitive.ObjectID) error {
+	directions := []struct {
+		find  func(context.Context, primitive.ObjectID, int, string) (*pagination.Page[models.Follow], error)
+		other func(*models.Follow) primitive.ObjectID
+		field string
+	}{
+		{r.FollowRepo.FindFollowers, func(f *models.Follow) primitive.ObjectID { return f.FollowerID }, "following_count"},
+		{r.FollowRepo.FindFollowing, func(f *models.Follow) primitive.ObjectID { return f.FolloweeID }, "followers_count"},
+	}
+
+	for _, d := range directions {
+		for {
+			page, err := d.find(ctx, userID, fanOutBatch, "")
+			if err != nil {
+				return err
+			}
+			follows := page.Nodes()
+			if len(follows) == 0 {
+				break
+			}
+
+			ids := make([]primitive.ObjectID, len(follows))
+			others := make([]primitive.ObjectID, len(follows))
+			for i, f := range follows {
+				ids[i] = f.ID
+				others[i] = d.other(f)
+			}
+			if err := r.UserRepo.UpdateFollowCounts(ctx, others, d.field, -1); err != nil {
+				return err
+			}
+			if err := r.FollowRepo.DeleteByIDs(ctx, ids); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/rbac"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionUser loads the signed-in user. Credentials can only be managed from
+// an interactive session, never with a personal access token.
+func (r *Resolver) sessionUser(ctx context.Context) (*models.User, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	return r.UserRepo.FindByID(ctx, userID)
+}
+
+// requireSession returns the caller's claims unless they are unauthenticated
+// or using a personal access token
+func (r *Resolver) requireSession(ctx context.Context) (*auth.Claims, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	if claims.ViaPersonalAccessToken() {
+		return nil, errors.New("this action is not available to personal access tokens")
+	}
+	return claims, nil
+}
+
+// requireScope returns the caller's claims when their credential grants scope
+func (r *Resolver) requireScope(ctx context.Context, scope string) (*auth.Claims, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+	if !claims.HasScope(scope) {
+		return nil, fmt.Errorf("token is missing the %s scope", scope)
+	}
+	return claims, nil
+}
+
+// requirePermission returns the caller's claims when their role grants
+// permission
+func (r *Resolver) requirePermission(ctx context.Context, permission rbac.Permission) (*auth.Claims, error) {
+	claims, err := r.requireStaff(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := r.Roles.HasPermission(ctx, claims.Role, permission)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, errors.New("forbidden")
+	}
+
+	return claims, nil
+}
+
+// requireStaff returns the caller's claims when their role grants any
+// permission. Personal access tokens need the admin scope, and with
+// RequireAdminMFA set the session must have used a second factor.
+func (r *Resolver) requireStaff(ctx context.Context) (*auth.Claims, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil || !claims.HasScope(auth.ScopeAdmin) {
+		return nil, errors.New("forbidden")
+	}
+
+	staff, err := r.Roles.IsStaff(ctx, claims.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !staff {
+		return nil, errors.New("forbidden")
+	}
+
+	if r.Config.RequireAdminMFA && !claims.MFA {
+		return nil, errors.New("two-factor authentication is required for admin actions")
+	}
+
+	return claims, nil
+}
+
+// requireReadScope refuses reads to personal access tokens without the read
+// scope. Anonymous callers and interactive sessions can read as before.
+func (r *Resolver) requireReadScope(ctx context.Context) error {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil
+	}
+	if !claims.HasScope(auth.ScopeRead) {
+		return fmt.Errorf("token is missing the %s scope", auth.ScopeRead)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/devthreads/backend/internal/middleware"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Lockout keys are namespaced so account and IP counters cannot collide
+func accountLockoutKey(identifier string) string {
+	return "login:account:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func ipLockoutKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// checkLoginAllowed rejects the attempt while the account or the client IP is
+// locked out. Identifiers are throttled whether or not they belong to a user,
+// so lockouts cannot be used to discover registered emails. Store errors are
+// logged and let the attempt through rather than locking everyone out.
+func (r *mutationResolver) checkLoginAllowed(ctx context.Context, identifier string, userID *primitive.ObjectID) error {
+	client := middleware.ClientInfoFromContext(ctx)
+
+	wait, err := r.AccountLockout.Check(ctx, accountLockoutKey(identifier))
+	if err != nil {
+		log.Printf("Failed to check account lockout: %v", err)
+	}
+
+	if client.IP != "" {
+		ipWait, err := r.IPLockout.Check(ctx, ipLockoutKey(client.IP))
+		if err != nil {
+			log.Printf("Failed to check IP lockout: %v", err)
+		}
+		if ipWait > wait {
+			wait = ipWait
+		}
+	}
+
+	if wait == 0 {
+		return nil
+	}
+
+	r.logSecurityEvent(ctx, models.SecurityEventLoginBlocked, identifier, userID, "")
+	return fmt.Errorf("too many failed login attempts, try again in %s", wait.Round(time.Second))
+}
+
+// recordLoginFailure counts a failed attempt against the account and the
+// client IP, locking either once it reaches its limit
+func (r *mutationResolver) recordLoginFailure(ctx context.Context, identifier string, userID *primitive.ObjectID, reason string) {
+	client := middleware.ClientInfoFromContext(ctx)
+	r.logSecurityEvent(ctx, models.SecurityEventLoginFailed, identifier, userID, reason)
+
+	lock, err := r.AccountLockout.Failure(ctx, accountLockoutKey(identifier))
+	if err != nil {
+		log.Printf("Failed to record account login failure: %v", err)
+	} else if lock > 0 {
+		r.logSecurityEvent(ctx, models.SecurityEventLockout, identifier, userID, "account locked for "+lock.String())
+	}
+
+	if client.IP == "" {
+		return
+	}
+
+	lock, err = r.IPLockout.Failure(ctx, ipLockoutKey(client.IP))
+	if err != nil {
+		log.Printf("Failed to record IP login failure: %v", err)
+	} else if lock > 0 {
+		r.logSecurityEvent(ctx, models.SecurityEventLockout, identifier, userID, "IP locked for "+lock.String())
+	}
+}
+
+// recordLoginSuccess clears the account's failures. The IP counter is left
+// alone, or signing in to one account would reset a spraying attacker's count.
+func (r *mutationResolver) recordLoginSuccess(ctx context.Context, identifier string, userID primitive.ObjectID, detail string) {
+	if err := r.AccountLockout.Reset(ctx, accountLockoutKey(identifier)); err != nil {
+		log.Printf("Failed to reset account lockout: %v", err)
+	}
+	r.logSecurityEvent(ctx, models.SecurityEventLoginSucceeded, identifier, &userID, detail)
+}
+
+func (r *mutationResolver) logSecurityEvent(ctx context.Context, eventType, identifier string, userID *primitive.ObjectID, detail string) {
+	client := middleware.ClientInfoFromContext(ctx)
+	event := &models.SecurityEvent{
+		Type:       eventType,
+		UserID:     userID,
+		Identifier: identifier,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		Detail:     detail,
+	}
+
+	log.Printf("Security event %s identifier=%q ip=%s %s", eventType, identifier, client.IP, detail)
+	if err := r.SecurityEventRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record security event %s: %v", eventType, err)
+	}
+}
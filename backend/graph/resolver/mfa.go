@@ -0,0 +1,204 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const totpIssuer = "DevThreads"
+
+var errInvalidMFACode = errors.New("invalid authentication code")
+
+// VerifyMfa completes a two-factor login with a TOTP or recovery code
+func (r *mutationResolver) VerifyMfa(ctx context.Context, challengeToken string, code string) (*model.AuthPayload, error) {
+	userID, err := r.AuthService.ValidateMFAChallenge(challengeToken)
+	if err != nil {
+		return nil, errors.New("login challenge is invalid or expired")
+	}
+
+	id, _ := primitive.ObjectIDFromHex(userID)
+	user, err := r.UserRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("login challenge is invalid or expired")
+	}
+
+	if user.BannedUntil != nil && user.BannedUntil.After(time.Now()) {
+		return nil, fmt.Errorf("account is banned until %s", user.BannedUntil.Format("2006-01-02 15:04:05"))
+	}
+
+	// Code guesses count against the same account lockout as passwords
+	if err := r.checkLoginAllowed(ctx, user.Email, &user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := r.verifySecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			r.recordLoginFailure(ctx, user.Email, &user.ID, "invalid second factor")
+		}
+		return nil, err
+	}
+
+	r.recordLoginSuccess(ctx, user.Email, user.ID, "second factor")
+	return r.issueTokens(ctx, user, true)
+}
+
+// EnrollTotp starts two-factor enrollment by generating a new secret
+func (r *mutationResolver) EnrollTotp(ctx context.Context) (*model.TotpEnrollment, error) {
+	user, err := r.sessionUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.UserRepo.SetPendingTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TotpEnrollment{
+		Secret:     secret,
+		OtpauthURL: auth.TOTPProvisioningURI(secret, totpIssuer, user.Email),
+	}, nil
+}
+
+// ConfirmTotp enables two-factor authentication once the user proves their
+// authenticator works, and returns recovery codes that are shown only once
+func (r *mutationResolver) ConfirmTotp(ctx context.Context, code string) ([]string, error) {
+	user, err := r.sessionUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, errors.New("start two-factor enrollment first")
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return nil, errInvalidMFACode
+	}
+
+	codes, hashes, err := r.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.UserRepo.EnableTOTP(ctx, user.ID, user.TOTPPendingSecret, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTotp turns off two-factor authentication after checking a code
+func (r *mutationResolver) DisableTotp(ctx context.Context, code string) (bool, error) {
+	user, err := r.sessionUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if !user.TOTPEnabled {
+		return false, errors.New("two-factor authentication is not enabled")
+	}
+
+	if r.Config.RequireAdminMFA {
+		staff, err := r.Roles.IsStaff(ctx, user.Role)
+		if err != nil {
+			return false, err
+		}
+		if staff {
+			return false, errors.New("admins and moderators must keep two-factor authentication enabled")
+		}
+	}
+
+	if err := r.verifySecondFactor(ctx, user, code); err != nil {
+		return false, err
+	}
+
+	if err := r.UserRepo.DisableTOTP(ctx, user.ID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a code
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	user, err := r.sessionUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := r.verifySecondFactor(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := r.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.UserRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// verifySecondFactor accepts a current TOTP code that has not been used
+// before, or an unused recovery code
+func (r *mutationResolver) verifySecondFactor(ctx context.Context, user *models.User, code string) error {
+	if step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		fresh, err := r.UserRepo.RecordTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := r.UserRepo.UseRecoveryCode(ctx, user.ID, r.AuthService.HashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+func (r *mutationResolver) generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = r.AuthService.HashToken(code)
+	}
+	return codes, hashes, nil
+}
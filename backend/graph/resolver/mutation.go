@@ -6,16 +6,20 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+	"net/mail"
+	"strconv"
 	"time"
 
 	"github.com/devthreads/backend/graph/model"
 	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/middleware"
 	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/ranking"
+	"github.com/devthreads/backend/internal/rbac"
+	"github.com/devthreads/backend/internal/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -27,6 +31,15 @@ func (r *Resolver) Mutation() MutationResolver {
 
 // Signup creates a new user account
 func (r *mutationResolver) Signup(ctx context.Context, input model.SignupInput) (*model.AuthPayload, error) {
+	address, err := mail.ParseAddress(input.Email)
+	if err != nil || address.Address != input.Email {
+		return nil, errors.New("invalid email address")
+	}
+
+	if err := validatePassword(input.Password); err != nil {
+		return nil, err
+	}
+
 	// Check if user already exists
 	existingUser, _ := r.UserRepo.FindByEmail(ctx, input.Email)
 	if existingUser != nil {
@@ -51,44 +64,36 @@ func (r *mutationResolver) Signup(ctx context.Context, input model.SignupInput)
 		Password:    hashedPassword,
 		DisplayName: *input.DisplayName,
 		Reputation:  0,
-		IsAdmin:     false,
 	}
 
 	if err := r.UserRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
-	// Generate tokens
-	accessToken, err := r.AuthService.GenerateAccessToken(user.ID.Hex(), user.Username, user.IsAdmin)
-	if err != nil {
-		return nil, err
-	}
-
-	refreshToken, err := r.AuthService.GenerateRefreshToken()
-	if err != nil {
-		return nil, err
+	if err := r.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID.Hex(), err)
 	}
 
-	// Store refresh token (simplified - should be stored in DB)
-	// TODO: Store in refresh_tokens collection
-
-	return &model.AuthPayload{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         convertUser(user),
-	}, nil
+	// Generate tokens
+	return r.issueTokens(ctx, user, false)
 }
 
 // Login authenticates a user
-func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.AuthPayload, error) {
+func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (model.LoginResult, error) {
+	if err := r.checkLoginAllowed(ctx, input.Email, nil); err != nil {
+		return nil, err
+	}
+
 	// Find user
 	user, err := r.UserRepo.FindByEmail(ctx, input.Email)
 	if err != nil {
+		r.recordLoginFailure(ctx, input.Email, nil, "unknown account")
 		return nil, errors.New("invalid credentials")
 	}
 
 	// Check password
 	if !r.AuthService.CheckPassword(input.Password, user.Password) {
+		r.recordLoginFailure(ctx, input.Email, &user.ID, "wrong password")
 		return nil, errors.New("invalid credentials")
 	}
 
@@ -97,140 +102,389 @@ func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*
 		return nil, fmt.Errorf("account is banned until %s", user.BannedUntil.Format("2006-01-02 15:04:05"))
 	}
 
-	// Generate tokens
-	accessToken, err := r.AuthService.GenerateAccessToken(user.ID.Hex(), user.Username, user.IsAdmin)
+	// With two-factor enabled the failures are only cleared once the second
+	// factor succeeds, so correct passwords cannot reset code guessing
+	if !user.TOTPEnabled {
+		r.recordLoginSuccess(ctx, input.Email, user.ID, "password")
+	}
+
+	return r.completeLogin(ctx, user)
+}
+
+// LoginWithGithub signs in with a code from the /auth/github/callback route,
+// which has verified the state against the nonce cookie of the browser that
+// started the flow. The state is accepted once.
+func (r *Resolver) LoginWithGithub(ctx context.Context, code string, state *auth.OAuthState) (model.LoginResult, error) {
+	if err := r.redeemOAuthState(ctx, primitive.NilObjectID, state); err != nil {
+		return nil, err
+	}
+	return (&mutationResolver{r}).loginWithGithub(ctx, code)
+}
+
+func (r *mutationResolver) loginWithGithub(ctx context.Context, code string) (model.LoginResult, error) {
+
+	githubUser, err := r.GithubOAuth.Authenticate(ctx, code)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := r.AuthService.GenerateRefreshToken()
+	// Find or create user
+	githubID := strconv.FormatInt(githubUser.ID, 10)
+	user, err := r.UserRepo.FindByGithubID(ctx, githubID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.AuthPayload{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         convertUser(user),
-	}, nil
+	if user == nil {
+		// GitHub only reports verified primary emails, so an account registered
+		// with the same address belongs to the same person, provided its owner
+		// verified the address too. Otherwise whoever registered it may not
+		// own it, and merging would leave their password working.
+		existing, err := r.UserRepo.FindByEmail(ctx, githubUser.Email)
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return nil, err
+		}
+		if existing != nil {
+			if existing.GithubID != "" {
+				return nil, errors.New("account is already linked to a different GitHub account")
+			}
+			if !existing.EmailVerified {
+				return nil, errors.New("an account with this email already exists; sign in with its password and link GitHub from your settings")
+			}
+			if err := r.UserRepo.SetGithubID(ctx, existing.ID, githubID); err != nil {
+				return nil, err
+			}
+			existing.GithubID = githubID
+			user = existing
+		}
+	}
+
+	if user == nil {
+		username, err := r.availableUsername(ctx, githubUser.Login, githubID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Create new user
+		user = &models.User{
+			Username:      username,
+			Email:         githubUser.Email,
+			EmailVerified: true,
+			DisplayName:   githubUser.Name,
+			AvatarURL:     githubUser.AvatarURL,
+			GithubID:      githubID,
+			Reputation:    0,
+		}
+
+		if err := r.UserRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.BannedUntil != nil && user.BannedUntil.After(time.Now()) {
+		return nil, fmt.Errorf("account is banned until %s", user.BannedUntil.Format("2006-01-02 15:04:05"))
+	}
+
+	return r.completeLogin(ctx, user)
 }
 
-// LoginWithGithub authenticates using GitHub OAuth
-func (r *mutationResolver) LoginWithGithub(ctx context.Context, code string) (*model.AuthPayload, error) {
-	// Exchange code for access token
-	tokenURL := "https://github.com/login/oauth/access_token"
-	data := map[string]string{
-		"client_id":     r.Config.GithubClientID,
-		"client_secret": r.Config.GithubClientSecret,
-		"code":          code,
+// GithubLinkURL starts linking a GitHub account to the signed-in user. The
+// returned GitHub URL carries a state issued to the user, which the callback
+// hands back to linkGithub.
+func (r *mutationResolver) GithubLinkURL(ctx context.Context) (string, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	jsonData, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", tokenURL, nil)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	state, _, err := r.AuthService.GenerateOAuthState(claims.UserID)
+	if err != nil {
+		return "", err
+	}
+	return r.GithubOAuth.AuthCodeURL(state), nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// LinkGithub attaches a GitHub account to the signed-in user. The state must
+// have been issued to the same user by githubLinkUrl and is accepted once, so
+// a code and state obtained by someone else cannot be linked to the account.
+func (r *mutationResolver) LinkGithub(ctx context.Context, code string, state string) (*model.User, error) {
+	claims, err := r.requireSession(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var tokenResp struct {
-		AccessToken string `json:"access_token"`
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	verified, err := r.AuthService.VerifyLinkState(state, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.redeemOAuthState(ctx, userID, verified); err != nil {
+		return nil, err
 	}
 
-	body, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(body, &tokenResp)
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.GithubID != "" {
+		return nil, errors.New("a GitHub account is already linked")
+	}
 
-	// Get user info from GitHub
-	userReq, _ := http.NewRequest("GET", "https://api.github.com/user", nil)
-	userReq.Header.Set("Authorization", "Bearer "+tokenResp.AccessToken)
+	githubUser, err := r.GithubOAuth.Authenticate(ctx, code)
+	if err != nil {
+		return nil, err
+	}
 
-	userResp, err := client.Do(userReq)
+	githubID := strconv.FormatInt(githubUser.ID, 10)
+	linked, err := r.UserRepo.FindByGithubID(ctx, githubID)
 	if err != nil {
 		return nil, err
 	}
-	defer userResp.Close()
+	if linked != nil {
+		return nil, errors.New("this GitHub account is linked to another user")
+	}
 
-	var githubUser struct {
-		ID        int    `json:"id"`
-		Login     string `json:"login"`
-		Email     string `json:"email"`
-		Name      string `json:"name"`
-		AvatarURL string `json:"avatar_url"`
+	if err := r.UserRepo.SetGithubID(ctx, user.ID, githubID); err != nil {
+		return nil, err
 	}
+	user.GithubID = githubID
 
-	json.NewDecoder(userResp.Body).Decode(&githubUser)
+	return convertUser(user, userViewFull), nil
+}
 
-	// Find or create user
-	githubID := fmt.Sprintf("%d", githubUser.ID)
-	user, err := r.UserRepo.FindByGithubID(ctx, githubID)
+// redeemOAuthState marks the state as used, rejecting it if it already was
+func (r *Resolver) redeemOAuthState(ctx context.Context, userID primitive.ObjectID, state *auth.OAuthState) error {
+	redeemed, err := r.AccountTokenRepo.Redeem(ctx, userID, r.AuthService.HashToken(state.Nonce), models.TokenPurposeOAuthState, state.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return auth.ErrInvalidOAuthState
+	}
+	return nil
+}
 
-	if user == nil {
-		// Create new user
-		user = &models.User{
-			Username:    githubUser.Login,
-			Email:       githubUser.Email,
-			DisplayName: githubUser.Name,
-			AvatarURL:   githubUser.AvatarURL,
-			GithubID:    githubID,
-			Reputation:  0,
-			IsAdmin:     false,
+// UnlinkGithub detaches the GitHub account from the signed-in user
+func (r *mutationResolver) UnlinkGithub(ctx context.Context) (*model.User, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.GithubID == "" {
+		return nil, errors.New("no GitHub account is linked")
+	}
+
+	// Without a password the user would have no way left to sign in
+	if user.Password == "" {
+		return nil, errors.New("set a password before unlinking GitHub")
+	}
+
+	if err := r.UserRepo.UnsetGithubID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	user.GithubID = ""
+
+	return convertUser(user, userViewFull), nil
+}
+
+// availableUsername picks the GitHub login when it is free and falls back to
+// login-<githubID>, which is stable across retries of the same account
+func (r *mutationResolver) availableUsername(ctx context.Context, login, githubID string) (string, error) {
+	for _, candidate := range []string{login, login + "-" + githubID} {
+		existing, _ := r.UserRepo.FindByUsername(ctx, candidate)
+		if existing == nil {
+			return candidate, nil
 		}
+	}
+	return "", errors.New("could not find an available username for this GitHub account")
+}
 
-		if err := r.UserRepo.Create(ctx, user); err != nil {
-			return nil, err
+// RefreshToken rotates a refresh token and issues a new access token. Each
+// refresh token is single-use: presenting one that was already rotated is
+// treated as theft and ends the session it belongs to.
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
+	stored, err := r.RefreshTokenRepo.FindByHash(ctx, r.AuthService.HashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if stored.Revoked {
+		if stored.ReplacedBy != nil {
+			r.endSession(ctx, stored.UserID, stored.FamilyID)
+			return nil, errors.New("refresh token reuse detected, please log in again")
 		}
+		return nil, errors.New("invalid refresh token")
 	}
 
-	// Generate tokens
-	accessToken, err := r.AuthService.GenerateAccessToken(user.ID.Hex(), user.Username, user.IsAdmin)
+	if stored.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	user, err := r.UserRepo.FindByID(ctx, stored.UserID)
 	if err != nil {
-		return nil, err
+		return nil, errors.New("invalid refresh token")
 	}
 
-	refreshToken, err := r.AuthService.GenerateRefreshToken()
+	if user.BannedUntil != nil && user.BannedUntil.After(time.Now()) {
+		r.endSession(ctx, user.ID, stored.FamilyID)
+		return nil, fmt.Errorf("account is banned until %s", user.BannedUntil.Format("2006-01-02 15:04:05"))
+	}
+
+	payload, next, err := r.generateTokens(ctx, user, stored.FamilyID, stored.MFA)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.AuthPayload{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         convertUser(user),
-	}, nil
-}
+	// Lost the race against another refresh with the same token
+	rotated, err := r.RefreshTokenRepo.MarkRotated(ctx, stored.ID, next.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		r.endSession(ctx, user.ID, stored.FamilyID)
+		return nil, errors.New("refresh token reuse detected, please log in again")
+	}
 
-// RefreshToken generates a new access token
-func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
-	// TODO: Validate refresh token from database
-	// For now, this is a simplified implementation
+	client := middleware.ClientInfoFromContext(ctx)
+	if err := r.SessionRepo.Touch(ctx, stored.FamilyID, client.UserAgent, client.IP, next.ExpiresAt); err != nil {
+		log.Printf("Failed to update session %s: %v", stored.FamilyID.Hex(), err)
+	}
 
-	return nil, errors.New("refresh token functionality not fully implemented")
+	return payload, nil
 }
 
-// Logout invalidates the refresh token
+// Logout ends the caller's current session
 func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
-	// TODO: Invalidate refresh token in database
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return false, errors.New("token is not bound to a session")
+	}
+
+	if err := r.endSession(ctx, userID, sessionID); err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
 
-// Helper function to convert models.User to model.User
-func convertUser(u *models.User) *model.User {
-	return &model.User{
+// completeLogin finishes a first-factor login. Users with two-factor
+// authentication get a challenge to answer through verifyMfa instead of tokens.
+func (r *mutationResolver) completeLogin(ctx context.Context, user *models.User) (model.LoginResult, error) {
+	if user.TOTPEnabled {
+		challenge, expiresAt, err := r.AuthService.GenerateMFAChallenge(user.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		return &model.MfaChallenge{ChallengeToken: challenge, ExpiresAt: expiresAt}, nil
+	}
+
+	// Generate tokens
+	return r.issueTokens(ctx, user, false)
+}
+
+// issueTokens starts a new session for a fresh login
+func (r *mutationResolver) issueTokens(ctx context.Context, user *models.User, mfa bool) (*model.AuthPayload, error) {
+	session, err := r.startSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, _, err := r.generateTokens(ctx, user, session.ID, mfa)
+	return payload, err
+}
+
+// generateTokens creates an access token and a persisted refresh token for a
+// session; mfa records whether a second factor was used
+func (r *mutationResolver) generateTokens(ctx context.Context, user *models.User, sessionID primitive.ObjectID, mfa bool) (*model.AuthPayload, *models.RefreshToken, error) {
+	accessToken, err := r.AuthService.GenerateAccessToken(auth.AccessTokenSubject{
+		UserID:       user.ID.Hex(),
+		Username:     user.Username,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID.Hex(),
+		MFA:          mfa,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := r.AuthService.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := &models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: r.AuthService.HashToken(refreshToken),
+		FamilyID:  sessionID,
+		MFA:       mfa,
+		ExpiresAt: time.Now().Add(r.AuthService.RefreshExpiry()),
+	}
+	if err := r.RefreshTokenRepo.Create(ctx, stored); err != nil {
+		return nil, nil, err
+	}
+
+	return &model.AuthPayload{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         convertUser(user, userViewFull),
+	}, stored, nil
+}
+
+// Helper function to convert models.User to model.User. Private fields are
+// only filled in for the full view; see userViewFor.
+func convertUser(u *models.User, view userView) *model.User {
+	user := &model.User{
 		ID:          u.ID.Hex(),
 		Username:    u.Username,
 		DisplayName: &u.DisplayName,
-		Email:       u.Email,
 		AvatarURL:   &u.AvatarURL,
-		Bio:         &u.Bio,
 		Reputation:  u.Reputation,
-		IsAdmin:     u.IsAdmin,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
+
+		FollowersCount: u.FollowersCount,
+		FollowingCount: u.FollowingCount,
+	}
+
+	full := view == userViewFull
+	githubLinked := u.GithubID != ""
+
+	if full || u.Privacy.ShowEmail {
+		user.Email = &u.Email
+	}
+	if full || u.Privacy.ShowGithub {
+		user.GithubLinked = &githubLinked
+	}
+	if full || profileVisible(u.Privacy.ProfileVisibility, view) {
+		user.Bio = &u.Bio
 	}
+
+	if full {
+		isAdmin := u.Role == rbac.RoleAdmin
+		user.IsAdmin = &isAdmin
+		user.Role = &u.Role
+		user.EmailVerified = &u.EmailVerified
+		user.TotpEnabled = &u.TOTPEnabled
+		user.BannedUntil = u.BannedUntil
+		user.Privacy = convertPrivacySettings(u.Privacy)
+		user.DeletionScheduledAt = u.DeletionScheduledAt
+		user.MutedTags = u.MutedTags
+	}
+
+	return user
 }
 
 // GetCloudinarySignature generates a signature for Cloudinary uploads
@@ -253,14 +507,25 @@ func (r *mutationResolver) GetCloudinarySignature(ctx context.Context, folder st
 
 // CreatePost creates a new post
 func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePostInput) (*model.Post, error) {
-	claims, err := auth.GetUserFromContext(ctx)
+	claims, err := r.requireScope(ctx, auth.ScopeWritePosts)
 	if err != nil {
-		return nil, errors.New("unauthorized")
+		return nil, err
 	}
 
 	authorID, _ := primitive.ObjectIDFromHex(claims.UserID)
 
-	visibility := "PUBLIC"
+	var codeSnippet, language string
+	if input.CodeSnippet != nil {
+		codeSnippet = *input.CodeSnippet
+	}
+	if input.Language != nil {
+		language = *input.Language
+	}
+	if err := validatePostText(input.Content, codeSnippet); err != nil {
+		return nil, err
+	}
+
+	visibility := models.VisibilityPublic
 	if input.Visibility != nil {
 		visibility = input.Visibility.String()
 	}
@@ -268,10 +533,12 @@ func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePos
 	post := &models.Post{
 		AuthorID:    authorID,
 		Content:     input.Content,
-		CodeSnippet: *input.CodeSnippet,
-		Language:    *input.Language,
+		CodeSnippet: codeSnippet,
+		Language:    language,
 		Tags:        input.Tags,
 		Visibility:  visibility,
+		// Ranked as fresh until the next hot score refresh
+		HotScore: r.HotRanking.HotScore(ranking.Engagement{}, 0),
 	}
 
 	if err := r.PostRepo.Create(ctx, post); err != nil {
@@ -281,31 +548,90 @@ func (r *mutationResolver) CreatePost(ctx context.Context, input model.CreatePos
 	// Award reputation points
 	r.UserRepo.UpdateReputation(ctx, authorID, 5)
 
+	if inTimelines(post.Visibility) {
+		r.queueFanOut(ctx, post)
+	}
+
 	return convertPost(post), nil
 }
 
 // Helper to convert models.Post to model.Post
 func convertPost(p *models.Post) *model.Post {
 	return &model.Post{
-		ID:            p.ID.Hex(),
-		Content:       p.Content,
-		CodeSnippet:   &p.CodeSnippet,
-		Language:      &p.Language,
-		Tags:          p.Tags,
-		CreatedAt:     p.CreatedAt,
-		UpdatedAt:     p.UpdatedAt,
-		LikesCount:    p.LikesCount,
-		CommentsCount: p.CommentsCount,
-		ViewsCount:    p.ViewsCount,
-		UpvotesCount:  p.UpvotesCount,
+		ID:             p.ID.Hex(),
+		Content:        p.Content,
+		CodeSnippet:    &p.CodeSnippet,
+		Language:       &p.Language,
+		Tags:           p.Tags,
+		Visibility:     model.Visibility(p.Visibility),
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+		LikesCount:     p.LikesCount,
+		CommentsCount:  p.CommentsCount,
+		ViewsCount:     p.ViewsCount,
+		UpvotesCount:   p.UpvotesCount,
+		DownvotesCount: p.DownvotesCount,
+		Score:          p.UpvotesCount - p.DownvotesCount,
+		Reactions:      convertReactions(p.ReactionCounts),
+		Edited:         p.Edited,
+		EditedAt:       p.EditedAt,
 	}
 }
 
 // MutationResolver interface (will be generated)
 type MutationResolver interface {
 	Signup(ctx context.Context, input model.SignupInput) (*model.AuthPayload, error)
-	Login(ctx context.Context, input model.LoginInput) (*model.AuthPayload, error)
-	LoginWithGithub(ctx context.Context, code string) (*model.AuthPayload, error)
+	Login(ctx context.Context, input model.LoginInput) (model.LoginResult, error)
+	VerifyMfa(ctx context.Context, challengeToken string, code string) (*model.AuthPayload, error)
+	GithubLinkURL(ctx context.Context) (string, error)
+	LinkGithub(ctx context.Context, code string, state string) (*model.User, error)
+	UnlinkGithub(ctx context.Context) (*model.User, error)
 	RefreshToken(ctx context.Context, refreshToken string) (*model.AuthPayload, error)
 	Logout(ctx context.Context) (bool, error)
+	RevokeSession(ctx context.Context, id string) (bool, error)
+	RevokeAllOtherSessions(ctx context.Context) (int, error)
+	RequestPasswordReset(ctx context.Context, email string) (bool, error)
+	ResetPassword(ctx context.Context, token string, newPassword string) (bool, error)
+	ChangePassword(ctx context.Context, currentPassword string, newPassword string) (*model.AuthPayload, error)
+	SendVerificationEmail(ctx context.Context) (bool, error)
+	VerifyEmail(ctx context.Context, token string) (bool, error)
+	EnrollTotp(ctx context.Context) (*model.TotpEnrollment, error)
+	ConfirmTotp(ctx context.Context, code string) ([]string, error)
+	DisableTotp(ctx context.Context, code string) (bool, error)
+	RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error)
+	CreatePersonalAccessToken(ctx context.Context, input model.CreatePersonalAccessTokenInput) (*model.CreatedPersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, id string) (bool, error)
+	UpdatePrivacySettings(ctx context.Context, input model.UpdatePrivacySettingsInput) (*model.User, error)
+	Follow(ctx context.Context, userID string) (bool, error)
+	Unfollow(ctx context.Context, userID string) (bool, error)
+	MuteUser(ctx context.Context, userID string) (bool, error)
+	UnmuteUser(ctx context.Context, userID string) (bool, error)
+	MuteTag(ctx context.Context, tag string) (bool, error)
+	UnmuteTag(ctx context.Context, tag string) (bool, error)
+	ViewPost(ctx context.Context, id string) (bool, error)
+	LikePost(ctx context.Context, id string, liked *bool) (*model.EngagementToggle, error)
+	UpvotePost(ctx context.Context, id string, upvoted *bool) (*model.EngagementToggle, error)
+	LikeReel(ctx context.Context, id string, liked *bool) (*model.EngagementToggle, error)
+	LikeComment(ctx context.Context, id string, liked *bool) (*model.EngagementToggle, error)
+	VotePost(ctx context.Context, id string, vote *model.Vote) (*model.VoteResult, error)
+	VoteComment(ctx context.Context, id string, vote *model.Vote) (*model.VoteResult, error)
+	React(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string) (*model.ReactionResult, error)
+	Unreact(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string) (*model.ReactionResult, error)
+	UpdatePost(ctx context.Context, id string, input model.UpdatePostInput) (*model.Post, error)
+	DeletePost(ctx context.Context, id string) (bool, error)
+	RestorePost(ctx context.Context, id string) (bool, error)
+	DeleteReel(ctx context.Context, id string) (bool, error)
+	RestoreReel(ctx context.Context, id string) (bool, error)
+	DeleteComment(ctx context.Context, id string) (bool, error)
+	RestoreComment(ctx context.Context, id string) (bool, error)
+	RequestDataExport(ctx context.Context) (*model.DataExport, error)
+	DeleteAccount(ctx context.Context, password *string) (bool, error)
+	CancelAccountDeletion(ctx context.Context) (bool, error)
+	AdminBanUser(ctx context.Context, input model.AdminBanUserInput) (bool, error)
+	AdminUnbanUser(ctx context.Context, userID string) (bool, error)
+	AdminSetUserRole(ctx context.Context, userID string, role string) (bool, error)
+	AdminCreateRole(ctx context.Context, input model.RoleInput) (*model.Role, error)
+	AdminUpdateRole(ctx context.Context, input model.RoleInput) (*model.Role, error)
+	AdminDeleteRole(ctx context.Context, name string) (bool, error)
+	AdminDeleteComment(ctx context.Context, commentID string, reason string) (bool, error)
 }
@@ -0,0 +1,104 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MuteUser keeps the user's posts out of the caller's FOR_YOU feed
+func (r *mutationResolver) MuteUser(ctx context.Context, userID string) (bool, error) {
+	return r.setUserMuted(ctx, userID, true)
+}
+
+// UnmuteUser lets the user's posts back into the caller's FOR_YOU feed
+func (r *mutationResolver) UnmuteUser(ctx context.Context, userID string) (bool, error) {
+	return r.setUserMuted(ctx, userID, false)
+}
+
+// MuteTag keeps posts with the tag out of the caller's FOR_YOU feed. Tags are
+// matched without regard to case.
+func (r *mutationResolver) MuteTag(ctx context.Context, tag string) (bool, error) {
+	return r.setTagMuted(ctx, tag, true)
+}
+
+// UnmuteTag lets posts with the tag back into the caller's FOR_YOU feed
+func (r *mutationResolver) UnmuteTag(ctx context.Context, tag string) (bool, error) {
+	return r.setTagMuted(ctx, tag, false)
+}
+
+// MutedUsers lists the users obj has muted. Only obj can see them.
+func (r *userResolver) MutedUsers(ctx context.Context, obj *model.User) ([]*model.User, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil || claims.UserID != obj.ID {
+		return nil, nil
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authors, err := r.loadAuthors(ctx, user.MutedUsers)
+	if err != nil {
+		return nil, err
+	}
+
+	muted := []*model.User{}
+	for _, id := range user.MutedUsers {
+		if u, ok := authors[id]; ok {
+			muted = append(muted, u)
+		}
+	}
+	return muted, nil
+}
+
+func (r *mutationResolver) setUserMuted(ctx context.Context, userID string, mute bool) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	callerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	mutedID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, errors.New("invalid user id")
+	}
+	if mutedID == callerID {
+		return false, errors.New("you cannot mute yourself")
+	}
+
+	if mute {
+		if _, err := r.UserRepo.FindByID(ctx, mutedID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.UserRepo.SetMuted(ctx, callerID, "muted_users", mutedID, mute); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) setTagMuted(ctx context.Context, tag string, mute bool) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return false, errors.New("tag is required")
+	}
+
+	callerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	if err := r.UserRepo.SetMuted(ctx, callerID, "muted_tags", tag, mute); err != nil {
+		return false, err
+	}
+	return true, nil
+}
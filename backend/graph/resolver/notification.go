@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notificationTopic is the pubsub topic of a user's notifications
+func notificationTopic(userID string) string {
+	return "notifications:" + userID
+}
+
+// notify stores a notification for the user and pushes it to their open
+// subscriptions. A failure is logged rather than returned because whatever
+// triggered it has already happened.
+func (r *Resolver) notify(ctx context.Context, userID primitive.ObjectID, notificationType, content string, relatedID *primitive.ObjectID) {
+	notification := &models.Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		Content:   content,
+		RelatedID: relatedID,
+	}
+	if err := r.NotificationRepo.Create(ctx, notification); err != nil {
+		log.Printf("Failed to send %s notification to user %s: %v", notificationType, userID.Hex(), err)
+		return
+	}
+
+	r.PubSub.Publish(notificationTopic(userID.Hex()), notification)
+}
+
+// NotificationReceived streams the signed-in user's new notifications. The
+// user comes from the connection_init payload, so nobody can subscribe to
+// another user's notifications.
+func (r *subscriptionResolver) NotificationReceived(ctx context.Context) (<-chan *model.Notification, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	events := r.PubSub.Subscribe(ctx, notificationTopic(claims.UserID))
+	out := make(chan *model.Notification, 1)
+
+	go func() {
+		defer close(out)
+		for event := range events {
+			notification, ok := event.(*models.Notification)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- convertNotification(notification):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Helper to convert models.Notification to model.Notification
+func convertNotification(n *models.Notification) *model.Notification {
+	var relatedID *string
+	if n.RelatedID != nil {
+		id := n.RelatedID.Hex()
+		relatedID = &id
+	}
+	return &model.Notification{
+		ID:        n.ID.Hex(),
+		UserID:    n.UserID.Hex(),
+		Type:      model.NotificationType(n.Type),
+		Content:   n.Content,
+		RelatedID: relatedID,
+		Read:      n.Read,
+		CreatedAt: n.CreatedAt,
+	}
+}
@@ -0,0 +1,84 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageSize returns the number of items a connection returns for first
+func pageSize(first *int) int {
+	n := defaultPageSize
+	if first != nil && *first > 0 {
+		n = *first
+	}
+	if n > maxPageSize {
+		n = maxPageSize
+	}
+	return n
+}
+
+// pageCursor returns the after argument of a connection for the repositories
+func pageCursor(after *string) string {
+	if after == nil {
+		return ""
+	}
+	return *after
+}
+
+func convertPageInfo[T any](page *pagination.Page[T]) *model.PageInfo {
+	return &model.PageInfo{
+		HasNextPage: page.HasNextPage,
+		EndCursor:   page.EndCursor(),
+	}
+}
+
+// loadAuthors converts the users with the given IDs as the caller may see
+// them, loading them in one query. Users that no longer exist are missing
+// from the result.
+func (r *Resolver) loadAuthors(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]*model.User, error) {
+	users, err := r.UserRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[primitive.ObjectID]*model.User, len(users))
+	for _, u := range users {
+		authors[u.ID] = convertUser(u, r.userViewFor(ctx, u))
+	}
+	return authors, nil
+}
+
+// postConnection converts a page of posts with their authors. Posts whose
+// author no longer exists are left out; pageInfo still describes the page as
+// it was read.
+func (r *Resolver) postConnection(ctx context.Context, edges []pagination.Edge[models.Post], pageInfo *model.PageInfo) (*model.PostConnection, error) {
+	ids := make([]primitive.ObjectID, len(edges))
+	for i, edge := range edges {
+		ids[i] = edge.Node.AuthorID
+	}
+	authors, err := r.loadAuthors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.PostConnection{Edges: []*model.PostEdge{}, PageInfo: pageInfo}
+	for _, edge := range edges {
+		author, ok := authors[edge.Node.AuthorID]
+		if !ok {
+			continue
+		}
+		post := convertPost(edge.Node)
+		post.Author = author
+		connection.Edges = append(connection.Edges, &model.PostEdge{Cursor: edge.Cursor, Node: post})
+	}
+	return connection, nil
+}
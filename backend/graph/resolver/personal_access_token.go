@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// personalAccessTokenPrefixLength is how much of a token is kept for display
+const personalAccessTokenPrefixLength = 12
+
+var scopeNames = map[model.TokenScope]string{
+	model.TokenScopeRead:       auth.ScopeRead,
+	model.TokenScopeWritePosts: auth.ScopeWritePosts,
+	model.TokenScopeWriteReels: auth.ScopeWriteReels,
+	model.TokenScopeAdmin:      auth.ScopeAdmin,
+}
+
+// CreatePersonalAccessToken issues a scoped token for scripts. The token is
+// returned only once; afterwards only its prefix is shown.
+func (r *mutationResolver) CreatePersonalAccessToken(ctx context.Context, input model.CreatePersonalAccessTokenInput) (*model.CreatedPersonalAccessToken, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(input.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, s := range input.Scopes {
+		scope, ok := scopeNames[s]
+		if !ok {
+			return nil, errors.New("unknown scope " + s.String())
+		}
+		if scope == auth.ScopeAdmin {
+			if _, err := r.requireStaff(ctx); err != nil {
+				return nil, err
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays != nil {
+		if *input.ExpiresInDays <= 0 {
+			return nil, errors.New("expiresInDays must be positive")
+		}
+		t := time.Now().AddDate(0, 0, *input.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, err := r.AuthService.GeneratePersonalAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	pat := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      input.Name,
+		TokenHash: r.AuthService.HashToken(token),
+		Prefix:    token[:personalAccessTokenPrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+
+		CreatedWithMFA: claims.MFA,
+	}
+	if err := r.PersonalAccessTokenRepo.Create(ctx, pat); err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedPersonalAccessToken{
+		Token:               token,
+		PersonalAccessToken: convertPersonalAccessToken(pat),
+	}, nil
+}
+
+// RevokePersonalAccessToken revokes one of the caller's tokens
+func (r *mutationResolver) RevokePersonalAccessToken(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid token id")
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	if err := r.PersonalAccessTokenRepo.Revoke(ctx, tokenID, userID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MyPersonalAccessTokens lists the caller's active tokens
+func (r *queryResolver) MyPersonalAccessTokens(ctx context.Context) ([]*model.PersonalAccessToken, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	tokens, err := r.PersonalAccessTokenRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.PersonalAccessToken, len(tokens))
+	for i, t := range tokens {
+		result[i] = convertPersonalAccessToken(t)
+	}
+	return result, nil
+}
+
+// Helper to convert models.PersonalAccessToken to model.PersonalAccessToken
+func convertPersonalAccessToken(t *models.PersonalAccessToken) *model.PersonalAccessToken {
+	scopes := make([]model.TokenScope, 0, len(t.Scopes))
+	for _, s := range t.Scopes {
+		for gqlScope, name := range scopeNames {
+			if name == s {
+				scopes = append(scopes, gqlScope)
+			}
+		}
+	}
+
+	return &model.PersonalAccessToken{
+		ID:         t.ID.Hex(),
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
@@ -0,0 +1,223 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/diff"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Longest content and code snippet a post can have, in characters. They also
+// bound the work of diffing its revisions.
+const (
+	maxPostContentLength     = 10_000
+	maxPostCodeSnippetLength = 20_000
+)
+
+// How many versions of a post revisions returns
+const (
+	defaultRevisions = 20
+	maxRevisions     = 50
+)
+
+// validatePostText checks the content and code snippet of a post
+func validatePostText(content, codeSnippet string) error {
+	if strings.TrimSpace(content) == "" {
+		return errors.New("content cannot be empty")
+	}
+	if utf8.RuneCountInString(content) > maxPostContentLength {
+		return fmt.Errorf("content must be at most %d characters", maxPostContentLength)
+	}
+	if utf8.RuneCountInString(codeSnippet) > maxPostCodeSnippetLength {
+		return fmt.Errorf("code snippet must be at most %d characters", maxPostCodeSnippetLength)
+	}
+	return nil
+}
+
+// UpdatePost edits the caller's post. Fields left out of input are kept.
+// Changing the content, code snippet, language or tags stores the previous
+// version as a revision and marks the post edited; changing only the
+// visibility does neither.
+func (r *mutationResolver) UpdatePost(ctx context.Context, id string, input model.UpdatePostInput) (*model.Post, error) {
+	claims, err := r.requireScope(ctx, auth.ScopeWritePosts)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid post id")
+	}
+	post, err := r.findVisiblePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != userID {
+		return nil, errors.New("you can only edit your own posts")
+	}
+
+	previous := *post
+	update := bson.M{}
+
+	if input.Content != nil {
+		post.Content = *input.Content
+	}
+	if input.CodeSnippet != nil {
+		post.CodeSnippet = *input.CodeSnippet
+	}
+	if input.Language != nil {
+		post.Language = *input.Language
+	}
+	if input.Tags != nil {
+		post.Tags = input.Tags
+	}
+
+	contentChanged := post.Content != previous.Content ||
+		post.CodeSnippet != previous.CodeSnippet ||
+		post.Language != previous.Language ||
+		!slices.Equal(post.Tags, previous.Tags)
+	if contentChanged {
+		if err := validatePostText(post.Content, post.CodeSnippet); err != nil {
+			return nil, err
+		}
+		update["content"] = post.Content
+		update["code_snippet"] = post.CodeSnippet
+		update["language"] = post.Language
+		update["tags"] = post.Tags
+	}
+
+	if input.Visibility != nil && input.Visibility.String() != post.Visibility {
+		post.Visibility = input.Visibility.String()
+		update["visibility"] = post.Visibility
+	}
+
+	if len(update) == 0 {
+		return r.convertPostWithAuthor(ctx, post)
+	}
+
+	if contentChanged {
+		if err := r.PostRevisionRepo.Create(ctx, revisionOf(&previous)); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.PostRepo.ApplyEdit(ctx, post.ID, previous.Version, update, contentChanged); err != nil {
+		return nil, err
+	}
+
+	// Followers get a post that only now reaches their timelines
+	if inTimelines(post.Visibility) && !inTimelines(previous.Visibility) {
+		r.queueFanOut(ctx, post)
+	}
+
+	updated, err := r.PostRepo.FindByID(ctx, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.convertPostWithAuthor(ctx, updated)
+}
+
+// Revisions lists the latest last versions of the post, oldest first and
+// ending with the current one, with what changed from the version before
+func (r *postResolver) Revisions(ctx context.Context, obj *model.Post, last *int) ([]*model.PostRevision, error) {
+	n := defaultRevisions
+	if last != nil && *last > 0 {
+		n = *last
+	}
+	if n > maxRevisions {
+		n = maxRevisions
+	}
+
+	postID, err := primitive.ObjectIDFromHex(obj.ID)
+	if err != nil {
+		return nil, errors.New("invalid post id")
+	}
+	post, err := r.findVisiblePost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// One stored revision more than is returned, to diff the oldest against
+	revisions := []*models.PostRevision{}
+	if post.Edited {
+		if revisions, err = r.PostRevisionRepo.FindRecentByPost(ctx, post.ID, n); err != nil {
+			return nil, err
+		}
+	}
+	revisions = append(revisions, revisionOf(post))
+
+	skip := len(revisions) - n
+	if skip < 0 {
+		skip = 0
+	}
+	result := make([]*model.PostRevision, 0, len(revisions)-skip)
+	for i, rev := range revisions[skip:] {
+		converted := convertPostRevision(rev)
+		if i+skip > 0 {
+			prev := revisions[i+skip-1]
+			converted.ContentDiff = convertDiff(diff.Lines(prev.Content, rev.Content))
+			converted.CodeSnippetDiff = convertDiff(diff.Lines(prev.CodeSnippet, rev.CodeSnippet))
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+// convertPostWithAuthor converts a post along with its author
+func (r *Resolver) convertPostWithAuthor(ctx context.Context, post *models.Post) (*model.Post, error) {
+	author, err := r.UserRepo.FindByID(ctx, post.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := convertPost(post)
+	result.Author = convertUser(author, r.userViewFor(ctx, author))
+	return result, nil
+}
+
+// revisionOf captures the current version of a post
+func revisionOf(p *models.Post) *models.PostRevision {
+	publishedAt := p.CreatedAt
+	if p.EditedAt != nil {
+		publishedAt = *p.EditedAt
+	}
+
+	return &models.PostRevision{
+		PostID:      p.ID,
+		AuthorID:    p.AuthorID,
+		Version:     p.Version,
+		Content:     p.Content,
+		CodeSnippet: p.CodeSnippet,
+		Language:    p.Language,
+		Tags:        p.Tags,
+		PublishedAt: publishedAt,
+	}
+}
+
+func convertPostRevision(rev *models.PostRevision) *model.PostRevision {
+	return &model.PostRevision{
+		Version:     rev.Version,
+		Content:     rev.Content,
+		CodeSnippet: &rev.CodeSnippet,
+		Language:    &rev.Language,
+		Tags:        rev.Tags,
+		PublishedAt: rev.PublishedAt,
+	}
+}
+
+func convertDiff(lines []diff.Line) []*model.DiffLine {
+	result := make([]*model.DiffLine, len(lines))
+	for i, line := range lines {
+		result[i] = &model.DiffLine{Op: model.DiffOp(line.Op), Text: line.Text}
+	}
+	return result
+}
@@ -0,0 +1,22 @@
+package resolver
+
+// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+)
+
+type postResolver struct{ *Resolver }
+
+func (r *Resolver) Post() PostResolver {
+	return &postResolver{r}
+}
+
+// PostResolver interface (will be generated)
+type PostResolver interface {
+	Revisions(ctx context.Context, obj *model.Post, last *int) ([]*model.PostRevision, error)
+	ViewerVote(ctx context.Context, obj *model.Post) (*model.Vote, error)
+	ViewerReactions(ctx context.Context, obj *model.Post) ([]string, error)
+}
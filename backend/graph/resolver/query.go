@@ -0,0 +1,38 @@
+package resolver
+
+// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+)
+
+type queryResolver struct{ *Resolver }
+
+func (r *Resolver) Query() QueryResolver {
+	return &queryResolver{r}
+}
+
+// QueryResolver interface (will be generated)
+type QueryResolver interface {
+	Me(ctx context.Context) (*model.User, error)
+	Feed(ctx context.Context, filter *model.FeedFilter, window *model.TrendingWindow, first *int, after *string) (*model.PostConnection, error)
+	Post(ctx context.Context, id string) (*model.Post, error)
+	UserPosts(ctx context.Context, userID string, first *int, after *string) (*model.PostConnection, error)
+	Reels(ctx context.Context, first *int, after *string) (*model.ReelConnection, error)
+	Reel(ctx context.Context, id string) (*model.Reel, error)
+	UserReels(ctx context.Context, userID string, first *int, after *string) (*model.ReelConnection, error)
+	User(ctx context.Context, id *string, username *string) (*model.User, error)
+	SearchUsers(ctx context.Context, query string, limit *int) ([]*model.User, error)
+	MyPersonalAccessTokens(ctx context.Context) ([]*model.PersonalAccessToken, error)
+	MySessions(ctx context.Context) ([]*model.Session, error)
+	MyDataExports(ctx context.Context) ([]*model.DataExport, error)
+	Comments(ctx context.Context, postID *string, reelID *string, first *int, after *string) (*model.CommentConnection, error)
+	ReactionEmoji(ctx context.Context) ([]string, error)
+	AdminUsers(ctx context.Context, first *int, after *string, filter *string) (*model.UserConnection, error)
+	AdminPosts(ctx context.Context, first *int, after *string, filter *string) (*model.PostConnection, error)
+	AdminReels(ctx context.Context, first *int, after *string) (*model.ReelConnection, error)
+	AdminModerationLogs(ctx context.Context, first *int, after *string) (*model.ModerationLogConnection, error)
+	AdminRoles(ctx context.Context) ([]*model.Role, error)
+}
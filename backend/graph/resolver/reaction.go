@@ -0,0 +1,217 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reactionsTopic is the pubsub topic of a target's reaction counts
+func reactionsTopic(targetType, targetID string) string {
+	return "reactions:" + targetType + ":" + targetID
+}
+
+// reactionTarget is a post, reel or comment being reacted to
+type reactionTarget struct {
+	targetType string
+	id         primitive.ObjectID
+	adjust     func(ctx context.Context, id primitive.ObjectID, emoji string, delta int) (map[string]int, error)
+}
+
+// findReactionTarget loads the target if the caller may open it
+func (r *Resolver) findReactionTarget(ctx context.Context, targetType model.ReactionTarget, targetID string) (reactionTarget, error) {
+	id, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return reactionTarget{}, errors.New("invalid target id")
+	}
+
+	t := reactionTarget{targetType: targetType.String(), id: id}
+	switch t.targetType {
+	case models.TargetPost:
+		_, err = r.findVisiblePost(ctx, id)
+		t.adjust = r.PostRepo.AdjustReaction
+	case models.TargetReel:
+		_, err = r.findVisibleReel(ctx, id)
+		t.adjust = r.ReelRepo.AdjustReaction
+	case models.TargetComment:
+		_, err = r.findVisibleComment(ctx, id)
+		t.adjust = r.CommentRepo.AdjustReaction
+	default:
+		err = errors.New("invalid target type")
+	}
+	return t, err
+}
+
+// React adds the caller's reaction with the emoji to a post, reel or comment
+func (r *mutationResolver) React(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string) (*model.ReactionResult, error) {
+	if !r.reactionEmojiAllowed(emoji) {
+		return nil, errors.New("unsupported reaction")
+	}
+	return r.setReaction(ctx, targetType, targetID, emoji, true)
+}
+
+// Unreact takes back the caller's reaction with the emoji. Reactions with
+// emoji that have since been removed from REACTION_EMOJI can still be taken
+// back.
+func (r *mutationResolver) Unreact(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string) (*model.ReactionResult, error) {
+	if emoji == "" {
+		return nil, errors.New("unsupported reaction")
+	}
+	return r.setReaction(ctx, targetType, targetID, emoji, false)
+}
+
+// setReaction sets whether the caller has the reaction, moves the target's
+// count with it and pushes the new counts to reactionsUpdated subscribers
+func (r *Resolver) setReaction(ctx context.Context, targetType model.ReactionTarget, targetID string, emoji string, active bool) (*model.ReactionResult, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	t, err := r.findReactionTarget(ctx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts map[string]int
+	changed := false
+	_, err = r.toggleEngagement(ctx, &models.Engagement{
+		UserID:     userID,
+		TargetType: t.targetType,
+		TargetID:   t.id,
+		Type:       models.EngagementReaction,
+		Emoji:      emoji,
+	}, &active, func(delta int) (int, error) {
+		c, err := t.adjust(ctx, t.id, emoji, delta)
+		counts, changed = c, delta != 0
+		return c[emoji], err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		r.PubSub.Publish(reactionsTopic(t.targetType, t.id.Hex()), counts)
+	}
+
+	viewerReactions, err := r.EngagementRepo.FindReactions(ctx, userID, t.targetType, t.id)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReactionResult{
+		Reactions:       convertReactions(counts),
+		ViewerReactions: viewerReactions,
+	}, nil
+}
+
+// reactionEmojiAllowed reports whether the emoji is one of REACTION_EMOJI
+func (r *Resolver) reactionEmojiAllowed(emoji string) bool {
+	for _, allowed := range r.Config.ReactionEmoji {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ReactionEmoji lists the emoji that can be reacted with
+func (r *queryResolver) ReactionEmoji(ctx context.Context) ([]string, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	if r.Config.ReactionEmoji == nil {
+		return []string{}, nil
+	}
+	return r.Config.ReactionEmoji, nil
+}
+
+// ReactionsUpdated streams the target's reaction counts as they change.
+// Only targets the subscriber may open can be subscribed to.
+func (r *subscriptionResolver) ReactionsUpdated(ctx context.Context, targetType model.ReactionTarget, targetID string) (<-chan *model.Reactions, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	t, err := r.findReactionTarget(ctx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := r.PubSub.Subscribe(ctx, reactionsTopic(t.targetType, t.id.Hex()))
+	out := make(chan *model.Reactions, 1)
+
+	go func() {
+		defer close(out)
+		for event := range events {
+			counts, ok := event.(map[string]int)
+			if !ok {
+				continue
+			}
+			select {
+			case out <- &model.Reactions{
+				TargetType: targetType,
+				TargetID:   t.id.Hex(),
+				Reactions:  convertReactions(counts),
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// ViewerReactions is the emoji the signed-in user reacted to the post with
+func (r *postResolver) ViewerReactions(ctx context.Context, obj *model.Post) ([]string, error) {
+	return r.viewerReactions(ctx, models.TargetPost, obj.ID)
+}
+
+// ViewerReactions is the emoji the signed-in user reacted to the reel with
+func (r *reelResolver) ViewerReactions(ctx context.Context, obj *model.Reel) ([]string, error) {
+	return r.viewerReactions(ctx, models.TargetReel, obj.ID)
+}
+
+// ViewerReactions is the emoji the signed-in user reacted to the comment with
+func (r *commentResolver) ViewerReactions(ctx context.Context, obj *model.Comment) ([]string, error) {
+	return r.viewerReactions(ctx, models.TargetComment, obj.ID)
+}
+
+func (r *Resolver) viewerReactions(ctx context.Context, targetType, id string) ([]string, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	viewerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	targetID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid id")
+	}
+
+	return r.EngagementRepo.FindReactions(ctx, viewerID, targetType, targetID)
+}
+
+// convertReactions lists the reaction counts above 0, most used first
+func convertReactions(counts map[string]int) []*model.ReactionCount {
+	reactions := make([]*model.ReactionCount, 0, len(counts))
+	for emoji, count := range counts {
+		if count > 0 {
+			reactions = append(reactions, &model.ReactionCount{Emoji: emoji, Count: count})
+		}
+	}
+	sort.Slice(reactions, func(i, j int) bool {
+		if reactions[i].Count != reactions[j].Count {
+			return reactions[i].Count > reactions[j].Count
+		}
+		return reactions[i].Emoji < reactions[j].Emoji
+	})
+	return reactions
+}
@@ -0,0 +1,22 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/internal/jobs"
+	"github.com/devthreads/backend/internal/reconcile"
+)
+
+// CounterReconcileJob checks the like, upvote, view and comment counters
+// against what they count, correcting them if COUNTER_RECONCILE_FIX is set
+func (r *Resolver) CounterReconcileJob() jobs.Job {
+	reconciler := reconcile.New(r.PostRepo, r.ReelRepo, r.CommentRepo, r.EngagementRepo, r.ModerationLogRepo, r.Config.CounterReconcileBatch)
+	return jobs.Job{
+		Name:     "counter-reconcile",
+		Interval: r.Config.CounterReconcileInterval,
+		Run: func(ctx context.Context) error {
+			_, err := reconciler.Run(ctx, r.Config.CounterReconcileFix)
+			return err
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reels returns public reels, newest first
+func (r *queryResolver) Reels(ctx context.Context, first *int, after *string) (*model.ReelConnection, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	page, err := r.ReelRepo.List(ctx, pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+	return r.reelConnection(ctx, page)
+}
+
+// Reel opens a reel by direct link, which reaches unlisted reels too
+func (r *queryResolver) Reel(ctx context.Context, id string) (*model.Reel, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	reelID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid reel id")
+	}
+
+	reel, err := r.findVisibleReel(ctx, reelID)
+	if err != nil {
+		return nil, err
+	}
+	author, err := r.UserRepo.FindByID(ctx, reel.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := convertReel(reel)
+	result.Author = convertUser(author, r.userViewFor(ctx, author))
+	return result, nil
+}
+
+// UserReels returns a user's reels, newest first, with the same visibility
+// rules as UserPosts, and is likewise empty when the profile is not visible
+func (r *queryResolver) UserReels(ctx context.Context, userID string, first *int, after *string) (*model.ReelConnection, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	authorID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	visible, err := r.profileListsVisible(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return &model.ReelConnection{Edges: []*model.ReelEdge{}, PageInfo: &model.PageInfo{}}, nil
+	}
+
+	visibilities, err := r.listedBy(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.ReelRepo.FindByAuthor(ctx, authorID, visibilities, pageSize(first), pageCursor(after))
+	if err != nil {
+		return nil, err
+	}
+	return r.reelConnection(ctx, page)
+}
+
+// reelConnection converts a page of reels with their authors
+func (r *Resolver) reelConnection(ctx context.Context, page *pagination.Page[models.Reel]) (*model.ReelConnection, error) {
+	ids := make([]primitive.ObjectID, len(page.Edges))
+	for i, edge := range page.Edges {
+		ids[i] = edge.Node.AuthorID
+	}
+	authors, err := r.loadAuthors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &model.ReelConnection{Edges: []*model.ReelEdge{}, PageInfo: convertPageInfo(page)}
+	for _, edge := range page.Edges {
+		author, ok := authors[edge.Node.AuthorID]
+		if !ok {
+			continue
+		}
+		reel := convertReel(edge.Node)
+		reel.Author = author
+		connection.Edges = append(connection.Edges, &model.ReelEdge{Cursor: edge.Cursor, Node: reel})
+	}
+	return connection, nil
+}
+
+// Helper to convert models.Reel to model.Reel
+func convertReel(rl *models.Reel) *model.Reel {
+	return &model.Reel{
+		ID:            rl.ID.Hex(),
+		Title:         &rl.Title,
+		Description:   &rl.Description,
+		VideoURL:      rl.VideoURL,
+		ThumbnailURL:  &rl.ThumbnailURL,
+		Duration:      rl.Duration,
+		Tags:          rl.Tags,
+		Visibility:    model.Visibility(rl.Visibility),
+		CreatedAt:     rl.CreatedAt,
+		UpdatedAt:     rl.UpdatedAt,
+		LikesCount:    rl.LikesCount,
+		CommentsCount: rl.CommentsCount,
+		ViewsCount:    rl.ViewsCount,
+		Reactions:     convertReactions(rl.ReactionCounts),
+	}
+}
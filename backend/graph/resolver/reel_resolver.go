@@ -0,0 +1,20 @@
+package resolver
+
+// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+)
+
+type reelResolver struct{ *Resolver }
+
+func (r *Resolver) Reel() ReelResolver {
+	return &reelResolver{r}
+}
+
+// ReelResolver interface (will be generated)
+type ReelResolver interface {
+	ViewerReactions(ctx context.Context, obj *model.Reel) ([]string, error)
+}
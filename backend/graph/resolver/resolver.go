@@ -4,6 +4,13 @@ import (
 	"github.com/devthreads/backend/config"
 	"github.com/devthreads/backend/internal/auth"
 	"github.com/devthreads/backend/internal/database"
+	"github.com/devthreads/backend/internal/foryou"
+	"github.com/devthreads/backend/internal/lockout"
+	"github.com/devthreads/backend/internal/mailer"
+	"github.com/devthreads/backend/internal/middleware"
+	"github.com/devthreads/backend/internal/pubsub"
+	"github.com/devthreads/backend/internal/ranking"
+	"github.com/devthreads/backend/internal/rbac"
 	"github.com/devthreads/backend/internal/repository"
 )
 
@@ -12,27 +19,118 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	DB          *database.Database
-	AuthService *auth.Service
-	Config      *config.Config
+	DB           *database.Database
+	AuthService  *auth.Service
+	GithubOAuth  *auth.GithubOAuth
+	Mailer       mailer.Mailer
+	Config       *config.Config
+	UserState    *middleware.UserStateCache
+	SessionState *middleware.SessionStateCache
+	Roles        *rbac.Registry
+	PubSub       *pubsub.Broker
+
+	// Failed login throttling per account and per client IP
+	AccountLockout *lockout.Limiter
+	IPLockout      *lockout.Limiter
+
+	// Weights of the TRENDING hot score
+	HotRanking ranking.Weights
+
+	// Ranks the FOR_YOU feed
+	ForYou *foryou.Ranker
+
+	// Wakes the data export job when an export is requested
+	exportQueued chan struct{}
 
 	// Repositories
-	UserRepo       *repository.UserRepository
-	PostRepo       *repository.PostRepository
-	ReelRepo       *repository.ReelRepository
-	CommentRepo    *repository.CommentRepository
-	EngagementRepo *repository.EngagementRepository
+	UserRepo                *repository.UserRepository
+	PostRepo                *repository.PostRepository
+	PostRevisionRepo        *repository.PostRevisionRepository
+	ReelRepo                *repository.ReelRepository
+	CommentRepo             *repository.CommentRepository
+	EngagementRepo          *repository.EngagementRepository
+	RefreshTokenRepo        *repository.RefreshTokenRepository
+	AccountTokenRepo        *repository.AccountTokenRepository
+	PersonalAccessTokenRepo *repository.PersonalAccessTokenRepository
+	SessionRepo             *repository.SessionRepository
+	ModerationLogRepo       *repository.ModerationLogRepository
+	SecurityEventRepo       *repository.SecurityEventRepository
+	RoleRepo                *repository.RoleRepository
+	NotificationRepo        *repository.NotificationRepository
+	DataExportRepo          *repository.DataExportRepository
+	FollowRepo              *repository.FollowRepository
+	TimelineRepo            *repository.TimelineRepository
+	TrendingRepo            *repository.TrendingRepository
 }
 
-func NewResolver(db *database.Database, authService *auth.Service, cfg *config.Config) *Resolver {
-	return &Resolver{
-		DB:             db,
-		AuthService:    authService,
-		Config:         cfg,
-		UserRepo:       repository.NewUserRepository(db.DB),
-		PostRepo:       repository.NewPostRepository(db.DB),
-		ReelRepo:       repository.NewReelRepository(db.DB),
-		CommentRepo:    repository.NewCommentRepository(db.DB),
-		EngagementRepo: repository.NewEngagementRepository(db.DB),
+func NewResolver(db *database.Database, authService *auth.Service, mail mailer.Mailer, lockoutStore lockout.Store, cfg *config.Config) *Resolver {
+	githubOAuth := auth.NewGithubOAuth(
+		cfg.GithubClientID,
+		cfg.GithubClientSecret,
+		cfg.GithubRedirectURL,
+		cfg.GithubOAuthURL,
+		cfg.GithubAPIURL,
+	)
+
+	userRepo := repository.NewUserRepository(db.DB)
+	roleRepo := repository.NewRoleRepository(db.DB)
+	sessionRepo := repository.NewSessionRepository(db.DB)
+
+	accountPolicy := lockout.Policy{
+		MaxFailures: cfg.LoginAccountMaxFailures,
+		Window:      cfg.LoginFailureWindow,
+		BaseLock:    cfg.LoginLockoutBase,
+		MaxLock:     cfg.LoginLockoutMax,
 	}
+	// Many users can share an IP behind NAT, so it gets a higher limit
+	ipPolicy := accountPolicy
+	ipPolicy.MaxFailures = cfg.LoginIPMaxFailures
+
+	hotRanking := ranking.Weights{
+		Like:    cfg.TrendingLikeWeight,
+		Upvote:  cfg.TrendingUpvoteWeight,
+		Comment: cfg.TrendingCommentWeight,
+		View:    cfg.TrendingViewWeight,
+		Gravity: cfg.TrendingGravity,
+	}
+
+	resolver := &Resolver{
+		DB:                      db,
+		AuthService:             authService,
+		GithubOAuth:             githubOAuth,
+		Mailer:                  mail,
+		Config:                  cfg,
+		UserState:               middleware.NewUserStateCache(userRepo, cfg.UserStateCacheTTL),
+		SessionState:            middleware.NewSessionStateCache(sessionRepo, cfg.UserStateCacheTTL),
+		Roles:                   rbac.NewRegistry(roleRepo, cfg.UserStateCacheTTL),
+		PubSub:                  pubsub.NewBroker(),
+		AccountLockout:          lockout.NewLimiter(lockoutStore, accountPolicy),
+		IPLockout:               lockout.NewLimiter(lockoutStore, ipPolicy),
+		HotRanking:              hotRanking,
+		exportQueued:            make(chan struct{}, 1),
+		UserRepo:                userRepo,
+		PostRepo:                repository.NewPostRepository(db.DB),
+		PostRevisionRepo:        repository.NewPostRevisionRepository(db.DB),
+		ReelRepo:                repository.NewReelRepository(db.DB),
+		CommentRepo:             repository.NewCommentRepository(db.DB),
+		EngagementRepo:          repository.NewEngagementRepository(db.DB),
+		RefreshTokenRepo:        repository.NewRefreshTokenRepository(db.DB),
+		AccountTokenRepo:        repository.NewAccountTokenRepository(db.DB),
+		PersonalAccessTokenRepo: repository.NewPersonalAccessTokenRepository(db.DB),
+		SessionRepo:             sessionRepo,
+		ModerationLogRepo:       repository.NewModerationLogRepository(db.DB),
+		SecurityEventRepo:       repository.NewSecurityEventRepository(db.DB),
+		RoleRepo:                roleRepo,
+		NotificationRepo:        repository.NewNotificationRepository(db.DB),
+		DataExportRepo:          repository.NewDataExportRepository(db.DB),
+		FollowRepo:              repository.NewFollowRepository(db.DB),
+		TimelineRepo:            repository.NewTimelineRepository(db.DB),
+		TrendingRepo:            repository.NewTrendingRepository(db.DB),
+	}
+	resolver.ForYou = foryou.NewRanker(
+		foryou.NewRepositoryStore(resolver.PostRepo, resolver.EngagementRepo, resolver.FollowRepo),
+		foryou.DefaultConfig,
+	)
+
+	return resolver
 }
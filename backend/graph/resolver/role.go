@@ -0,0 +1,199 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/rbac"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// AdminRoles lists the built-in roles followed by the custom ones
+func (r *queryResolver) AdminRoles(ctx context.Context) ([]*model.Role, error) {
+	if _, err := r.requirePermission(ctx, rbac.PermissionManageRoles); err != nil {
+		return nil, err
+	}
+
+	roles := make([]*model.Role, 0, len(rbac.BuiltinRoleNames))
+	for _, name := range rbac.BuiltinRoleNames {
+		roles = append(roles, &model.Role{
+			Name:        name,
+			Permissions: convertPermissions(rbac.BuiltinPermissions(name)),
+			BuiltIn:     true,
+		})
+	}
+
+	custom, err := r.RoleRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range custom {
+		roles = append(roles, convertRole(role))
+	}
+
+	return roles, nil
+}
+
+// AdminCreateRole defines a custom role with permissions the caller holds
+func (r *mutationResolver) AdminCreateRole(ctx context.Context, input model.RoleInput) (*model.Role, error) {
+	claims, err := r.requirePermission(ctx, rbac.PermissionManageRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	if !roleNamePattern.MatchString(input.Name) {
+		return nil, errors.New("role names must be 2-32 lowercase letters, digits, dashes or underscores and start with a letter")
+	}
+	if rbac.IsBuiltin(input.Name) {
+		return nil, errors.New("a role with this name already exists")
+	}
+
+	permissions, err := validatePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireGrantable(ctx, claims, permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        input.Name,
+		Permissions: permissions,
+	}
+	if err := r.RoleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	r.Roles.Invalidate()
+
+	return convertRole(role), nil
+}
+
+// AdminUpdateRole replaces the permissions of a custom role. The caller must
+// hold both the role's current and its new permissions. Users holding the
+// role are affected from their next request.
+func (r *mutationResolver) AdminUpdateRole(ctx context.Context, input model.RoleInput) (*model.Role, error) {
+	claims, err := r.requirePermission(ctx, rbac.PermissionManageRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	if rbac.IsBuiltin(input.Name) {
+		return nil, errors.New("built-in roles cannot be changed")
+	}
+
+	current, err := r.Roles.Permissions(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireRoleOutranked(ctx, claims, current); err != nil {
+		return nil, err
+	}
+
+	permissions, err := validatePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireGrantable(ctx, claims, permissions); err != nil {
+		return nil, err
+	}
+
+	role, err := r.RoleRepo.UpdatePermissions(ctx, input.Name, permissions)
+	if err != nil {
+		return nil, err
+	}
+	r.Roles.Invalidate()
+
+	return convertRole(role), nil
+}
+
+// AdminDeleteRole removes a custom role that no user holds anymore
+func (r *mutationResolver) AdminDeleteRole(ctx context.Context, name string) (bool, error) {
+	if _, err := r.requirePermission(ctx, rbac.PermissionManageRoles); err != nil {
+		return false, err
+	}
+
+	if rbac.IsBuiltin(name) {
+		return false, errors.New("built-in roles cannot be deleted")
+	}
+
+	holders, err := r.UserRepo.Count(ctx, bson.M{"role": name})
+	if err != nil {
+		return false, err
+	}
+	if holders > 0 {
+		return false, fmt.Errorf("%d users still have this role", holders)
+	}
+
+	if err := r.RoleRepo.Delete(ctx, name); err != nil {
+		return false, err
+	}
+	r.Roles.Invalidate()
+
+	return true, nil
+}
+
+// requireGrantable refuses to hand out permissions the caller's own role does
+// not grant, so MANAGE_ROLES cannot be used to escalate privileges
+func (r *Resolver) requireGrantable(ctx context.Context, claims *auth.Claims, permissions []string) error {
+	want := make([]rbac.Permission, len(permissions))
+	for i, p := range permissions {
+		want[i] = rbac.Permission(p)
+	}
+	return r.requireRoleOutranked(ctx, claims, want)
+}
+
+// requireRoleOutranked checks that the caller's role grants every permission
+// of a role they are about to create, change or assign
+func (r *Resolver) requireRoleOutranked(ctx context.Context, claims *auth.Claims, permissions []rbac.Permission) error {
+	missing, err := r.Roles.Missing(ctx, claims.Role, permissions)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("your role does not grant the %s permission", missing[0])
+	}
+	return nil
+}
+
+func validatePermissions(permissions []model.Permission) ([]string, error) {
+	seen := make(map[model.Permission]bool, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if !rbac.ValidPermission(rbac.Permission(p)) {
+			return nil, fmt.Errorf("unknown permission %s", p)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, string(p))
+	}
+	return result, nil
+}
+
+func convertPermissions(permissions []rbac.Permission) []model.Permission {
+	result := make([]model.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		result = append(result, model.Permission(p))
+	}
+	return result
+}
+
+func convertRole(role *models.Role) *model.Role {
+	permissions := make([]model.Permission, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		permissions = append(permissions, model.Permission(p))
+	}
+	return &model.Role{
+		Name:        role.Name,
+		Permissions: permissions,
+		BuiltIn:     false,
+	}
+}
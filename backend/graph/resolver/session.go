@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/middleware"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MySessions lists the devices the caller is signed in on
+func (r *queryResolver) MySessions(ctx context.Context) ([]*model.Session, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	sessions, err := r.SessionRepo.FindActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Session, len(sessions))
+	for i, s := range sessions {
+		result[i] = convertSession(s, claims.SessionID)
+	}
+	return result, nil
+}
+
+// RevokeSession signs one of the caller's devices out
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, errors.New("invalid session id")
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	if err := r.endSession(ctx, userID, sessionID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RevokeAllOtherSessions signs the caller out everywhere except the current
+// device and returns how many sessions were ended
+func (r *mutationResolver) RevokeAllOtherSessions(ctx context.Context) (int, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	current, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return 0, errors.New("token is not bound to a session")
+	}
+
+	return r.endAllSessions(ctx, userID, current)
+}
+
+// startSession records a new signed-in device for the user
+func (r *Resolver) startSession(ctx context.Context, user *models.User) (*models.Session, error) {
+	client := middleware.ClientInfoFromContext(ctx)
+	session := &models.Session{
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(r.AuthService.RefreshExpiry()),
+	}
+
+	if err := r.SessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// endSession revokes a session together with its refresh tokens. The session
+// is revoked first, which fails unless it belongs to userID, so nobody can
+// sign out another user's device by its ID.
+func (r *Resolver) endSession(ctx context.Context, userID, sessionID primitive.ObjectID) error {
+	if err := r.SessionRepo.Revoke(ctx, sessionID, userID); err != nil {
+		return err
+	}
+	if err := r.RefreshTokenRepo.RevokeFamily(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	r.SessionState.Invalidate(sessionID)
+	return nil
+}
+
+// endAllSessions revokes every session of the user except the given one,
+// which may be primitive.NilObjectID, and returns how many were ended
+func (r *Resolver) endAllSessions(ctx context.Context, userID, except primitive.ObjectID) (int, error) {
+	ids, err := r.SessionRepo.RevokeAllForUser(ctx, userID, except)
+	if err != nil {
+		return 0, err
+	}
+
+	if except.IsZero() {
+		// Also covers refresh tokens issued before sessions were tracked
+		err = r.RefreshTokenRepo.RevokeAllForUser(ctx, userID)
+	} else {
+		err = r.RefreshTokenRepo.RevokeFamilies(ctx, ids)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		r.SessionState.Invalidate(id)
+	}
+	return len(ids), nil
+}
+
+// Helper to convert models.Session to model.Session
+func convertSession(s *models.Session, currentID string) *model.Session {
+	return &model.Session{
+		ID:         s.ID.Hex(),
+		UserAgent:  &s.UserAgent,
+		IP:         &s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		Current:    s.ID.Hex() == currentID,
+	}
+}
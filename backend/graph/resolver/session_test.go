@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devthreads/backend/internal/middleware"
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestEndSession(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	newResolver := func(mt *mtest.T) *Resolver {
+		sessionRepo := repository.NewSessionRepository(mt.DB)
+		return &Resolver{
+			SessionRepo:      sessionRepo,
+			RefreshTokenRepo: repository.NewRefreshTokenRepository(mt.DB),
+			SessionState:     middleware.NewSessionStateCache(sessionRepo, time.Minute),
+		}
+	}
+	updated := func(n int32) bson.D {
+		return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+	}
+
+	mt.Run("revokes the owner's session and its refresh tokens", func(mt *mtest.T) {
+		r := newResolver(mt)
+		userID, sessionID := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(updated(1), updated(2))
+
+		if err := r.endSession(context.Background(), userID, sessionID); err != nil {
+			mt.Fatalf("endSession() error = %v", err)
+		}
+
+		events := mt.GetAllStartedEvents()
+		if len(events) != 2 || events[0].CommandName != "update" || events[1].CommandName != "update" {
+			mt.Fatalf("got %d commands, want the session update then the refresh token update", len(events))
+		}
+		for i, coll := range []string{"sessions", "refresh_tokens"} {
+			cmd := events[i].Command
+			if cmd.Lookup("update").StringValue() != coll {
+				mt.Fatalf("command %d updated %s, want %s", i, cmd.Lookup("update"), coll)
+			}
+			q := cmd.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document()
+			if q.Lookup("user_id").ObjectID() != userID {
+				mt.Fatalf("%s filter = %s, want it scoped to the owner", coll, q)
+			}
+		}
+	})
+
+	mt.Run("leaves another user's session and tokens alone", func(mt *mtest.T) {
+		r := newResolver(mt)
+		// The session belongs to someone else, so the owner-scoped update
+		// matches nothing
+		mt.AddMockResponses(updated(0))
+
+		err := r.endSession(context.Background(), primitive.NewObjectID(), primitive.NewObjectID())
+		if !errors.Is(err, repository.ErrSessionNotFound) {
+			mt.Fatalf("endSession() error = %v, want ErrSessionNotFound", err)
+		}
+		if events := mt.GetAllStartedEvents(); len(events) != 1 {
+			mt.Fatalf("got %d commands, want only the session update", len(events))
+		}
+	})
+}
@@ -0,0 +1,21 @@
+package resolver
+
+// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+)
+
+type subscriptionResolver struct{ *Resolver }
+
+func (r *Resolver) Subscription() SubscriptionResolver {
+	return &subscriptionResolver{r}
+}
+
+// SubscriptionResolver interface (will be generated)
+type SubscriptionResolver interface {
+	NotificationReceived(ctx context.Context) (<-chan *model.Notification, error)
+	ReactionsUpdated(ctx context.Context, targetType model.ReactionTarget, targetID string) (<-chan *model.Reactions, error)
+}
@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"context"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/jobs"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"github.com/devthreads/backend/internal/ranking"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// hotScoreBatch is how many posts or reels one hot score write updates
+const hotScoreBatch = 500
+
+// trendingWindow returns how far back a TRENDING window reaches. WEEK is the
+// default.
+func (r *Resolver) trendingWindow(window *model.TrendingWindow) time.Duration {
+	if window == nil {
+		return r.Config.TrendingWindowWeek
+	}
+
+	switch *window {
+	case model.TrendingWindowToday:
+		return r.Config.TrendingWindowToday
+	case model.TrendingWindowMonth:
+		return r.Config.TrendingWindowMonth
+	default:
+		return r.Config.TrendingWindowWeek
+	}
+}
+
+// HotScoreJob recomputes the hot scores of posts and reels that a TRENDING
+// window reaches. Scores decay with age, so they are refreshed even when
+// nothing new was liked or commented on. Each run also snapshots the post
+// ranking for the TRENDING feed to page through.
+func (r *Resolver) HotScoreJob() jobs.Job {
+	return jobs.Job{
+		Name:     "hot-scores",
+		Interval: r.Config.TrendingRefreshInterval,
+		Run:      r.refreshHotScores,
+	}
+}
+
+func (r *Resolver) refreshHotScores(ctx context.Context) error {
+	now := time.Now().Truncate(time.Millisecond)
+	since := now.Add(-r.longestTrendingWindow())
+
+	var entries []*models.TrendingEntry
+	postScore := func(p *models.Post) (primitive.ObjectID, float64) {
+		score := r.HotRanking.HotScore(ranking.Engagement{
+			Likes:    p.LikesCount,
+			Upvotes:  p.UpvotesCount,
+			Comments: p.CommentsCount,
+			Views:    p.ViewsCount,
+		}, now.Sub(p.CreatedAt))
+		if !p.Deleted && p.Visibility == models.VisibilityPublic {
+			entries = append(entries, &models.TrendingEntry{PostID: p.ID, HotScore: score, PostCreatedAt: p.CreatedAt})
+		}
+		return p.ID, score
+	}
+	setPostScores := func(ctx context.Context, scores map[primitive.ObjectID]float64) error {
+		if err := r.PostRepo.SetHotScores(ctx, scores); err != nil {
+			return err
+		}
+		err := r.TrendingRepo.Add(ctx, now, entries)
+		entries = nil
+		return err
+	}
+	if err := refreshScores(ctx, since, r.PostRepo.FindCreatedSince, postScore, setPostScores); err != nil {
+		return err
+	}
+	if err := r.TrendingRepo.Publish(ctx, now); err != nil {
+		return err
+	}
+	if err := r.TrendingRepo.Prune(ctx, now.Add(-r.Config.TrendingSnapshotRetention)); err != nil {
+		return err
+	}
+	if err := r.PostRepo.ClearHotScores(ctx, since); err != nil {
+		return err
+	}
+
+	reelScore := func(reel *models.Reel) (primitive.ObjectID, float64) {
+		return reel.ID, r.HotRanking.HotScore(ranking.Engagement{
+			Likes:    reel.LikesCount,
+			Comments: reel.CommentsCount,
+			Views:    reel.ViewsCount,
+		}, now.Sub(reel.CreatedAt))
+	}
+	if err := refreshScores(ctx, since, r.ReelRepo.FindCreatedSince, reelScore, r.ReelRepo.SetHotScores); err != nil {
+		return err
+	}
+	return r.ReelRepo.ClearHotScores(ctx, since)
+}
+
+// longestTrendingWindow returns the window that reaches furthest back, which
+// bounds the content worth scoring
+func (r *Resolver) longestTrendingWindow() time.Duration {
+	longest := r.Config.TrendingWindowToday
+	for _, w := range []time.Duration{r.Config.TrendingWindowWeek, r.Config.TrendingWindowMonth} {
+		if w > longest {
+			longest = w
+		}
+	}
+	return longest
+}
+
+// refreshScores pages through what was created since the given time and
+// writes the scores of each page
+func refreshScores[T any](
+	ctx context.Context,
+	since time.Time,
+	find func(context.Context, time.Time, int, string) (*pagination.Page[T], error),
+	score func(*T) (primitive.ObjectID, float64),
+	set func(context.Context, map[primitive.ObjectID]float64) error,
+) error {
+	after := ""
+	for {
+		page, err := find(ctx, since, hotScoreBatch, after)
+		if err != nil {
+			return err
+		}
+
+		scores := make(map[primitive.ObjectID]float64, len(page.Edges))
+		for _, node := range page.Nodes() {
+			id, s := score(node)
+			scores[id] = s
+		}
+		if err := set(ctx, scores); err != nil {
+			return err
+		}
+
+		if !page.HasNextPage {
+			return nil
+		}
+		after = *page.EndCursor()
+	}
+}
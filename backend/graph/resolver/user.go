@@ -0,0 +1,172 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/rbac"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userView is how much of a profile the viewer may see
+type userView int
+
+const (
+	userViewPublic userView = iota // signed out
+	userViewMember                 // another signed-in user
+	userViewFull                   // the user themselves or an admin
+)
+
+const (
+	defaultUserSearchLimit = 20
+	maxUserSearchLimit     = 50
+)
+
+// userViewFor decides how much of u the caller may see. Admins need a role
+// granting VIEW_ADMIN_DASHBOARD and, when using a token, the admin scope.
+func (r *Resolver) userViewFor(ctx context.Context, u *models.User) userView {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return userViewPublic
+	}
+	if claims.UserID == u.ID.Hex() {
+		return userViewFull
+	}
+	if _, err := r.requirePermission(ctx, rbac.PermissionViewAdminDashboard); err == nil {
+		return userViewFull
+	}
+	return userViewMember
+}
+
+// profileVisible reports whether a profile with the given visibility shows
+// its details to view. Users without a setting have a public profile.
+func profileVisible(visibility string, view userView) bool {
+	switch visibility {
+	case models.ProfileVisibilityPrivate:
+		return view == userViewFull
+	case models.ProfileVisibilityMembers:
+		return view != userViewPublic
+	default:
+		return true
+	}
+}
+
+func convertPrivacySettings(p models.PrivacySettings) *model.PrivacySettings {
+	visibility := p.ProfileVisibility
+	if visibility == "" {
+		visibility = models.ProfileVisibilityPublic
+	}
+	return &model.PrivacySettings{
+		ShowEmail:         p.ShowEmail,
+		ShowGithub:        p.ShowGithub,
+		ProfileVisibility: model.ProfileVisibility(visibility),
+	}
+}
+
+// Me returns the signed-in user, or nil for anonymous requests
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, nil
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	user, err := r.UserRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUser(user, userViewFull), nil
+}
+
+// User looks a user up by ID or username
+func (r *queryResolver) User(ctx context.Context, id *string, username *string) (*model.User, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	var err error
+
+	switch {
+	case id != nil:
+		userID, parseErr := primitive.ObjectIDFromHex(*id)
+		if parseErr != nil {
+			return nil, errors.New("invalid user id")
+		}
+		user, err = r.UserRepo.FindByID(ctx, userID)
+	case username != nil:
+		user, err = r.UserRepo.FindByUsername(ctx, *username)
+	default:
+		return nil, errors.New("either id or username is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return convertUser(user, r.userViewFor(ctx, user)), nil
+}
+
+// SearchUsers matches usernames and display names. The query is matched
+// literally rather than as a regular expression.
+func (r *queryResolver) SearchUsers(ctx context.Context, query string, limit *int) ([]*model.User, error) {
+	if err := r.requireReadScope(ctx); err != nil {
+		return nil, err
+	}
+
+	n := defaultUserSearchLimit
+	if limit != nil && *limit > 0 {
+		n = *limit
+	}
+	if n > maxUserSearchLimit {
+		n = maxUserSearchLimit
+	}
+
+	users, err := r.UserRepo.Search(ctx, regexp.QuoteMeta(query), n)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.User, len(users))
+	for i, u := range users {
+		result[i] = convertUser(u, r.userViewFor(ctx, u))
+	}
+	return result, nil
+}
+
+// UpdatePrivacySettings changes the fields given in input and leaves the rest
+func (r *mutationResolver) UpdatePrivacySettings(ctx context.Context, input model.UpdatePrivacySettingsInput) (*model.User, error) {
+	user, err := r.sessionUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := user.Privacy
+	if input.ShowEmail != nil {
+		settings.ShowEmail = *input.ShowEmail
+	}
+	if input.ShowGithub != nil {
+		settings.ShowGithub = *input.ShowGithub
+	}
+	if input.ProfileVisibility != nil {
+		if !input.ProfileVisibility.IsValid() {
+			return nil, errors.New("invalid profile visibility")
+		}
+		settings.ProfileVisibility = input.ProfileVisibility.String()
+	}
+
+	if err := r.UserRepo.UpdatePrivacy(ctx, user.ID, settings); err != nil {
+		return nil, err
+	}
+	user.Privacy = settings
+
+	return convertUser(user, userViewFull), nil
+}
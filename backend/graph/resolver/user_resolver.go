@@ -0,0 +1,23 @@
+package resolver
+
+// THIS CODE IS A STARTING POINT ONLY. IT WILL NOT BE UPDATED WITH SCHEMA CHANGES.
+
+import (
+	"context"
+
+	"github.com/devthreads/backend/graph/model"
+)
+
+type userResolver struct{ *Resolver }
+
+func (r *Resolver) User() UserResolver {
+	return &userResolver{r}
+}
+
+// UserResolver interface (will be generated)
+type UserResolver interface {
+	Followers(ctx context.Context, obj *model.User, first *int, after *string) (*model.FollowConnection, error)
+	Following(ctx context.Context, obj *model.User, first *int, after *string) (*model.FollowConnection, error)
+	ViewerFollows(ctx context.Context, obj *model.User) (*bool, error)
+	MutedUsers(ctx context.Context, obj *model.User) ([]*model.User, error)
+}
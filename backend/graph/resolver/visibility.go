@@ -0,0 +1,147 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// audience is how the caller relates to the author of some content
+type audience int
+
+const (
+	audienceEveryone audience = iota // signed out, or not following the author
+	audienceFollower                 // follows the author
+	audienceAuthor                   // the author themselves
+)
+
+// audienceFor works out how the caller relates to authorID. Only callers who
+// are not the author cost a follow lookup.
+func (r *Resolver) audienceFor(ctx context.Context, authorID primitive.ObjectID) (audience, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return audienceEveryone, nil
+	}
+	if claims.UserID == authorID.Hex() {
+		return audienceAuthor, nil
+	}
+
+	viewerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	follows, err := r.FollowRepo.Exists(ctx, viewerID, authorID)
+	if err != nil {
+		return audienceEveryone, err
+	}
+	if follows {
+		return audienceFollower, nil
+	}
+	return audienceEveryone, nil
+}
+
+// canOpen reports whether an audience may open content with the given
+// visibility by direct link
+func (a audience) canOpen(visibility string) bool {
+	switch visibility {
+	case models.VisibilityPublic, models.VisibilityUnlisted:
+		return true
+	case models.VisibilityFollowersOnly:
+		return a >= audienceFollower
+	default:
+		return a == audienceAuthor
+	}
+}
+
+// listed returns the visibilities of an author's content an audience sees in
+// lists such as their profile. Nil means every visibility.
+func (a audience) listed() []string {
+	switch a {
+	case audienceAuthor:
+		return nil
+	case audienceFollower:
+		return []string{models.VisibilityPublic, models.VisibilityFollowersOnly}
+	default:
+		return []string{models.VisibilityPublic}
+	}
+}
+
+// listedBy returns the visibilities of authorID's content the caller sees in
+// lists
+func (r *Resolver) listedBy(ctx context.Context, authorID primitive.ObjectID) ([]string, error) {
+	a, err := r.audienceFor(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return a.listed(), nil
+}
+
+// profileListsVisible reports whether the caller may browse the posts and
+// reels on authorID's profile, which are hidden like the bio when the profile
+// is not visible to them
+func (r *Resolver) profileListsVisible(ctx context.Context, authorID primitive.ObjectID) (bool, error) {
+	author, err := r.UserRepo.FindByID(ctx, authorID)
+	if err != nil {
+		return false, err
+	}
+	return profileVisible(author.Privacy.ProfileVisibility, r.userViewFor(ctx, author)), nil
+}
+
+// inTimelines reports whether content with the given visibility is delivered
+// to the author's followers' FOLLOWING feeds
+func inTimelines(visibility string) bool {
+	return visibility == models.VisibilityPublic || visibility == models.VisibilityFollowersOnly
+}
+
+// findVisiblePost loads a post the caller may open. Posts they may not are
+// reported as not found, so their existence does not leak.
+func (r *Resolver) findVisiblePost(ctx context.Context, id primitive.ObjectID) (*models.Post, error) {
+	post, err := r.PostRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := r.audienceFor(ctx, post.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.canOpen(post.Visibility) {
+		return nil, errors.New("post not found")
+	}
+	return post, nil
+}
+
+// findVisibleReel loads a reel the caller may open, like findVisiblePost
+func (r *Resolver) findVisibleReel(ctx context.Context, id primitive.ObjectID) (*models.Reel, error) {
+	reel, err := r.ReelRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := r.audienceFor(ctx, reel.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.canOpen(reel.Visibility) {
+		return nil, errors.New("reel not found")
+	}
+	return reel, nil
+}
+
+// findVisibleComment loads a comment on a post or reel the caller may open
+func (r *Resolver) findVisibleComment(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	comment, err := r.CommentRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.PostID != nil {
+		_, err = r.findVisiblePost(ctx, *comment.PostID)
+	} else if comment.ReelID != nil {
+		_, err = r.findVisibleReel(ctx, *comment.ReelID)
+	}
+	if err != nil {
+		return nil, errors.New("comment not found")
+	}
+	return comment, nil
+}
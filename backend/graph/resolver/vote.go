@@ -0,0 +1,284 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/devthreads/backend/graph/model"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reputation an author gains or loses for a vote on their post or comment
+const (
+	upvoteReputation   = 2
+	downvoteReputation = -2
+)
+
+// voteTarget is a post or comment being voted on
+type voteTarget struct {
+	name       string
+	targetType string
+	id         primitive.ObjectID
+	authorID   primitive.ObjectID
+	adjust     func(ctx context.Context, id primitive.ObjectID, deltas map[string]int) (map[string]int, error)
+}
+
+// VotePost sets the caller's vote on a post
+func (r *mutationResolver) VotePost(ctx context.Context, id string, vote *model.Vote) (*model.VoteResult, error) {
+	voterID, target, err := r.postVoteTarget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	voteType := ""
+	if vote != nil {
+		voteType = vote.String()
+	}
+	counts, err := r.castVote(ctx, voterID, target, voteType)
+	if err != nil {
+		return nil, err
+	}
+	return voteResult(voteType, counts), nil
+}
+
+// UpvotePost upvotes a post or takes the upvote back. Upvoting replaces a
+// downvote; taking back an upvote leaves a downvote alone.
+func (r *mutationResolver) UpvotePost(ctx context.Context, id string, upvoted *bool) (*model.EngagementToggle, error) {
+	voterID, target, err := r.postVoteTarget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.EngagementRepo.FindVote(ctx, voterID, target.targetType, target.id)
+	if err != nil {
+		return nil, err
+	}
+	upvote := current == nil || current.Type != models.EngagementUpvote
+	if upvoted != nil {
+		upvote = *upvoted
+	}
+
+	voteType := models.EngagementUpvote
+	if !upvote {
+		voteType = ""
+		if current != nil && current.Type == models.EngagementDownvote {
+			voteType = models.EngagementDownvote
+		}
+	}
+
+	counts, err := r.castVote(ctx, voterID, target, voteType)
+	if err != nil {
+		return nil, err
+	}
+	return &model.EngagementToggle{Active: voteType == models.EngagementUpvote, Count: counts["upvotes_count"]}, nil
+}
+
+func (r *mutationResolver) postVoteTarget(ctx context.Context, id string) (primitive.ObjectID, voteTarget, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return primitive.NilObjectID, voteTarget{}, err
+	}
+	voterID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	postID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, voteTarget{}, errors.New("invalid post id")
+	}
+	post, err := r.findVisiblePost(ctx, postID)
+	if err != nil {
+		return primitive.NilObjectID, voteTarget{}, err
+	}
+
+	return voterID, voteTarget{
+		name:       "post",
+		targetType: models.TargetPost,
+		id:         post.ID,
+		authorID:   post.AuthorID,
+		adjust:     r.PostRepo.AdjustCounts,
+	}, nil
+}
+
+// VoteComment sets the caller's vote on a comment
+func (r *mutationResolver) VoteComment(ctx context.Context, id string, vote *model.Vote) (*model.VoteResult, error) {
+	claims, err := r.requireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	voterID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	commentID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid comment id")
+	}
+	comment, err := r.findVisibleComment(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	voteType := ""
+	if vote != nil {
+		voteType = vote.String()
+	}
+	counts, err := r.castVote(ctx, voterID, voteTarget{
+		name:       "comment",
+		targetType: models.TargetComment,
+		id:         comment.ID,
+		authorID:   comment.AuthorID,
+		adjust:     r.CommentRepo.AdjustCounts,
+	}, voteType)
+	if err != nil {
+		return nil, err
+	}
+	return voteResult(voteType, counts), nil
+}
+
+// castVote sets the voter's vote on the target, or takes it back if voteType
+// is empty, moves the target's vote counters and the author's reputation to
+// match, and returns the counters. The vote itself is atomic; counters left
+// out of step by a failure part way are corrected by reconciliation.
+func (r *Resolver) castVote(ctx context.Context, voterID primitive.ObjectID, t voteTarget, voteType string) (map[string]int, error) {
+	if t.authorID == voterID {
+		return nil, fmt.Errorf("you cannot vote on your own %ss", t.name)
+	}
+
+	var previous *models.Engagement
+	reputation := 0
+	if voteType == "" {
+		removed, err := r.EngagementRepo.RemoveVote(ctx, voterID, t.targetType, t.id)
+		if err != nil {
+			return nil, err
+		}
+		previous = removed
+	} else {
+		current, err := r.EngagementRepo.FindVote(ctx, voterID, t.targetType, t.id)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.Type == voteType {
+			return t.adjust(ctx, t.id, map[string]int{"upvotes_count": 0, "downvotes_count": 0})
+		}
+
+		reputation, err = r.voteReputation(ctx, voterID, t.authorID, t.id, voteType)
+		if err != nil {
+			return nil, err
+		}
+		previous, err = r.EngagementRepo.CastVote(ctx, &models.Engagement{
+			UserID:         voterID,
+			TargetType:     t.targetType,
+			TargetID:       t.id,
+			Type:           voteType,
+			TargetAuthorID: &t.authorID,
+			Reputation:     reputation,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deltas := map[string]int{"upvotes_count": 0, "downvotes_count": 0}
+	if previous != nil {
+		deltas[voteCounter(previous.Type)]--
+		reputation -= previous.Reputation
+	}
+	if voteType != "" {
+		deltas[voteCounter(voteType)]++
+	}
+	counts, err := t.adjust(ctx, t.id, deltas)
+	if err != nil {
+		return nil, err
+	}
+
+	if reputation != 0 {
+		if err := r.UserRepo.UpdateReputation(ctx, t.authorID, reputation); err != nil {
+			log.Printf("Failed to update reputation of user %s: %v", t.authorID.Hex(), err)
+		}
+	}
+	return counts, nil
+}
+
+// voteReputation is what a vote adds to the author's reputation. One voter's
+// votes move one author's reputation at most VOTE_REPUTATION_LIMIT times per
+// VOTE_REPUTATION_WINDOW, which stops serial voting. Upvotes move nothing
+// while the author has reached that limit voting on the voter, which is how
+// vote rings show. Votes on targetID itself are left out of the counts, so
+// changing a vote is judged like casting it.
+func (r *Resolver) voteReputation(ctx context.Context, voterID, authorID, targetID primitive.ObjectID, voteType string) (int, error) {
+	limit := int64(r.Config.VoteReputationLimit)
+	since := time.Now().Add(-r.Config.VoteReputationWindow)
+
+	given, err := r.EngagementRepo.CountReputationVotes(ctx, voterID, authorID, since, targetID)
+	if err != nil {
+		return 0, err
+	}
+	if given >= limit {
+		return 0, nil
+	}
+	if voteType == models.EngagementDownvote {
+		return downvoteReputation, nil
+	}
+
+	received, err := r.EngagementRepo.CountReputationVotes(ctx, authorID, voterID, since, primitive.NilObjectID)
+	if err != nil {
+		return 0, err
+	}
+	if received >= limit {
+		return 0, nil
+	}
+	return upvoteReputation, nil
+}
+
+// voteCounter is the counter a vote of the type adds to
+func voteCounter(voteType string) string {
+	if voteType == models.EngagementDownvote {
+		return "downvotes_count"
+	}
+	return "upvotes_count"
+}
+
+func voteResult(voteType string, counts map[string]int) *model.VoteResult {
+	result := &model.VoteResult{
+		UpvotesCount:   counts["upvotes_count"],
+		DownvotesCount: counts["downvotes_count"],
+		Score:          counts["upvotes_count"] - counts["downvotes_count"],
+	}
+	if voteType != "" {
+		vote := model.Vote(voteType)
+		result.Vote = &vote
+	}
+	return result
+}
+
+// ViewerVote is the signed-in user's vote on the post
+func (r *postResolver) ViewerVote(ctx context.Context, obj *model.Post) (*model.Vote, error) {
+	return r.viewerVote(ctx, models.TargetPost, obj.ID)
+}
+
+// ViewerVote is the signed-in user's vote on the comment
+func (r *commentResolver) ViewerVote(ctx context.Context, obj *model.Comment) (*model.Vote, error) {
+	return r.viewerVote(ctx, models.TargetComment, obj.ID)
+}
+
+func (r *Resolver) viewerVote(ctx context.Context, targetType, id string) (*model.Vote, error) {
+	claims, err := auth.GetUserFromContext(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	viewerID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	targetID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid id")
+	}
+
+	current, err := r.EngagementRepo.FindVote(ctx, viewerID, targetType, targetID)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	vote := model.Vote(current.Type)
+	return &vote, nil
+}
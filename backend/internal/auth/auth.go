@@ -3,7 +3,9 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -21,19 +23,40 @@ var (
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
+	Role     string `json:"role"`
+	// SessionID identifies the signed-in device the token was issued to
+	SessionID string `json:"sid,omitempty"`
+	// MFA is set when the session was established with a second factor
+	MFA bool `json:"mfa,omitempty"`
+	// TokenVersion must match the user's current version for the token to
+	// be accepted
+	TokenVersion int `json:"ver"`
+	// TokenID and Scopes are set for personal access tokens only
+	TokenID string   `json:"-"`
+	Scopes  []string `json:"-"`
 	jwt.StandardClaims
 }
 
+// mfaChallengeAudience marks tokens that only prove the password step of a
+// two-factor login
+const mfaChallengeAudience = "mfa-challenge"
+
+const mfaChallengeTTL = 5 * time.Minute
+
 type Service struct {
 	jwtSecret        string
+	keys             *KeySet
 	accessExpiry     time.Duration
 	refreshExpiry    time.Duration
 }
 
-func NewService(jwtSecret string, accessExpiry, refreshExpiry time.Duration) *Service {
+// NewService creates the auth service. Access tokens are signed with the
+// active key of keys, or with HS256 and jwtSecret when keys is nil. The secret
+// also signs tokens that never leave this service, such as MFA challenges.
+func NewService(jwtSecret string, keys *KeySet, accessExpiry, refreshExpiry time.Duration) *Service {
 	return &Service{
 		jwtSecret:     jwtSecret,
+		keys:          keys,
 		accessExpiry:  accessExpiry,
 		refreshExpiry: refreshExpiry,
 	}
@@ -51,24 +74,59 @@ func (s *Service) CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
+// AccessTokenSubject describes the user and session an access token is
+// issued to
+type AccessTokenSubject struct {
+	UserID       string
+	Username     string
+	Role         string
+	TokenVersion int
+	SessionID    string
+	MFA          bool
+}
+
 // GenerateAccessToken creates a new JWT access token
-func (s *Service) GenerateAccessToken(userID, username string, isAdmin bool) (string, error) {
+func (s *Service) GenerateAccessToken(subject AccessTokenSubject) (string, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		IsAdmin:  isAdmin,
+		UserID:       subject.UserID,
+		Username:     subject.Username,
+		Role:         subject.Role,
+		SessionID:    subject.SessionID,
+		MFA:          subject.MFA,
+		TokenVersion: subject.TokenVersion,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(s.accessExpiry).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	if s.keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(s.jwtSecret))
+	}
+
+	key := s.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// JWKS returns the public keys access tokens can be verified with. It is
+// empty when tokens are signed with the shared secret.
+func (s *Service) JWKS() JWKS {
+	if s.keys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return s.keys.JWKS()
 }
 
 // GenerateRefreshToken creates a secure random refresh token
 func (s *Service) GenerateRefreshToken() (string, error) {
+	return s.GenerateSecureToken()
+}
+
+// GenerateSecureToken creates a random URL-safe token for links and credentials
+func (s *Service) GenerateSecureToken() (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
@@ -76,26 +134,86 @@ func (s *Service) GenerateRefreshToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
+// HashToken returns the SHA-256 hex digest used to store opaque tokens at rest
+func (s *Service) HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshExpiry returns how long a refresh token stays valid
+func (s *Service) RefreshExpiry() time.Duration {
+	return s.refreshExpiry
+}
+
 // ValidateAccessToken validates and parses a JWT access token
 func (s *Service) ValidateAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.jwtSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.accessTokenKey)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != "" {
 		return claims, nil
 	}
 
 	return nil, ErrInvalidToken
 }
 
+// accessTokenKey picks the verification key named by the token's kid. The
+// algorithm must match the key's, so a public key can never be used as an
+// HMAC secret.
+func (s *Service) accessTokenKey(token *jwt.Token) (interface{}, error) {
+	if s.keys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.jwtSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// GenerateMFAChallenge issues a short-lived token proving the password step
+// of a login for a user with two-factor authentication enabled
+func (s *Service) GenerateMFAChallenge(userID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+	claims := jwt.StandardClaims{
+		Subject:   userID,
+		Audience:  mfaChallengeAudience,
+		ExpiresAt: expiresAt.Unix(),
+		IssuedAt:  time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(s.jwtSecret))
+	return signed, expiresAt, err
+}
+
+// ValidateMFAChallenge returns the user ID carried by a challenge token
+func (s *Service) ValidateMFAChallenge(tokenString string) (string, error) {
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.jwtSecret), nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience(mfaChallengeAudience, true) || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+
+	return claims.Subject, nil
+}
+
 // GetUserFromContext retrieves user information from context
 func GetUserFromContext(ctx context.Context) (*Claims, error) {
 	claims, ok := ctx.Value("user").(*Claims)
@@ -104,12 +222,3 @@ func GetUserFromContext(ctx context.Context) (*Claims, error) {
 	}
 	return claims, nil
 }
-
-// GetGithubOAuthURL generates GitHub OAuth URL
-func (s *Service) GetGithubOAuthURL(clientID, redirectURL string) string {
-	return fmt.Sprintf(
-		"https://github.com/login/oauth/authorize?client_id=%s&redirect_uri=%s&scope=user:email",
-		clientID,
-		redirectURL,
-	)
-}
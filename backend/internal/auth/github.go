@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidOAuthState = errors.New("invalid oauth state")
+	ErrNoVerifiedEmail   = errors.New("github account has no verified primary email")
+)
+
+// oauthStateTTL bounds how long a user may take to approve the GitHub prompt
+const oauthStateTTL = 10 * time.Minute
+
+// GithubUser is the subset of the GitHub profile DevThreads relies on
+type GithubUser struct {
+	ID            int64
+	Login         string
+	Name          string
+	AvatarURL     string
+	Email         string
+	EmailVerified bool
+}
+
+// GithubOAuth performs the GitHub authorization code flow. The base URLs are
+// configurable so the flow can be exercised against a local fake server.
+type GithubOAuth struct {
+	config *oauth2.Config
+	apiURL string
+}
+
+func NewGithubOAuth(clientID, clientSecret, redirectURL, oauthBaseURL, apiBaseURL string) *GithubOAuth {
+	oauthBaseURL = strings.TrimSuffix(oauthBaseURL, "/")
+
+	return &GithubOAuth{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   oauthBaseURL + "/login/oauth/authorize",
+				TokenURL:  oauthBaseURL + "/login/oauth/access_token",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		apiURL: strings.TrimSuffix(apiBaseURL, "/"),
+	}
+}
+
+// AuthCodeURL returns the GitHub consent page URL carrying the given state
+func (g *GithubOAuth) AuthCodeURL(state string) string {
+	return g.config.AuthCodeURL(state)
+}
+
+// Authenticate exchanges an authorization code and loads the GitHub profile,
+// including the primary email from /user/emails
+func (g *GithubOAuth) Authenticate(ctx context.Context, code string) (*GithubUser, error) {
+	token, err := g.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("github code exchange failed: %w", err)
+	}
+
+	client := g.config.Client(ctx, token)
+
+	var profile struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := g.get(ctx, client, "/user", &profile); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := g.get(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	user := &GithubUser{
+		ID:        profile.ID,
+		Login:     profile.Login,
+		Name:      profile.Name,
+		AvatarURL: profile.AvatarURL,
+	}
+	for _, e := range emails {
+		if e.Primary {
+			user.Email = e.Email
+			user.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	if user.ID == 0 {
+		return nil, errors.New("github returned an empty profile")
+	}
+	if user.Email == "" || !user.EmailVerified {
+		return nil, ErrNoVerifiedEmail
+	}
+
+	return user, nil
+}
+
+func (g *GithubOAuth) get(ctx context.Context, client *http.Client, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("github %s returned status %d", path, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// OAuthState is a state value whose signature and expiry have been checked
+type OAuthState struct {
+	Nonce string
+	// UserID is set on states issued to link GitHub to that user's account
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// GenerateOAuthState creates a state value of the form
+// nonce.userID.expiry.signature, signed with the JWT secret. userID is empty
+// for sign-in. The nonce is also returned so the caller can bind it to the
+// browser, e.g. in a cookie.
+func (s *Service) GenerateOAuthState(userID string) (state string, nonce string, err error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	nonce = base64.RawURLEncoding.EncodeToString(b)
+	payload := nonce + "." + userID + "." + strconv.FormatInt(time.Now().Add(oauthStateTTL).Unix(), 10)
+	return payload + "." + s.signState(payload), nonce, nil
+}
+
+// ParseOAuthState checks the signature and expiry of a state value
+func (s *Service) ParseOAuthState(state string) (*OAuthState, error) {
+	parts := strings.Split(state, ".")
+	if len(parts) != 4 {
+		return nil, ErrInvalidOAuthState
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.signState(payload))) {
+		return nil, ErrInvalidOAuthState
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidOAuthState
+	}
+
+	return &OAuthState{Nonce: parts[0], UserID: parts[1], ExpiresAt: time.Unix(expiresAt, 0)}, nil
+}
+
+// VerifyOAuthState checks a sign-in state value against the nonce bound to
+// the browser that started the flow. An empty nonce is rejected, so a state
+// cannot be replayed from another client.
+func (s *Service) VerifyOAuthState(state, nonce string) (*OAuthState, error) {
+	parsed, err := s.ParseOAuthState(state)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.UserID != "" {
+		return nil, ErrInvalidOAuthState
+	}
+	if nonce == "" || !hmac.Equal([]byte(parsed.Nonce), []byte(nonce)) {
+		return nil, ErrInvalidOAuthState
+	}
+	return parsed, nil
+}
+
+// VerifyLinkState checks a state value issued to link GitHub to the user's
+// account, so a code obtained by someone else cannot be linked to it
+func (s *Service) VerifyLinkState(state, userID string) (*OAuthState, error) {
+	parsed, err := s.ParseOAuthState(state)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" || !hmac.Equal([]byte(parsed.UserID), []byte(userID)) {
+		return nil, ErrInvalidOAuthState
+	}
+	return parsed, nil
+}
+
+func (s *Service) signState(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.jwtSecret))
+	mac.Write([]byte("oauth-state:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOAuthState(t *testing.T) {
+	s := NewService("test-secret", nil, time.Minute, time.Hour)
+
+	signIn, nonce, err := s.GenerateOAuthState("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, linkNonce, err := s.GenerateOAuthState("user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewService("other-secret", nil, time.Minute, time.Hour)
+	forged, forgedNonce, _ := other.GenerateOAuthState("")
+
+	tests := []struct {
+		name  string
+		state string
+		nonce string
+		ok    bool
+	}{
+		{"matching nonce", signIn, nonce, true},
+		{"empty nonce", signIn, "", false},
+		{"other nonce", signIn, linkNonce, false},
+		{"link state", link, linkNonce, false},
+		{"other secret", forged, forgedNonce, false},
+		{"tampered", strings.Replace(signIn, nonce, linkNonce, 1), linkNonce, false},
+		{"malformed", "not-a-state", nonce, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := s.VerifyOAuthState(tt.state, tt.nonce)
+			if tt.ok && (err != nil || parsed.Nonce != tt.nonce) {
+				t.Fatalf("VerifyOAuthState() = %v, %v; want nonce %q", parsed, err, tt.nonce)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidOAuthState) {
+				t.Fatalf("VerifyOAuthState() error = %v, want ErrInvalidOAuthState", err)
+			}
+		})
+	}
+}
+
+func TestLinkState(t *testing.T) {
+	s := NewService("test-secret", nil, time.Minute, time.Hour)
+	link, _, _ := s.GenerateOAuthState("user-1")
+	signIn, _, _ := s.GenerateOAuthState("")
+
+	if parsed, err := s.VerifyLinkState(link, "user-1"); err != nil || parsed.UserID != "user-1" {
+		t.Fatalf("VerifyLinkState(own) = %v, %v", parsed, err)
+	}
+	if _, err := s.VerifyLinkState(link, "user-2"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("VerifyLinkState(other user) error = %v", err)
+	}
+	if _, err := s.VerifyLinkState(signIn, ""); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("VerifyLinkState(sign-in state) error = %v", err)
+	}
+}
+
+func TestOAuthStateExpiry(t *testing.T) {
+	s := NewService("test-secret", nil, time.Minute, time.Hour)
+	payload := "nonce..1"
+	expired := payload + "." + s.signState(payload)
+
+	if _, err := s.ParseOAuthState(expired); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("ParseOAuthState(expired) error = %v", err)
+	}
+}
+
+// fakeGithub serves the OAuth token endpoint and the REST API, accepting the
+// code "good-code" and the emails given
+func fakeGithub(t *testing.T, emails []map[string]interface{}) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "good-code" || r.Form.Get("client_secret") != "secret" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+	})
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer gh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("/user", authorized(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": 42, "login": "octocat", "name": "The Octocat", "avatar_url": "https://example.com/a.png",
+		})
+	}))
+	mux.HandleFunc("/user/emails", authorized(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(emails)
+	}))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGithubAuthenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		emails  []map[string]interface{}
+		want    string
+		wantErr error
+	}{
+		{
+			name: "primary verified email",
+			code: "good-code",
+			emails: []map[string]interface{}{
+				{"email": "other@example.com", "primary": false, "verified": true},
+				{"email": "octo@example.com", "primary": true, "verified": true},
+			},
+			want: "octo@example.com",
+		},
+		{
+			name: "primary email unverified",
+			code: "good-code",
+			emails: []map[string]interface{}{
+				{"email": "other@example.com", "primary": false, "verified": true},
+				{"email": "octo@example.com", "primary": true, "verified": false},
+			},
+			wantErr: ErrNoVerifiedEmail,
+		},
+		{
+			name: "no primary email",
+			code: "good-code",
+			emails: []map[string]interface{}{
+				{"email": "other@example.com", "primary": false, "verified": true},
+			},
+			wantErr: ErrNoVerifiedEmail,
+		},
+		{
+			name: "bad code",
+			code: "bad-code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeGithub(t, tt.emails)
+			g := NewGithubOAuth("client", "secret", srv.URL+"/auth/github/callback", srv.URL, srv.URL)
+
+			user, err := g.Authenticate(context.Background(), tt.code)
+			switch {
+			case tt.want != "":
+				if err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+				if user.Email != tt.want || !user.EmailVerified || user.ID != 42 || user.Login != "octocat" {
+					t.Fatalf("Authenticate() = %+v, want %s", user, tt.want)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if err == nil {
+					t.Fatalf("Authenticate() = %+v, want an error", user)
+				}
+			}
+		})
+	}
+}
+
+func TestGithubAuthCodeURL(t *testing.T) {
+	g := NewGithubOAuth("client", "secret", "http://localhost/cb", "https://github.example/", "https://api.github.example")
+
+	got := g.AuthCodeURL("the-state")
+	if !strings.HasPrefix(got, "https://github.example/login/oauth/authorize?") || !strings.Contains(got, "state=the-state") {
+		t.Fatalf("AuthCodeURL() = %s", got)
+	}
+}
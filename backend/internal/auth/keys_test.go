@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	testRSAKey      *rsa.PrivateKey
+	_, testEdKey, _ = ed25519.GenerateKey(rand.Reader)
+)
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	if testRSAKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testRSAKey = key
+	}
+	return testRSAKey
+}
+
+// writeKey stores key as dir/kid.pem, as a private key or, with public set,
+// only its public half
+func writeKey(t *testing.T, dir, kid string, key crypto.Signer, public bool) {
+	t.Helper()
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		writePEM(t, dir, kid, "PUBLIC KEY", der)
+		return
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "PRIVATE KEY", der)
+}
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(dir string)
+		active string
+		err    string
+	}{
+		{
+			name:   "private keys sign, public keys verify",
+			setup:  func(dir string) { writeKey(t, dir, "a", testEdKey, false); writeKey(t, dir, "b", rsaKey(t), true) },
+			active: "a",
+		},
+		{
+			name:   "PKCS#1 RSA key",
+			setup:  func(dir string) { writePEM(t, dir, "a", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey(t))) },
+			active: "a",
+		},
+		{
+			name:   "active key missing",
+			setup:  func(dir string) { writeKey(t, dir, "a", testEdKey, false) },
+			active: "b",
+			err:    `active signing key "b" not found`,
+		},
+		{
+			name:   "active key is public only",
+			setup:  func(dir string) { writeKey(t, dir, "a", testEdKey, true) },
+			active: "a",
+			err:    `active signing key "a" has no private key`,
+		},
+		{
+			name:   "RSA key too short",
+			setup:  func(dir string) { writeKey(t, dir, "a", smallKey, false) },
+			active: "a",
+			err:    "at least 2048 bits",
+		},
+		{
+			name:   "unsupported PEM block",
+			setup:  func(dir string) { writePEM(t, dir, "a", "CERTIFICATE", []byte{1}) },
+			active: "a",
+			err:    `unsupported PEM block "CERTIFICATE"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.setup(dir)
+
+			set, err := LoadKeySet(dir, tt.active)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("LoadKeySet() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeySet() error = %v", err)
+			}
+			if set.Active().ID != tt.active || set.Active().PrivateKey == nil {
+				t.Fatalf("Active() = %+v", set.Active())
+			}
+		})
+	}
+}
+
+func newKeyService(t *testing.T, dir, active string) *Service {
+	t.Helper()
+	keys, err := LoadKeySet(dir, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService("test-secret", keys, time.Minute, time.Hour)
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	subject := AccessTokenSubject{UserID: "user-1", Username: "ada", Role: "user"}
+
+	// Before: only the old key exists and signs
+	writeKey(t, dir, "2024-01", testEdKey, false)
+	before := newKeyService(t, dir, "2024-01")
+	oldToken, err := before.GenerateAccessToken(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotation: the new key signs, the old one is kept as a public key
+	writeKey(t, dir, "2024-06", rsaKey(t), false)
+	writeKey(t, dir, "2024-01", testEdKey, true)
+	during := newKeyService(t, dir, "2024-06")
+	newToken, err := during.GenerateAccessToken(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After: the old key is removed once its tokens have expired
+	if err := os.Remove(filepath.Join(dir, "2024-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+	after := newKeyService(t, dir, "2024-06")
+
+	// An HS256 token keyed with a public key must not verify
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey(t).PublicKey)
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "user-1"})
+	confused.Header["kid"] = "2024-06"
+	confusedToken, _ := confused.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+
+	unknown := jwt.NewWithClaims(SigningMethodEdDSA, &Claims{UserID: "user-1"})
+	unknown.Header["kid"] = "2023-01"
+	unknownToken, _ := unknown.SignedString(testEdKey)
+
+	tests := []struct {
+		name    string
+		service *Service
+		token   string
+		kid     string
+		ok      bool
+	}{
+		{"old token before rotation", before, oldToken, "2024-01", true},
+		{"old token during rotation", during, oldToken, "2024-01", true},
+		{"new token during rotation", during, newToken, "2024-06", true},
+		{"old token after its key is removed", after, oldToken, "2024-01", false},
+		{"new token after rotation", after, newToken, "2024-06", true},
+		{"HMAC token using a public key", after, confusedToken, "2024-06", false},
+		{"unknown kid", after, unknownToken, "2023-01", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, _, err := new(jwt.Parser).ParseUnverified(tt.token, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid := parsed.Header["kid"]; kid != tt.kid {
+				t.Fatalf("kid = %v, want %s", kid, tt.kid)
+			}
+
+			claims, err := tt.service.ValidateAccessToken(tt.token)
+			if tt.ok != (err == nil) {
+				t.Fatalf("ValidateAccessToken() error = %v, want ok = %v", err, tt.ok)
+			}
+			if tt.ok && claims.UserID != "user-1" {
+				t.Fatalf("claims.UserID = %q", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "rsa", rsaKey(t), false)
+	writeKey(t, dir, "ed", testEdKey, true)
+	s := newKeyService(t, dir, "rsa")
+
+	jwks := s.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "ed" || jwks.Keys[1].KeyID != "rsa" {
+		t.Fatalf("JWKS() keys = %+v, want ed then rsa", jwks.Keys)
+	}
+
+	ed := jwks.Keys[0]
+	x, _ := base64.RawURLEncoding.DecodeString(ed.X)
+	if ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Algorithm != "EdDSA" || ed.Use != "sig" ||
+		!bytes.Equal(x, testEdKey.Public().(ed25519.PublicKey)) {
+		t.Errorf("Ed25519 JWK = %+v", ed)
+	}
+
+	rs := jwks.Keys[1]
+	n, _ := base64.RawURLEncoding.DecodeString(rs.N)
+	e, _ := base64.RawURLEncoding.DecodeString(rs.E)
+	pub := rsaKey(t).PublicKey
+	if rs.KeyType != "RSA" || rs.Algorithm != "RS256" || rs.Use != "sig" ||
+		new(big.Int).SetBytes(n).Cmp(pub.N) != 0 || new(big.Int).SetBytes(e).Int64() != int64(pub.E) {
+		t.Errorf("RSA JWK = %+v", rs)
+	}
+
+	// Only public parameters are published
+	data, _ := json.Marshal(jwks)
+	var raw struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range raw.Keys {
+		for _, private := range []string{"d", "p", "q", "dp", "dq", "qi"} {
+			if _, ok := key[private]; ok {
+				t.Errorf("JWK %v publishes %q", key["kid"], private)
+			}
+		}
+	}
+
+	if empty := NewService("test-secret", nil, time.Minute, time.Hour).JWKS(); empty.Keys == nil || len(empty.Keys) != 0 {
+		t.Errorf("JWKS() without keys = %+v, want an empty list", empty)
+	}
+}
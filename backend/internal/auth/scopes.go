@@ -0,0 +1,57 @@
+package auth
+
+import "strings"
+
+// PersonalAccessTokenPrefix distinguishes personal access tokens from JWTs
+const PersonalAccessTokenPrefix = "dtp_"
+
+// Scopes grantable to personal access tokens
+const (
+	ScopeRead       = "read"
+	ScopeWritePosts = "write:posts"
+	ScopeWriteReels = "write:reels"
+	ScopeAdmin      = "admin"
+)
+
+// ValidScope reports whether scope is a known personal access token scope
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeWritePosts, ScopeWriteReels, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// GeneratePersonalAccessToken creates a new prefixed personal access token
+func (s *Service) GeneratePersonalAccessToken() (string, error) {
+	token, err := s.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + strings.TrimRight(token, "="), nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// ViaPersonalAccessToken reports whether the request authenticated with a
+// personal access token rather than an interactive session
+func (c *Claims) ViaPersonalAccessToken() bool {
+	return c.TokenID != ""
+}
+
+// HasScope reports whether the credential grants scope. Interactive sessions
+// are not scoped and may do anything the user can.
+func (c *Claims) HasScope(scope string) bool {
+	if !c.ViaPersonalAccessToken() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
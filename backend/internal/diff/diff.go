@@ -0,0 +1,111 @@
+// Package diff compares texts line by line, for showing what changed between
+// versions of a post
+package diff
+
+import "strings"
+
+// Op is what happened to a line
+type Op string
+
+const (
+	Equal  Op = "EQUAL"
+	Insert Op = "INSERT"
+	Delete Op = "DELETE"
+)
+
+// Line is a line of a diff
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// maxCells bounds the table of the longest common subsequence to about 2 MB.
+// Texts too far apart to compare within it are shown as wholly replaced.
+const maxCells = 250_000
+
+// Lines returns the edits that turn a into b: the lines of a longest common
+// subsequence are kept and the rest deleted from a or inserted from b
+func Lines(a, b string) []Line {
+	x, y := split(a), split(b)
+
+	// Common leading and trailing lines need no table
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for _, text := range x[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	lines = append(lines, middle(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	for _, text := range x[len(x)-suffix:] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	return lines
+}
+
+// middle diffs what is left between the common prefix and suffix
+func middle(x, y []string) []Line {
+	var lines []Line
+	if len(x)*len(y) > maxCells {
+		for _, text := range x {
+			lines = append(lines, Line{Op: Delete, Text: text})
+		}
+		for _, text := range y {
+			lines = append(lines, Line{Op: Insert, Text: text})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, Line{Op: Equal, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, Line{Op: Delete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, Line{Op: Insert, Text: y[j]})
+	}
+	return lines
+}
+
+// split breaks text into lines. Empty text has no lines.
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
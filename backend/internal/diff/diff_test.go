@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"both empty", "", "", nil},
+		{"added", "", "a\n", []Line{{Insert, "a"}}},
+		{"removed", "a\nb", "a", []Line{{Equal, "a"}, {Delete, "b"}}},
+		{
+			name: "changed line between common ones",
+			a:    "func f() {\n\treturn 1\n}\n",
+			b:    "func f() {\n\treturn 2\n}\n",
+			want: []Line{{Equal, "func f() {"}, {Delete, "\treturn 1"}, {Insert, "\treturn 2"}, {Equal, "}"}},
+		},
+		{
+			name: "moved line",
+			a:    "a\nb\nc",
+			b:    "b\nc\na",
+			want: []Line{{Delete, "a"}, {Equal, "b"}, {Equal, "c"}, {Insert, "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesTooFarApart(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&a, "a%d\n", i)
+		fmt.Fprintf(&b, "b%d\n", i)
+	}
+
+	lines := Lines(a.String(), b.String())
+	if len(lines) != 1200 || lines[0] != (Line{Delete, "a0"}) || lines[600] != (Line{Insert, "b0"}) {
+		t.Fatalf("Lines() of texts past maxCells = %d lines starting %v, want all of a deleted then all of b inserted", len(lines), lines[0])
+	}
+}
@@ -0,0 +1,47 @@
+package foryou
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cursor is a position in a ranking: when it was computed, in Unix
+// milliseconds, and the score and ID of the last post returned
+type cursor struct {
+	AsOf  int64              `json:"t"`
+	Score float64            `json:"s"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+// precedes reports whether s ranks after the cursor
+func (c *cursor) precedes(s Scored) bool {
+	if s.Score != c.Score {
+		return s.Score < c.Score
+	}
+	return bytes.Compare(s.Post.ID[:], c.ID[:]) < 0
+}
+
+func encodeCursor(c *cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID.IsZero() || c.AsOf <= 0 {
+		return nil, pagination.ErrInvalidCursor
+	}
+	return &c, nil
+}
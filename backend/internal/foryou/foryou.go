@@ -0,0 +1,419 @@
+// Package foryou ranks the FOR_YOU feed. A viewer's affinity to tags,
+// languages and authors is learned from their recent engagements and the
+// users they follow. Candidates are drawn from posts matching their strongest
+// interests plus the hottest posts overall, and those the viewer has not seen
+// or muted are ranked by how well they match.
+//
+// The pipeline reads through a Store, so it runs against MemoryStore as well
+// as the database.
+package foryou
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store is where the pipeline reads engagements, follows and posts from
+type Store interface {
+	// Engagements returns up to limit of the user's engagements with posts
+	// made between since and until, newest first
+	Engagements(ctx context.Context, userID primitive.ObjectID, since, until time.Time, limit int) ([]*models.Engagement, error)
+	// Following returns up to limit users the user had followed by until
+	// and still follows
+	Following(ctx context.Context, userID primitive.ObjectID, until time.Time, limit int) ([]primitive.ObjectID, error)
+	// Posts returns the posts among ids that are not deleted
+	Posts(ctx context.Context, ids []primitive.ObjectID) ([]*models.Post, error)
+	// Candidates returns up to query.Limit public posts matching query,
+	// hottest first
+	Candidates(ctx context.Context, query Query) ([]*models.Post, error)
+	// Seen returns which of postIDs the user has engaged with in any way
+	Seen(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+}
+
+// Query selects candidate posts created between Since and Until. Posts by
+// any of Authors, with any of Tags or in any of Languages match; when all
+// three are empty every post does.
+type Query struct {
+	Since     time.Time
+	Until     time.Time
+	Authors   []primitive.ObjectID
+	Tags      []string
+	Languages []string
+	Limit     int
+}
+
+func (q Query) matchesAll() bool {
+	return len(q.Authors) == 0 && len(q.Tags) == 0 && len(q.Languages) == 0
+}
+
+// Viewer is whose feed is ranked, with what they muted
+type Viewer struct {
+	ID         primitive.ObjectID
+	MutedUsers []primitive.ObjectID
+	MutedTags  []string
+}
+
+// Config tunes what is learned and how candidates are scored
+type Config struct {
+	// What an engagement or a follow adds to the affinities it touches
+	Like   float64
+	Upvote float64
+	View   float64
+	Follow float64
+	// HalfLife is how long until an engagement counts half as much
+	HalfLife time.Duration
+
+	// Weights of each affinity in a post's score, and of its hot score
+	// relative to the hottest candidate
+	Tag      float64
+	Language float64
+	Author   float64
+	Hot      float64
+
+	// Window is how far back engagements are learned from and candidates
+	// are drawn from
+	Window time.Duration
+	// MaxEngagements and MaxFollowing bound what is learned from
+	MaxEngagements int
+	MaxFollowing   int
+	// TopInterests is how many tags, languages and authors candidates are
+	// drawn from
+	TopInterests int
+	// MaxCandidates bounds the posts drawn from interests, MaxExplore those
+	// drawn from the hottest posts so new interests can surface
+	MaxCandidates int
+	MaxExplore    int
+}
+
+// DefaultConfig weighs active engagement above views and authors above
+// topics
+var DefaultConfig = Config{
+	Like:     3,
+	Upvote:   3,
+	View:     1,
+	Follow:   5,
+	HalfLife: 7 * 24 * time.Hour,
+
+	Tag:      1,
+	Language: 0.5,
+	Author:   1.5,
+	Hot:      1,
+
+	Window:         14 * 24 * time.Hour,
+	MaxEngagements: 500,
+	MaxFollowing:   500,
+	TopInterests:   20,
+	MaxCandidates:  500,
+	MaxExplore:     100,
+}
+
+// Profile is a viewer's affinity to tags, languages and authors, each scaled
+// so the strongest is 1
+type Profile struct {
+	Tags      map[string]float64
+	Languages map[string]float64
+	Authors   map[primitive.ObjectID]float64
+}
+
+// Scored is a ranked post
+type Scored struct {
+	Post  *models.Post
+	Score float64
+}
+
+// Ranker builds FOR_YOU feeds
+type Ranker struct {
+	store  Store
+	config Config
+	now    func() time.Time
+}
+
+func NewRanker(store Store, config Config) *Ranker {
+	return &Ranker{store: store, config: config, now: time.Now}
+}
+
+// Feed returns a page of the viewer's feed, best match first. The ranking is
+// computed as of the time of the first page and the cursors carry that time:
+// engagements, follows and posts made after it are left out, so later pages
+// learn the same profile from the same candidates. Posts seen or muted in the
+// meantime drop out without shifting the rest. Hot scores and unfollows are
+// read as they are now, so a post whose hot score is refreshed between pages
+// can move across the cursor and be skipped or shown twice.
+func (r *Ranker) Feed(ctx context.Context, viewer Viewer, first int, after string) (*pagination.Page[models.Post], error) {
+	asOf := r.now().Truncate(time.Millisecond)
+	var from *cursor
+	if after != "" {
+		c, err := decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		asOf = time.UnixMilli(c.AsOf)
+		from = c
+	}
+
+	ranked, err := r.Rank(ctx, viewer, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &pagination.Page[models.Post]{Edges: []pagination.Edge[models.Post]{}}
+	for _, s := range ranked {
+		if from != nil && !from.precedes(s) {
+			continue
+		}
+		if len(page.Edges) == first {
+			page.HasNextPage = true
+			break
+		}
+
+		next, err := encodeCursor(&cursor{AsOf: asOf.UnixMilli(), Score: s.Score, ID: s.Post.ID})
+		if err != nil {
+			return nil, err
+		}
+		page.Edges = append(page.Edges, pagination.Edge[models.Post]{Node: s.Post, Cursor: next})
+	}
+	return page, nil
+}
+
+// Rank returns every candidate post for the viewer, best match first, with
+// ties broken by newest ID
+func (r *Ranker) Rank(ctx context.Context, viewer Viewer, asOf time.Time) ([]Scored, error) {
+	profile, err := r.Learn(ctx, viewer.ID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := r.candidates(ctx, profile, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(candidates))
+	for i, p := range candidates {
+		ids[i] = p.ID
+	}
+	seen, err := r.store.Seen(ctx, viewer.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	mutedUsers := make(map[primitive.ObjectID]bool, len(viewer.MutedUsers))
+	for _, id := range viewer.MutedUsers {
+		mutedUsers[id] = true
+	}
+
+	// Hot scores are scaled before filtering, so posts dropping out as they
+	// are seen don't change the scores of the rest
+	maxHot := 0.0
+	for _, p := range candidates {
+		maxHot = math.Max(maxHot, p.HotScore)
+	}
+
+	var kept []*models.Post
+	for _, p := range candidates {
+		if p.AuthorID == viewer.ID || seen[p.ID] || mutedUsers[p.AuthorID] ||
+			hasMutedTag(p.Tags, viewer.MutedTags) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+
+	ranked := make([]Scored, len(kept))
+	for i, p := range kept {
+		ranked[i] = Scored{Post: p, Score: r.score(profile, p, maxHot)}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return bytes.Compare(ranked[i].Post.ID[:], ranked[j].Post.ID[:]) > 0
+	})
+	return ranked, nil
+}
+
+// Learn builds the user's profile from their engagements within the window
+// before asOf, weighted by type and decayed by age, and from who they had
+// followed by asOf
+func (r *Ranker) Learn(ctx context.Context, userID primitive.ObjectID, asOf time.Time) (*Profile, error) {
+	profile := &Profile{
+		Tags:      map[string]float64{},
+		Languages: map[string]float64{},
+		Authors:   map[primitive.ObjectID]float64{},
+	}
+
+	engagements, err := r.store.Engagements(ctx, userID, asOf.Add(-r.config.Window), asOf, r.config.MaxEngagements)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := map[primitive.ObjectID]float64{}
+	for _, e := range engagements {
+		weights[e.TargetID] += r.engagementWeight(e.Type) * r.decay(asOf.Sub(e.CreatedAt))
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+	posts, err := r.store.Posts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range posts {
+		if p.AuthorID == userID {
+			continue
+		}
+		w := weights[p.ID]
+		for _, tag := range p.Tags {
+			profile.Tags[tag] += w
+		}
+		if p.Language != "" {
+			profile.Languages[p.Language] += w
+		}
+		profile.Authors[p.AuthorID] += w
+	}
+
+	following, err := r.store.Following(ctx, userID, asOf, r.config.MaxFollowing)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range following {
+		profile.Authors[id] += r.config.Follow
+	}
+
+	scaleToMax(profile.Tags)
+	scaleToMax(profile.Languages)
+	scaleToMax(profile.Authors)
+	return profile, nil
+}
+
+// candidates draws posts created within the window before asOf matching the
+// profile's strongest interests, and the hottest such posts overall, without
+// duplicates
+func (r *Ranker) candidates(ctx context.Context, profile *Profile, asOf time.Time) ([]*models.Post, error) {
+	since := asOf.Add(-r.config.Window)
+	queries := []Query{{Since: since, Until: asOf, Limit: r.config.MaxExplore}}
+
+	interests := Query{
+		Since:     since,
+		Until:     asOf,
+		Authors:   top(profile.Authors, r.config.TopInterests, primitive.ObjectID.Hex),
+		Tags:      top(profile.Tags, r.config.TopInterests, func(s string) string { return s }),
+		Languages: top(profile.Languages, r.config.TopInterests, func(s string) string { return s }),
+		Limit:     r.config.MaxCandidates,
+	}
+	if !interests.matchesAll() {
+		queries = append(queries, interests)
+	}
+
+	var candidates []*models.Post
+	included := map[primitive.ObjectID]bool{}
+	for _, q := range queries {
+		posts, err := r.store.Candidates(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range posts {
+			if !included[p.ID] {
+				included[p.ID] = true
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// score adds up the viewer's affinity to the post's best tag, its language
+// and its author, plus its hot score relative to the hottest candidate
+func (r *Ranker) score(profile *Profile, p *models.Post, maxHot float64) float64 {
+	tag := 0.0
+	for _, t := range p.Tags {
+		tag = math.Max(tag, profile.Tags[t])
+	}
+
+	hot := 0.0
+	if maxHot > 0 {
+		hot = p.HotScore / maxHot
+	}
+
+	return r.config.Tag*tag +
+		r.config.Language*profile.Languages[p.Language] +
+		r.config.Author*profile.Authors[p.AuthorID] +
+		r.config.Hot*hot
+}
+
+func (r *Ranker) engagementWeight(engagementType string) float64 {
+	switch engagementType {
+	case models.EngagementLike:
+		return r.config.Like
+	case models.EngagementUpvote:
+		return r.config.Upvote
+	case models.EngagementView:
+		return r.config.View
+	}
+	return 0
+}
+
+// decay halves a weight every HalfLife
+func (r *Ranker) decay(age time.Duration) float64 {
+	if r.config.HalfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(r.config.HalfLife))
+}
+
+func hasMutedTag(tags, muted []string) bool {
+	for _, tag := range tags {
+		for _, m := range muted {
+			if strings.EqualFold(tag, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scaleToMax divides the affinities by the strongest one
+func scaleToMax[K comparable](affinities map[K]float64) {
+	strongest := 0.0
+	for _, v := range affinities {
+		strongest = math.Max(strongest, v)
+	}
+	if strongest == 0 {
+		return
+	}
+	for k, v := range affinities {
+		affinities[k] = v / strongest
+	}
+}
+
+// top returns the n keys with the highest positive affinity. Ties are broken
+// by key so the same profile always draws the same candidates.
+func top[K comparable](affinities map[K]float64, n int, key func(K) string) []K {
+	keys := make([]K, 0, len(affinities))
+	for k, v := range affinities {
+		if v > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := affinities[keys[i]], affinities[keys[j]]
+		if a != b {
+			return a > b
+		}
+		return key(keys[i]) < key(keys[j])
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
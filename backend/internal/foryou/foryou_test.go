@@ -0,0 +1,305 @@
+package foryou
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	now    = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	viewer = primitive.NewObjectID()
+	alice  = primitive.NewObjectID()
+	bob    = primitive.NewObjectID()
+)
+
+func newPost(author primitive.ObjectID, language string, hot float64, tags ...string) *models.Post {
+	return &models.Post{
+		ID:         primitive.NewObjectID(),
+		AuthorID:   author,
+		Language:   language,
+		Tags:       tags,
+		Visibility: models.VisibilityPublic,
+		HotScore:   hot,
+		CreatedAt:  now.Add(-time.Hour),
+	}
+}
+
+func engage(p *models.Post, engagementType string, at time.Time) *models.Engagement {
+	return &models.Engagement{
+		UserID:     viewer,
+		TargetType: models.TargetPost,
+		TargetID:   p.ID,
+		Type:       engagementType,
+		CreatedAt:  at,
+	}
+}
+
+func newTestRanker(store Store) *Ranker {
+	r := NewRanker(store, DefaultConfig)
+	r.now = func() time.Time { return now }
+	return r
+}
+
+func TestLearn(t *testing.T) {
+	goPost := newPost(alice, "go", 0, "go")
+	rustPost := newPost(bob, "rust", 0, "rust")
+	ownPost := newPost(viewer, "zig", 0, "zig")
+
+	tests := []struct {
+		name        string
+		engagements []*models.Engagement
+		follows     []*models.Follow
+		tags        map[string]float64
+		languages   map[string]float64
+		authors     map[primitive.ObjectID]float64
+	}{
+		{
+			name:        "likes count three times a view",
+			engagements: []*models.Engagement{engage(goPost, models.EngagementLike, now), engage(rustPost, models.EngagementView, now)},
+			tags:        map[string]float64{"go": 1, "rust": 1.0 / 3},
+			languages:   map[string]float64{"go": 1, "rust": 1.0 / 3},
+			authors:     map[primitive.ObjectID]float64{alice: 1, bob: 1.0 / 3},
+		},
+		{
+			name: "engagement a half-life old counts half",
+			engagements: []*models.Engagement{
+				engage(goPost, models.EngagementUpvote, now.Add(-time.Minute)),
+				engage(rustPost, models.EngagementUpvote, now.Add(-time.Minute-DefaultConfig.HalfLife)),
+			},
+			tags:      map[string]float64{"go": 1, "rust": 0.5},
+			languages: map[string]float64{"go": 1, "rust": 0.5},
+			authors:   map[primitive.ObjectID]float64{alice: 1, bob: 0.5},
+		},
+		{
+			name: "engagement outside the window or after asOf is ignored",
+			engagements: []*models.Engagement{
+				engage(goPost, models.EngagementLike, now.Add(-DefaultConfig.Window-time.Minute)),
+				engage(rustPost, models.EngagementLike, now.Add(time.Minute)),
+			},
+			tags:      map[string]float64{},
+			languages: map[string]float64{},
+			authors:   map[primitive.ObjectID]float64{},
+		},
+		{
+			name:        "own posts teach nothing",
+			engagements: []*models.Engagement{engage(ownPost, models.EngagementLike, now)},
+			tags:        map[string]float64{},
+			languages:   map[string]float64{},
+			authors:     map[primitive.ObjectID]float64{},
+		},
+		{
+			name: "follows made by asOf add to authors",
+			follows: []*models.Follow{
+				{FollowerID: viewer, FolloweeID: alice, CreatedAt: now.Add(-time.Hour)},
+				{FollowerID: viewer, FolloweeID: bob, CreatedAt: now.Add(time.Hour)},
+			},
+			tags:      map[string]float64{},
+			languages: map[string]float64{},
+			authors:   map[primitive.ObjectID]float64{alice: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore([]*models.Post{goPost, rustPost, ownPost}, tt.engagements, tt.follows)
+			profile, err := newTestRanker(store).Learn(context.Background(), viewer, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertAffinities(t, "tags", profile.Tags, tt.tags)
+			assertAffinities(t, "languages", profile.Languages, tt.languages)
+			assertAffinities(t, "authors", profile.Authors, tt.authors)
+		})
+	}
+}
+
+func assertAffinities[K comparable](t *testing.T, name string, got, want map[K]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+		return
+	}
+	for k, w := range want {
+		if math.Abs(got[k]-w) > 1e-9 {
+			t.Errorf("%s[%v] = %v, want %v", name, k, got[k], w)
+		}
+	}
+}
+
+func TestRankExclusions(t *testing.T) {
+	goPost := newPost(alice, "go", 3, "go")
+	rustPost := newPost(bob, "rust", 2, "Rust")
+	ownPost := newPost(viewer, "go", 5, "go")
+	seenPost := newPost(alice, "go", 4, "go")
+	draft := newPost(bob, "go", 6, "go")
+	draft.Visibility = models.VisibilityPrivate
+
+	tests := []struct {
+		name   string
+		viewer Viewer
+		want   []*models.Post
+	}{
+		{"own, seen and non-public posts are left out", Viewer{ID: viewer}, []*models.Post{goPost, rustPost}},
+		{"muted user", Viewer{ID: viewer, MutedUsers: []primitive.ObjectID{alice}}, []*models.Post{rustPost}},
+		{"muted tag ignores case", Viewer{ID: viewer, MutedTags: []string{"rust"}}, []*models.Post{goPost}},
+		{"everything muted", Viewer{ID: viewer, MutedUsers: []primitive.ObjectID{bob}, MutedTags: []string{"GO"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(
+				[]*models.Post{goPost, rustPost, ownPost, seenPost, draft},
+				[]*models.Engagement{engage(seenPost, models.EngagementView, now.Add(-time.Minute))},
+				nil,
+			)
+			ranked, err := newTestRanker(store).Rank(context.Background(), tt.viewer, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertPosts(t, posts(ranked), tt.want, false)
+		})
+	}
+}
+
+func posts(ranked []Scored) []*models.Post {
+	result := make([]*models.Post, len(ranked))
+	for i, s := range ranked {
+		result[i] = s.Post
+	}
+	return result
+}
+
+// assertPosts compares posts by ID, in order or as sets
+func assertPosts(t *testing.T, got, want []*models.Post, ordered bool) {
+	t.Helper()
+	ids := func(ps []*models.Post) []string {
+		result := make([]string, len(ps))
+		for i, p := range ps {
+			result[i] = p.ID.Hex()
+		}
+		if !ordered {
+			sort.Strings(result)
+		}
+		return result
+	}
+	g, w := ids(got), ids(want)
+	if len(g) != len(w) {
+		t.Fatalf("got %v, want %v", g, w)
+	}
+	for i := range g {
+		if g[i] != w[i] {
+			t.Fatalf("got %v, want %v", g, w)
+		}
+	}
+}
+
+func TestFeedCursorContinuity(t *testing.T) {
+	var all []*models.Post
+	for i := 0; i < 8; i++ {
+		author, tag := alice, "go"
+		if i%2 == 1 {
+			author, tag = bob, "rust"
+		}
+		all = append(all, newPost(author, tag, float64(i), tag))
+	}
+	liked := newPost(alice, "go", 0, "go")
+	engagements := []*models.Engagement{engage(liked, models.EngagementLike, now.Add(-time.Hour))}
+
+	tests := []struct {
+		name string
+		// between runs after the first page is read
+		between func(store *MemoryStore, r *Ranker, first []*models.Post)
+		// dropped returns which posts of the first ranking later pages leave
+		// out
+		dropped func(first []*models.Post) []*models.Post
+	}{
+		{
+			name:    "unchanged",
+			between: func(*MemoryStore, *Ranker, []*models.Post) {},
+			dropped: func([]*models.Post) []*models.Post { return nil },
+		},
+		{
+			name: "later activity neither shifts nor joins the ranking",
+			between: func(store *MemoryStore, r *Ranker, first []*models.Post) {
+				later := now.Add(time.Minute)
+				r.now = func() time.Time { return later }
+
+				fresh := newPost(bob, "rust", 100, "rust")
+				fresh.CreatedAt = later
+				store.posts = append(store.posts, fresh)
+				store.follows = append(store.follows, &models.Follow{FollowerID: viewer, FolloweeID: bob, CreatedAt: later})
+				store.engagements = append(store.engagements, &models.Engagement{
+					UserID: viewer, TargetType: models.TargetPost, TargetID: fresh.ID, Type: models.EngagementLike, CreatedAt: later,
+				})
+			},
+			dropped: func([]*models.Post) []*models.Post { return nil },
+		},
+		{
+			name: "posts seen since drop out without shifting the rest",
+			between: func(store *MemoryStore, r *Ranker, first []*models.Post) {
+				seen := first[len(first)-2]
+				store.engagements = append(store.engagements, engage(seen, models.EngagementView, now.Add(time.Minute)))
+			},
+			dropped: func(first []*models.Post) []*models.Post { return []*models.Post{first[len(first)-2]} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(append([]*models.Post{liked}, all...), append([]*models.Engagement(nil), engagements...), nil)
+			r := newTestRanker(store)
+			ctx := context.Background()
+
+			ranked, err := r.Rank(ctx, Viewer{ID: viewer}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first := posts(ranked)
+
+			page, err := r.Feed(ctx, Viewer{ID: viewer}, 3, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []*models.Post{}
+			for _, e := range page.Edges {
+				got = append(got, e.Node)
+			}
+
+			tt.between(store, r, first)
+
+			for page.HasNextPage {
+				page, err = r.Feed(ctx, Viewer{ID: viewer}, 3, page.Edges[len(page.Edges)-1].Cursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, e := range page.Edges {
+					got = append(got, e.Node)
+				}
+			}
+
+			dropped := map[primitive.ObjectID]bool{}
+			for _, p := range tt.dropped(first) {
+				dropped[p.ID] = true
+			}
+			var want []*models.Post
+			for _, p := range first {
+				if !dropped[p.ID] {
+					want = append(want, p)
+				}
+			}
+			assertPosts(t, got, want, true)
+		})
+	}
+}
+
+func TestFeedInvalidCursor(t *testing.T) {
+	r := newTestRanker(NewMemoryStore(nil, nil, nil))
+	if _, err := r.Feed(context.Background(), Viewer{ID: viewer}, 10, "not-a-cursor"); !errors.Is(err, pagination.ErrInvalidCursor) {
+		t.Fatalf("Feed() error = %v, want ErrInvalidCursor", err)
+	}
+}
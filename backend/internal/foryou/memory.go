@@ -0,0 +1,117 @@
+package foryou
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore serves a fixed dataset from memory, for exercising the
+// pipeline without a database
+type MemoryStore struct {
+	posts       []*models.Post
+	engagements []*models.Engagement
+	follows     []*models.Follow
+}
+
+func NewMemoryStore(posts []*models.Post, engagements []*models.Engagement, follows []*models.Follow) *MemoryStore {
+	return &MemoryStore{posts: posts, engagements: engagements, follows: follows}
+}
+
+func (s *MemoryStore) Engagements(ctx context.Context, userID primitive.ObjectID, since, until time.Time, limit int) ([]*models.Engagement, error) {
+	var found []*models.Engagement
+	for _, e := range s.engagements {
+		if e.UserID == userID && e.TargetType == models.TargetPost && !e.CreatedAt.Before(since) && !e.CreatedAt.After(until) {
+			found = append(found, e)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].CreatedAt.After(found[j].CreatedAt) })
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+func (s *MemoryStore) Following(ctx context.Context, userID primitive.ObjectID, until time.Time, limit int) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, f := range s.follows {
+		if f.FollowerID == userID && !f.CreatedAt.After(until) && len(ids) < limit {
+			ids = append(ids, f.FolloweeID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *MemoryStore) Posts(ctx context.Context, ids []primitive.ObjectID) ([]*models.Post, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var found []*models.Post
+	for _, p := range s.posts {
+		if wanted[p.ID] && !p.Deleted {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+func (s *MemoryStore) Candidates(ctx context.Context, query Query) ([]*models.Post, error) {
+	authors := make(map[primitive.ObjectID]bool, len(query.Authors))
+	for _, id := range query.Authors {
+		authors[id] = true
+	}
+	tags := make(map[string]bool, len(query.Tags))
+	for _, tag := range query.Tags {
+		tags[tag] = true
+	}
+	languages := make(map[string]bool, len(query.Languages))
+	for _, language := range query.Languages {
+		languages[language] = true
+	}
+
+	var found []*models.Post
+	for _, p := range s.posts {
+		if p.Deleted || p.Visibility != models.VisibilityPublic || p.CreatedAt.Before(query.Since) || p.CreatedAt.After(query.Until) {
+			continue
+		}
+		matches := query.matchesAll() || authors[p.AuthorID] || languages[p.Language]
+		for _, tag := range p.Tags {
+			matches = matches || tags[tag]
+		}
+		if matches {
+			found = append(found, p)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].HotScore != found[j].HotScore {
+			return found[i].HotScore > found[j].HotScore
+		}
+		return bytes.Compare(found[i].ID[:], found[j].ID[:]) > 0
+	})
+	if len(found) > query.Limit {
+		found = found[:query.Limit]
+	}
+	return found, nil
+}
+
+func (s *MemoryStore) Seen(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for _, e := range s.engagements {
+		if e.UserID == userID && e.TargetType == models.TargetPost && wanted[e.TargetID] {
+			seen[e.TargetID] = true
+		}
+	}
+	return seen, nil
+}
@@ -0,0 +1,50 @@
+package foryou
+
+import (
+	"context"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RepositoryStore reads from the database through the repositories
+type RepositoryStore struct {
+	posts       *repository.PostRepository
+	engagements *repository.EngagementRepository
+	follows     *repository.FollowRepository
+}
+
+func NewRepositoryStore(posts *repository.PostRepository, engagements *repository.EngagementRepository, follows *repository.FollowRepository) *RepositoryStore {
+	return &RepositoryStore{posts: posts, engagements: engagements, follows: follows}
+}
+
+func (s *RepositoryStore) Engagements(ctx context.Context, userID primitive.ObjectID, since, until time.Time, limit int) ([]*models.Engagement, error) {
+	return s.engagements.FindRecentByUser(ctx, userID, models.TargetPost, since, until, limit)
+}
+
+func (s *RepositoryStore) Following(ctx context.Context, userID primitive.ObjectID, until time.Time, limit int) ([]primitive.ObjectID, error) {
+	return s.follows.FindFolloweeIDs(ctx, userID, until, limit)
+}
+
+func (s *RepositoryStore) Posts(ctx context.Context, ids []primitive.ObjectID) ([]*models.Post, error) {
+	return s.posts.FindByIDs(ctx, ids)
+}
+
+func (s *RepositoryStore) Candidates(ctx context.Context, query Query) ([]*models.Post, error) {
+	return s.posts.FindCandidates(ctx, query.Since, query.Until, query.Authors, query.Tags, query.Languages, query.Limit)
+}
+
+func (s *RepositoryStore) Seen(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	ids, err := s.engagements.FindEngagedTargets(ctx, userID, models.TargetPost, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return seen, nil
+}
@@ -0,0 +1,56 @@
+// Package jobs runs background work such as data exports on an interval.
+// Jobs must be safe to run on several instances at once, since every server
+// process runs them.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is a unit of background work
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+
+	// Wake, when set, runs the job before its interval has elapsed
+	Wake <-chan struct{}
+}
+
+// Start runs each job in its own goroutine until ctx is cancelled. Jobs run
+// once straight away, then after every interval. Errors are logged and the
+// job is retried on its next run.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go loop(ctx, job)
+	}
+}
+
+func loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Job %s failed: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-job.Wake:
+		}
+	}
+}
+
+// Notify wakes a job without blocking. A wake-up that is already pending
+// covers this one.
+func Notify(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,101 @@
+// Package lockout throttles repeated failed attempts, such as password
+// guesses, with exponential backoff. Counters live behind a Store so they can
+// be shared between instances.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps failure counters and locks. Implementations must make
+// Increment atomic, because concurrent attempts race on the same key.
+type Store interface {
+	// Increment adds a failure to key and returns the new count. The count
+	// expires window after the latest failure.
+	Increment(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock blocks key until the given time
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil returns when the lock on key ends, or the zero time when
+	// key is not locked
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset clears the failures and lock of key
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy decides when a key is locked and for how long
+type Policy struct {
+	// MaxFailures is how many failures are allowed before locking
+	MaxFailures int
+	// Window is how long failures are remembered after the latest one
+	Window time.Duration
+	// BaseLock is the first lock duration. Each further failure doubles it.
+	BaseLock time.Duration
+	// MaxLock caps the lock duration
+	MaxLock time.Duration
+}
+
+// lockDuration returns how long to lock after the given number of failures
+func (p Policy) lockDuration(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+
+	d := p.BaseLock
+	for i := p.MaxFailures; i < failures && d < p.MaxLock; i++ {
+		d *= 2
+	}
+	if d > p.MaxLock {
+		d = p.MaxLock
+	}
+	return d
+}
+
+// Limiter applies a Policy to keys in a Store
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, now: time.Now}
+}
+
+// Check returns how long the caller has to wait before key may be tried
+// again, or zero when it is not locked
+func (l *Limiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	until, err := l.store.LockedUntil(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if wait := until.Sub(l.now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Failure records a failed attempt and returns how long key is now locked
+// for, or zero when it is still below the limit
+func (l *Limiter) Failure(ctx context.Context, key string) (time.Duration, error) {
+	failures, err := l.store.Increment(ctx, key, l.policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	lock := l.policy.lockDuration(failures)
+	if lock == 0 {
+		return 0, nil
+	}
+
+	if err := l.store.Lock(ctx, key, l.now().Add(lock)); err != nil {
+		return 0, err
+	}
+	return lock, nil
+}
+
+// Reset forgets the failures of key after a successful attempt
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, key)
+}
@@ -0,0 +1,210 @@
+package lockout
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPolicyLockDuration(t *testing.T) {
+	policy := Policy{MaxFailures: 3, Window: time.Hour, BaseLock: time.Minute, MaxLock: 10 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.lockDuration(tt.failures); got != tt.want {
+			t.Errorf("lockDuration(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// step is one action against a Limiter in TestLimiter
+type step struct {
+	// advance moves the clock before the action
+	advance time.Duration
+	// action is "fail", "succeed" or "check"
+	action string
+	key    string
+	// want is the lock returned by a failure or the wait returned by a check
+	want time.Duration
+}
+
+func TestLimiter(t *testing.T) {
+	policy := Policy{MaxFailures: 2, Window: time.Hour, BaseLock: time.Minute, MaxLock: 5 * time.Minute}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "locks at the limit and doubles each further failure",
+			steps: []step{
+				{action: "fail", key: "a", want: 0},
+				{action: "check", key: "a", want: 0},
+				{action: "fail", key: "a", want: time.Minute},
+				{action: "check", key: "a", want: time.Minute},
+				{action: "fail", key: "a", want: 2 * time.Minute},
+				{action: "fail", key: "a", want: 4 * time.Minute},
+				{action: "fail", key: "a", want: 5 * time.Minute},
+				{action: "check", key: "a", want: 5 * time.Minute},
+			},
+		},
+		{
+			name: "lock expires",
+			steps: []step{
+				{action: "fail", key: "a"},
+				{action: "fail", key: "a", want: time.Minute},
+				{advance: 45 * time.Second, action: "check", key: "a", want: 15 * time.Second},
+				{advance: 15 * time.Second, action: "check", key: "a", want: 0},
+				// Failures are still remembered within the window
+				{action: "fail", key: "a", want: 2 * time.Minute},
+			},
+		},
+		{
+			name: "success resets failures and lock",
+			steps: []step{
+				{action: "fail", key: "a"},
+				{action: "fail", key: "a", want: time.Minute},
+				{action: "succeed", key: "a"},
+				{action: "check", key: "a", want: 0},
+				{action: "fail", key: "a", want: 0},
+			},
+		},
+		{
+			name: "keys are counted apart",
+			steps: []step{
+				{action: "fail", key: "a"},
+				{action: "fail", key: "b"},
+				{action: "fail", key: "a", want: time.Minute},
+				{action: "check", key: "b", want: 0},
+				{action: "succeed", key: "b"},
+				{action: "check", key: "a", want: time.Minute},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			l := NewLimiter(NewMemoryStore(), policy)
+			l.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				now = now.Add(s.advance)
+
+				var got time.Duration
+				var err error
+				switch s.action {
+				case "fail":
+					got, err = l.Failure(ctx, s.key)
+				case "succeed":
+					err = l.Reset(ctx, s.key)
+				case "check":
+					got, err = l.Check(ctx, s.key)
+				}
+				if err != nil {
+					t.Fatalf("step %d: %s(%s) error = %v", i, s.action, s.key, err)
+				}
+				if got != s.want {
+					t.Fatalf("step %d: %s(%s) = %s, want %s", i, s.action, s.key, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+// TestRedisStore runs the Store contract against the server in
+// LOCKOUT_TEST_REDIS_URL, such as redis://localhost:6379/15
+func TestRedisStore(t *testing.T) {
+	url := os.Getenv("LOCKOUT_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("LOCKOUT_TEST_REDIS_URL is not set")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(opts)
+	t.Cleanup(func() { client.Close() })
+
+	testStore(t, NewRedisStore(client))
+}
+
+// testStore checks the behaviour every Store must share. Keys are unique per
+// run, so a shared Redis needs no cleanup.
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	newKey := func() string { return "test:" + primitive.NewObjectID().Hex() }
+
+	t.Run("counts failures per key", func(t *testing.T) {
+		a, b := newKey(), newKey()
+		for want := 1; want <= 3; want++ {
+			if got, err := store.Increment(ctx, a, time.Minute); err != nil || got != want {
+				t.Fatalf("Increment() = %d, %v; want %d", got, err, want)
+			}
+		}
+		if got, err := store.Increment(ctx, b, time.Minute); err != nil || got != 1 {
+			t.Fatalf("Increment() of another key = %d, %v; want 1", got, err)
+		}
+	})
+
+	t.Run("forgets failures after the window", func(t *testing.T) {
+		key := newKey()
+		store.Increment(ctx, key, 50*time.Millisecond)
+		store.Increment(ctx, key, 50*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		if got, err := store.Increment(ctx, key, time.Minute); err != nil || got != 1 {
+			t.Fatalf("Increment() after the window = %d, %v; want 1", got, err)
+		}
+	})
+
+	t.Run("reports locks", func(t *testing.T) {
+		key := newKey()
+		if until, err := store.LockedUntil(ctx, key); err != nil || !until.IsZero() {
+			t.Fatalf("LockedUntil() of an unlocked key = %v, %v; want zero", until, err)
+		}
+
+		want := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+		if err := store.Lock(ctx, key, want); err != nil {
+			t.Fatal(err)
+		}
+		if until, err := store.LockedUntil(ctx, key); err != nil || !until.Equal(want) {
+			t.Fatalf("LockedUntil() = %v, %v; want %v", until, err, want)
+		}
+	})
+
+	t.Run("reset clears failures and lock", func(t *testing.T) {
+		key := newKey()
+		store.Increment(ctx, key, time.Minute)
+		store.Lock(ctx, key, time.Now().Add(time.Minute))
+
+		if err := store.Reset(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		if until, err := store.LockedUntil(ctx, key); err != nil || !until.IsZero() {
+			t.Fatalf("LockedUntil() after Reset = %v, %v; want zero", until, err)
+		}
+		if got, err := store.Increment(ctx, key, time.Minute); err != nil || got != 1 {
+			t.Fatalf("Increment() after Reset = %d, %v; want 1", got, err)
+		}
+	})
+}
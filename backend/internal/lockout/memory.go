@@ -0,0 +1,91 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps counters in process. Each instance counts on its own, so
+// use RedisStore when running more than one.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	failures    int
+	expiresAt   time.Time
+	lockedUntil time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Increment(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpiredLocked(now)
+
+	entry, ok := s.entries[key]
+	if !ok || entry.expiresAt.Before(now) {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.expiresAt = now.Add(window)
+	return entry.failures, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+
+	entry.lockedUntil = until
+	if entry.expiresAt.Before(until) {
+		entry.expiresAt = until
+	}
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	return entry.lockedUntil, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// evictExpiredLocked drops entries whose failures and lock have both
+// expired. It only sweeps once the map is large, so the cost is amortised.
+func (s *MemoryStore) evictExpiredLocked(now time.Time) {
+	if len(s.entries) < 10000 {
+		return
+	}
+	for key, entry := range s.entries {
+		if entry.expiresAt.Before(now) {
+			delete(s.entries, key)
+		}
+	}
+}
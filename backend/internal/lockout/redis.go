@@ -0,0 +1,66 @@
+package lockout
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore shares counters between instances. Keys expire on their own, so
+// nothing needs cleaning up.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client, prefix: "lockout:"}
+}
+
+func (s *RedisStore) failuresKey(key string) string {
+	return s.prefix + "failures:" + key
+}
+
+func (s *RedisStore) lockKey(key string) string {
+	return s.prefix + "lock:" + key
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, window time.Duration) (int, error) {
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, s.failuresKey(key))
+	pipe.PExpire(ctx, s.failuresKey(key), window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.lockKey(key), until.UnixMilli(), ttl).Err()
+}
+
+func (s *RedisStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	value, err := s.client.Get(ctx, s.lockKey(key)).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.failuresKey(key), s.lockKey(key)).Err()
+}
@@ -0,0 +1,168 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// smtpSink is a local SMTP server that accepts any mail and records it
+type smtpSink struct {
+	ln       net.Listener
+	received chan sinkMail
+}
+
+type sinkMail struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, received: make(chan sinkMail, 1)}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ready")
+	var mail sinkMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail.from = strings.TrimSuffix(strings.TrimPrefix(line[len("MAIL FROM:"):], "<"), ">")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.to = append(mail.to, strings.TrimSuffix(strings.TrimPrefix(line[len("RCPT TO:"):], "<"), ">"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			mail.data = data.String()
+			s.received <- mail
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	sink := newSMTPSink(t)
+	host, port, _ := net.SplitHostPort(sink.ln.Addr().String())
+	m := NewSMTPMailer(host, port, "", "", "DevThreads <no-reply@devthreads.local>")
+
+	err := m.Send(context.Background(), Message{
+		To:      "ada@example.com",
+		Subject: "Reset your DevThreads password",
+		Body:    "Hi ada,\n\nhttp://localhost:3000/reset-password?token=abc\n",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	mail := <-sink.received
+	if mail.from != "no-reply@devthreads.local" {
+		t.Errorf("MAIL FROM = %q", mail.from)
+	}
+	if len(mail.to) != 1 || mail.to[0] != "ada@example.com" {
+		t.Errorf("RCPT TO = %v", mail.to)
+	}
+	for _, want := range []string{
+		"To: ada@example.com\r\n",
+		"Subject: Reset your DevThreads password\r\n",
+		"\r\n\r\nHi ada,\r\n\r\nhttp://localhost:3000/reset-password?token=abc\r\n",
+	} {
+		if !strings.Contains(mail.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, mail.data)
+		}
+	}
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	m := NewSMTPMailer(host, port, "", "", "no-reply@devthreads.local")
+	if err := m.Send(context.Background(), Message{To: "ada@example.com"}); err == nil {
+		t.Fatal("Send() to a closed port succeeded")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	m, err := NewFileMailer(path, "no-reply@devthreads.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, to := range []string{"ada@example.com", "grace@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to, Subject: "Confirm your DevThreads email", Body: "Hi\n"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if n := strings.Count(got, "\r\n.\r\n"); n != 2 {
+		t.Errorf("file holds %d messages, want 2:\n%s", n, got)
+	}
+	for _, want := range []string{"To: ada@example.com\r\n", "To: grace@example.com\r\n", "From: no-reply@devthreads.local\r\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("file does not contain %q", want)
+		}
+	}
+}
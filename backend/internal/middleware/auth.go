@@ -2,14 +2,21 @@ package middleware
 
 import (
 	"context"
+	"log"
 	"strings"
 
 	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/rbac"
+	"github.com/devthreads/backend/internal/repository"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// AuthMiddleware extracts and validates JWT tokens
-func AuthMiddleware(authService *auth.Service) gin.HandlerFunc {
+// AuthMiddleware extracts and validates JWT tokens and personal access tokens.
+// Every credential is checked against the user's current state, so bans and
+// role changes apply to tokens that were issued before them, and access
+// tokens stop working once their session is signed out.
+func AuthMiddleware(authService *auth.Service, patRepo *repository.PersonalAccessTokenRepository, userState *UserStateCache, sessions *SessionStateCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
@@ -26,8 +33,7 @@ func AuthMiddleware(authService *auth.Service) gin.HandlerFunc {
 			return
 		}
 
-		token := parts[1]
-		claims, err := authService.ValidateAccessToken(token)
+		claims, err := authenticate(c.Request.Context(), authService, patRepo, userState, sessions, parts[1])
 		if err != nil {
 			// Invalid token, but don't block - just don't set user context
 			c.Next()
@@ -42,6 +48,87 @@ func AuthMiddleware(authService *auth.Service) gin.HandlerFunc {
 	}
 }
 
+// authenticate accepts an access token or a personal access token
+func authenticate(ctx context.Context, authService *auth.Service, patRepo *repository.PersonalAccessTokenRepository, userState *UserStateCache, sessions *SessionStateCache, token string) (*auth.Claims, error) {
+	if auth.IsPersonalAccessToken(token) {
+		return authenticatePersonalAccessToken(ctx, authService, patRepo, userState, token)
+	}
+	return authenticateAccessToken(ctx, authService, userState, sessions, token)
+}
+
+// authenticateAccessToken validates a JWT and rejects it when the user has
+// been banned, their token version has moved on since it was issued or the
+// session it was issued to has been signed out
+func authenticateAccessToken(ctx context.Context, authService *auth.Service, userState *UserStateCache, sessions *SessionStateCache, token string) (*auth.Claims, error) {
+	claims, err := authService.ValidateAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	state, err := userState.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Banned() || claims.TokenVersion != state.TokenVersion {
+		return nil, auth.ErrInvalidToken
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	active, err := sessions.Active(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, auth.ErrInvalidToken
+	}
+
+	// Trust the stored role over the one baked into the token
+	claims.Role = state.Role
+	return claims, nil
+}
+
+// authenticatePersonalAccessToken builds claims equivalent to a session JWT
+// for the token's owner, restricted to the token's scopes
+func authenticatePersonalAccessToken(ctx context.Context, authService *auth.Service, patRepo *repository.PersonalAccessTokenRepository, userState *UserStateCache, token string) (*auth.Claims, error) {
+	pat, err := patRepo.FindActiveByHash(ctx, authService.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := userState.Get(ctx, pat.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Banned() {
+		return nil, auth.ErrInvalidToken
+	}
+
+	if err := patRepo.TouchLastUsed(ctx, pat.ID); err != nil {
+		log.Printf("Failed to record personal access token usage: %v", err)
+	}
+
+	return &auth.Claims{
+		UserID:   pat.UserID.Hex(),
+		Username: state.Username,
+		Role:     state.Role,
+		// A token is only as strong as the session that created it
+		MFA:          pat.CreatedWithMFA,
+		TokenVersion: state.TokenVersion,
+		TokenID:      pat.ID.Hex(),
+		Scopes:       pat.Scopes,
+	}, nil
+}
+
 // RequireAuth ensures user is authenticated
 func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -55,11 +142,12 @@ func RequireAuth() gin.HandlerFunc {
 	}
 }
 
-// RequireAdmin ensures user is an admin
+// RequireAdmin ensures user is an admin. GraphQL fields are guarded with the
+// schema's @hasRole and @hasPermission directives instead.
 func RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, err := auth.GetUserFromContext(c.Request.Context())
-		if err != nil || !claims.IsAdmin {
+		if err != nil || claims.Role != rbac.RoleAdmin || !claims.HasScope(auth.ScopeAdmin) {
 			c.JSON(403, gin.H{"error": "Forbidden"})
 			c.Abort()
 			return
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientInfo describes the device a request came from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// ClientInfoMiddleware records the caller's user agent and IP in the request
+// context so resolvers can attach them to sessions
+func ClientInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		}
+
+		ctx := context.WithValue(c.Request.Context(), "client_info", info)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// ClientInfoFromContext returns the client info stored by ClientInfoMiddleware
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value("client_info").(ClientInfo)
+	return info
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionState records whether a session was still active when it was loaded
+type sessionState struct {
+	active    bool
+	fetchedAt time.Time
+}
+
+// SessionStateCache remembers for a short time whether sessions are active,
+// so that access tokens of a signed-out device stop working without a
+// database round trip on every request. Sessions ended by this instance call
+// Invalidate; other instances pick them up within ttl.
+type SessionStateCache struct {
+	sessionRepo *repository.SessionRepository
+	ttl         time.Duration
+
+	mu      sync.Mutex
+	entries map[primitive.ObjectID]*sessionState
+}
+
+func NewSessionStateCache(sessionRepo *repository.SessionRepository, ttl time.Duration) *SessionStateCache {
+	return &SessionStateCache{
+		sessionRepo: sessionRepo,
+		ttl:         ttl,
+		entries:     make(map[primitive.ObjectID]*sessionState),
+	}
+}
+
+// Active reports whether the session has been neither revoked nor expired,
+// loading it when the cached answer is missing or stale
+func (c *SessionStateCache) Active(ctx context.Context, sessionID primitive.ObjectID) (bool, error) {
+	c.mu.Lock()
+	state, ok := c.entries[sessionID]
+	c.mu.Unlock()
+
+	if ok && time.Since(state.fetchedAt) < c.ttl {
+		return state.active, nil
+	}
+
+	_, err := c.sessionRepo.FindActiveByID(ctx, sessionID)
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return false, err
+	}
+
+	state = &sessionState{active: err == nil, fetchedAt: time.Now()}
+
+	c.mu.Lock()
+	c.entries[sessionID] = state
+	c.evictExpiredLocked()
+	c.mu.Unlock()
+
+	return state.active, nil
+}
+
+// Invalidate drops the cached state of a session after it was ended
+func (c *SessionStateCache) Invalidate(sessionID primitive.ObjectID) {
+	c.mu.Lock()
+	delete(c.entries, sessionID)
+	c.mu.Unlock()
+}
+
+// evictExpiredLocked keeps the map from growing without bound, like
+// UserStateCache.evictExpiredLocked
+func (c *SessionStateCache) evictExpiredLocked() {
+	if len(c.entries) < 10000 {
+		return
+	}
+	for id, state := range c.entries {
+		if time.Since(state.fetchedAt) >= c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSessionStateCache(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("caches active sessions until invalidated", func(mt *mtest.T) {
+		cache := NewSessionStateCache(repository.NewSessionRepository(mt.DB), time.Minute)
+		id := primitive.NewObjectID()
+		ns := mt.DB.Name() + ".sessions"
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		for i := 0; i < 2; i++ {
+			active, err := cache.Active(context.Background(), id)
+			if err != nil || !active {
+				mt.Fatalf("Active() = %v, %v; want true", active, err)
+			}
+		}
+
+		// A signed-out session no longer matches the active filter
+		cache.Invalidate(id)
+		active, err := cache.Active(context.Background(), id)
+		if err != nil || active {
+			mt.Fatalf("Active() after sign-out = %v, %v; want false", active, err)
+		}
+	})
+
+	mt.Run("reports lookup failures", func(mt *mtest.T) {
+		cache := NewSessionStateCache(repository.NewSessionRepository(mt.DB), time.Minute)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 1, Message: "boom"}))
+
+		if _, err := cache.Active(context.Background(), primitive.NewObjectID()); err == nil {
+			mt.Fatal("Active() hid a lookup failure")
+		}
+	})
+}
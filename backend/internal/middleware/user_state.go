@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserState is the part of a user record that decides whether their tokens
+// are still honoured and what they may do
+type UserState struct {
+	Username     string
+	Role         string
+	TokenVersion int
+	BannedUntil  *time.Time
+	fetchedAt    time.Time
+}
+
+// Banned reports whether the user is currently banned
+func (s *UserState) Banned() bool {
+	return s.BannedUntil != nil && s.BannedUntil.After(time.Now())
+}
+
+// UserStateCache keeps recently loaded user states for a short time so that
+// every request can be checked without a database round trip. Changes made
+// by this instance call Invalidate; other instances pick them up within ttl.
+type UserStateCache struct {
+	userRepo *repository.UserRepository
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[primitive.ObjectID]*UserState
+}
+
+func NewUserStateCache(userRepo *repository.UserRepository, ttl time.Duration) *UserStateCache {
+	return &UserStateCache{
+		userRepo: userRepo,
+		ttl:      ttl,
+		entries:  make(map[primitive.ObjectID]*UserState),
+	}
+}
+
+// Get returns the current state of the user, loading it when the cached copy
+// is missing or stale
+func (c *UserStateCache) Get(ctx context.Context, userID primitive.ObjectID) (*UserState, error) {
+	c.mu.Lock()
+	state, ok := c.entries[userID]
+	c.mu.Unlock()
+
+	if ok && time.Since(state.fetchedAt) < c.ttl {
+		return state, nil
+	}
+
+	user, err := c.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	state = &UserState{
+		Username:     user.Username,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+		BannedUntil:  user.BannedUntil,
+		fetchedAt:    time.Now(),
+	}
+
+	c.mu.Lock()
+	c.entries[userID] = state
+	c.evictExpiredLocked()
+	c.mu.Unlock()
+
+	return state, nil
+}
+
+// Invalidate drops the cached state of a user after it changed
+func (c *UserStateCache) Invalidate(userID primitive.ObjectID) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+// evictExpiredLocked keeps the map from growing without bound. It only runs
+// once the cache is large, so the sweep is amortised over many inserts.
+func (c *UserStateCache) evictExpiredLocked() {
+	if len(c.entries) < 10000 {
+		return
+	}
+	for id, state := range c.entries {
+		if time.Since(state.fetchedAt) >= c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
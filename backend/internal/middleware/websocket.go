@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/devthreads/backend/internal/auth"
+	"github.com/devthreads/backend/internal/repository"
+)
+
+// WebsocketInitFunc authenticates GraphQL subscriptions from the
+// "authorization" entry of the connection_init payload, since browsers cannot
+// set headers on WebSocket requests. Connections without one stay anonymous;
+// an invalid token rejects the connection.
+//
+// The credential is checked again every recheckInterval and the connection is
+// closed once it is revoked, its session is signed out, the user is banned or
+// an access token expires.
+// Clients reconnect with a refreshed token.
+func WebsocketInitFunc(authService *auth.Service, patRepo *repository.PersonalAccessTokenRepository, userState *UserStateCache, sessions *SessionStateCache, recheckInterval time.Duration) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		token := initPayloadToken(payload)
+		if token == "" {
+			return ctx, nil, nil
+		}
+
+		claims, err := authenticate(ctx, authService, patRepo, userState, sessions, token)
+		if err != nil {
+			return ctx, nil, errors.New("invalid or expired token")
+		}
+
+		ctx = context.WithValue(ctx, "user", claims)
+
+		// Personal access tokens have no expiry in their claims
+		var cancel context.CancelFunc
+		if claims.ExpiresAt != 0 {
+			ctx, cancel = context.WithDeadline(ctx, time.Unix(claims.ExpiresAt, 0))
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+
+		go func() {
+			defer cancel()
+
+			ticker := time.NewTicker(recheckInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if _, err := authenticate(ctx, authService, patRepo, userState, sessions, token); err != nil {
+						if ctx.Err() == nil {
+							log.Printf("Closing subscription connection of user %s: %v", claims.UserID, err)
+						}
+						return
+					}
+				}
+			}
+		}()
+
+		return ctx, nil, nil
+	}
+}
+
+// initPayloadToken reads a "Bearer <token>" authorization entry, which clients
+// send as either "authorization" or "Authorization"
+func initPayloadToken(payload transport.InitPayload) string {
+	for _, key := range []string{"authorization", "Authorization"} {
+		value, ok := payload[key].(string)
+		if !ok {
+			continue
+		}
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
@@ -8,56 +8,145 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username    string             `bson:"username" json:"username"`
-	DisplayName string             `bson:"display_name,omitempty" json:"displayName"`
-	Email       string             `bson:"email" json:"email"`
-	Password    string             `bson:"password,omitempty" json:"-"`
-	AvatarURL   string             `bson:"avatar_url,omitempty" json:"avatarUrl"`
-	Bio         string             `bson:"bio,omitempty" json:"bio"`
-	Reputation  int                `bson:"reputation" json:"reputation"`
-	IsAdmin     bool               `bson:"is_admin" json:"isAdmin"`
-	BannedUntil *time.Time         `bson:"banned_until,omitempty" json:"bannedUntil"`
-	GithubID    string             `bson:"github_id,omitempty" json:"-"`
-	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username      string             `bson:"username" json:"username"`
+	DisplayName   string             `bson:"display_name,omitempty" json:"displayName"`
+	Email         string             `bson:"email" json:"email"`
+	EmailVerified bool               `bson:"email_verified" json:"emailVerified"`
+	Password      string             `bson:"password,omitempty" json:"-"`
+	AvatarURL     string             `bson:"avatar_url,omitempty" json:"avatarUrl"`
+	Bio           string             `bson:"bio,omitempty" json:"bio"`
+	Reputation    int                `bson:"reputation" json:"reputation"`
+	Role          string             `bson:"role" json:"role"`
+	BannedUntil   *time.Time         `bson:"banned_until,omitempty" json:"bannedUntil"`
+	GithubID      string             `bson:"github_id,omitempty" json:"-"`
+	Privacy       PrivacySettings    `bson:"privacy" json:"privacy"`
+
+	// Kept in step with the follows collection so profiles don't count it
+	FollowersCount int `bson:"followers_count" json:"followersCount"`
+	FollowingCount int `bson:"following_count" json:"followingCount"`
+
+	// Users and tags whose posts are kept out of the FOR_YOU feed
+	MutedUsers []primitive.ObjectID `bson:"muted_users,omitempty" json:"mutedUsers"`
+	MutedTags  []string             `bson:"muted_tags,omitempty" json:"mutedTags"`
+
+	// DeletionScheduledAt is when the account will be purged. Until then the
+	// user can sign in and cancel the deletion.
+	DeletionScheduledAt *time.Time `bson:"deletion_scheduled_at,omitempty" json:"deletionScheduledAt"`
+
+	// TokenVersion is bumped on bans, role and password changes so that
+	// access tokens issued before the change stop being accepted
+	TokenVersion int `bson:"token_version" json:"-"`
+
+	// Two-factor authentication. TOTPPendingSecret holds an enrollment that
+	// has not been confirmed with a code yet.
+	TOTPEnabled       bool     `bson:"totp_enabled" json:"totpEnabled"`
+	TOTPSecret        string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep      int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes     []string `bson:"recovery_codes,omitempty" json:"-"`
+
+	CreatedAt time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updatedAt"`
 }
 
+// Profile visibilities
+const (
+	ProfileVisibilityPublic  = "PUBLIC"
+	ProfileVisibilityMembers = "MEMBERS"
+	ProfileVisibilityPrivate = "PRIVATE"
+)
+
+// PrivacySettings control what other users see of a profile. The zero value
+// hides the email and GitHub link and leaves the profile public.
+type PrivacySettings struct {
+	ShowEmail         bool   `bson:"show_email" json:"showEmail"`
+	ShowGithub        bool   `bson:"show_github" json:"showGithub"`
+	ProfileVisibility string `bson:"profile_visibility,omitempty" json:"profileVisibility"`
+}
+
+// Post and reel visibilities. PUBLIC content is listed everywhere, UNLISTED
+// content only opens by direct link, FOLLOWERS_ONLY content is shown to the
+// author's followers and PRIVATE content to the author alone.
+const (
+	VisibilityPublic        = "PUBLIC"
+	VisibilityUnlisted      = "UNLISTED"
+	VisibilityFollowersOnly = "FOLLOWERS_ONLY"
+	VisibilityPrivate       = "PRIVATE"
+)
+
 // Post represents a microblog post
 type Post struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	AuthorID     primitive.ObjectID `bson:"author_id" json:"authorId"`
-	Content      string             `bson:"content" json:"content"`
-	CodeSnippet  string             `bson:"code_snippet,omitempty" json:"codeSnippet"`
-	Language     string             `bson:"language,omitempty" json:"language"`
-	Tags         []string           `bson:"tags,omitempty" json:"tags"`
-	Visibility   string             `bson:"visibility" json:"visibility"`
-	LikesCount   int                `bson:"likes_count" json:"likesCount"`
-	CommentsCount int               `bson:"comments_count" json:"commentsCount"`
-	ViewsCount   int                `bson:"views_count" json:"viewsCount"`
-	UpvotesCount int                `bson:"upvotes_count" json:"upvotesCount"`
-	Deleted      bool               `bson:"deleted" json:"deleted"`
-	CreatedAt    time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AuthorID       primitive.ObjectID `bson:"author_id" json:"authorId"`
+	Content        string             `bson:"content" json:"content"`
+	CodeSnippet    string             `bson:"code_snippet,omitempty" json:"codeSnippet"`
+	Language       string             `bson:"language,omitempty" json:"language"`
+	Tags           []string           `bson:"tags,omitempty" json:"tags"`
+	Visibility     string             `bson:"visibility" json:"visibility"`
+	LikesCount     int                `bson:"likes_count" json:"likesCount"`
+	CommentsCount  int                `bson:"comments_count" json:"commentsCount"`
+	ViewsCount     int                `bson:"views_count" json:"viewsCount"`
+	UpvotesCount   int                `bson:"upvotes_count" json:"upvotesCount"`
+	DownvotesCount int                `bson:"downvotes_count" json:"downvotesCount"`
+	ReactionCounts map[string]int     `bson:"reaction_counts,omitempty" json:"reactionCounts"`
+	HotScore       float64            `bson:"hot_score" json:"-"`
+	Deleted        bool               `bson:"deleted" json:"deleted"`
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updatedAt"`
+
+	// Set when the author or a moderator deletes the post. The author can
+	// restore what they deleted themselves for a while.
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty" json:"deletedAt"`
+	DeletedBy *primitive.ObjectID `bson:"deleted_by,omitempty" json:"-"`
+
+	// Version counts the edits of the content, code snippet, language or
+	// tags. Earlier versions are kept as PostRevisions.
+	Version  int        `bson:"version" json:"version"`
+	Edited   bool       `bson:"edited" json:"edited"`
+	EditedAt *time.Time `bson:"edited_at,omitempty" json:"editedAt"`
+}
+
+// PostRevision is a post as it was before an edit. Version 0 is the post as
+// first published.
+type PostRevision struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID      primitive.ObjectID `bson:"post_id" json:"postId"`
+	AuthorID    primitive.ObjectID `bson:"author_id" json:"authorId"`
+	Version     int                `bson:"version" json:"version"`
+	Content     string             `bson:"content" json:"content"`
+	CodeSnippet string             `bson:"code_snippet,omitempty" json:"codeSnippet"`
+	Language    string             `bson:"language,omitempty" json:"language"`
+	Tags        []string           `bson:"tags,omitempty" json:"tags"`
+	// PublishedAt is when this version was published, CreatedAt when it was
+	// replaced
+	PublishedAt time.Time `bson:"published_at" json:"publishedAt"`
+	CreatedAt   time.Time `bson:"created_at" json:"createdAt"`
 }
 
 // Reel represents a short video post
 type Reel struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	AuthorID      primitive.ObjectID `bson:"author_id" json:"authorId"`
-	Title         string             `bson:"title,omitempty" json:"title"`
-	Description   string             `bson:"description,omitempty" json:"description"`
-	VideoURL      string             `bson:"video_url" json:"videoUrl"`
-	ThumbnailURL  string             `bson:"thumbnail_url,omitempty" json:"thumbnailUrl"`
-	Duration      int                `bson:"duration" json:"duration"`
-	Tags          []string           `bson:"tags,omitempty" json:"tags"`
-	Visibility    string             `bson:"visibility" json:"visibility"`
-	LikesCount    int                `bson:"likes_count" json:"likesCount"`
-	CommentsCount int                `bson:"comments_count" json:"commentsCount"`
-	ViewsCount    int                `bson:"views_count" json:"viewsCount"`
-	Deleted       bool               `bson:"deleted" json:"deleted"`
-	CreatedAt     time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt     time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AuthorID       primitive.ObjectID `bson:"author_id" json:"authorId"`
+	Title          string             `bson:"title,omitempty" json:"title"`
+	Description    string             `bson:"description,omitempty" json:"description"`
+	VideoURL       string             `bson:"video_url" json:"videoUrl"`
+	ThumbnailURL   string             `bson:"thumbnail_url,omitempty" json:"thumbnailUrl"`
+	Duration       int                `bson:"duration" json:"duration"`
+	Tags           []string           `bson:"tags,omitempty" json:"tags"`
+	Visibility     string             `bson:"visibility" json:"visibility"`
+	LikesCount     int                `bson:"likes_count" json:"likesCount"`
+	CommentsCount  int                `bson:"comments_count" json:"commentsCount"`
+	ViewsCount     int                `bson:"views_count" json:"viewsCount"`
+	ReactionCounts map[string]int     `bson:"reaction_counts,omitempty" json:"reactionCounts"`
+	HotScore       float64            `bson:"hot_score" json:"-"`
+	Deleted        bool               `bson:"deleted" json:"deleted"`
+	CreatedAt      time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updatedAt"`
+
+	// Set on deletion, as on Post
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty" json:"deletedAt"`
+	DeletedBy *primitive.ObjectID `bson:"deleted_by,omitempty" json:"-"`
 }
 
 // Comment represents a comment on a post or reel
@@ -69,8 +158,18 @@ type Comment struct {
 	ReelID          *primitive.ObjectID `bson:"reel_id,omitempty" json:"reelId"`
 	ParentCommentID *primitive.ObjectID `bson:"parent_comment_id,omitempty" json:"parentCommentId"`
 	LikesCount      int                 `bson:"likes_count" json:"likesCount"`
+	UpvotesCount    int                 `bson:"upvotes_count" json:"upvotesCount"`
+	DownvotesCount  int                 `bson:"downvotes_count" json:"downvotesCount"`
+	ReactionCounts  map[string]int      `bson:"reaction_counts,omitempty" json:"reactionCounts"`
 	Deleted         bool                `bson:"deleted" json:"deleted"`
 	CreatedAt       time.Time           `bson:"created_at" json:"createdAt"`
+
+	// Set on deletion, as on Post. DeletedWith is the post, reel or comment
+	// whose deletion took this comment with it; it comes back when that is
+	// restored.
+	DeletedAt   *time.Time          `bson:"deleted_at,omitempty" json:"deletedAt"`
+	DeletedBy   *primitive.ObjectID `bson:"deleted_by,omitempty" json:"-"`
+	DeletedWith *primitive.ObjectID `bson:"deleted_with,omitempty" json:"-"`
 }
 
 // Engagement represents user interactions (likes, views, upvotes)
@@ -79,20 +178,131 @@ type Engagement struct {
 	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
 	TargetType string             `bson:"target_type" json:"targetType"` // POST, REEL, COMMENT
 	TargetID   primitive.ObjectID `bson:"target_id" json:"targetId"`
-	Type       string             `bson:"type" json:"type"` // LIKE, VIEW, UPVOTE
+	Type       string             `bson:"type" json:"type"` // LIKE, VIEW, UPVOTE, DOWNVOTE, REACTION
+	Emoji      string             `bson:"emoji,omitempty" json:"emoji,omitempty"`
 	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+
+	// Set on UPVOTE and DOWNVOTE engagements, of which a user has at most
+	// one per target. Reputation is what the vote added to the reputation
+	// of TargetAuthorID, and is taken back when the vote is.
+	Vote           bool                `bson:"vote,omitempty" json:"-"`
+	TargetAuthorID *primitive.ObjectID `bson:"target_author_id,omitempty" json:"-"`
+	Reputation     int                 `bson:"reputation,omitempty" json:"-"`
 }
 
-// RefreshToken represents a refresh token
+// Engagement targets and types. A user has at most one engagement of each
+// type with a target, except for reactions, of which they have one per
+// emoji.
+const (
+	TargetPost    = "POST"
+	TargetReel    = "REEL"
+	TargetComment = "COMMENT"
+
+	EngagementLike     = "LIKE"
+	EngagementView     = "VIEW"
+	EngagementUpvote   = "UPVOTE"
+	EngagementDownvote = "DOWNVOTE"
+	EngagementReaction = "REACTION"
+)
+
+// Follow records that FollowerID follows FolloweeID
+type Follow struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FollowerID primitive.ObjectID `bson:"follower_id" json:"followerId"`
+	FolloweeID primitive.ObjectID `bson:"followee_id" json:"followeeId"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// TimelineEntry places a post in the FOLLOWING feed of one of its author's
+// followers. Entries are written when the post is created, so reading a feed
+// never has to look at who the reader follows.
+type TimelineEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"postId"`
+	AuthorID  primitive.ObjectID `bson:"author_id" json:"authorId"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// TrendingEntry is a post's place in one snapshot of the TRENDING ranking.
+// Every hot score refresh takes a new snapshot, and TRENDING pages stay in
+// the snapshot of their first page.
+type TrendingEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Snapshot      time.Time          `bson:"snapshot" json:"snapshot"`
+	PostID        primitive.ObjectID `bson:"post_id" json:"postId"`
+	HotScore      float64            `bson:"hot_score" json:"hotScore"`
+	PostCreatedAt time.Time          `bson:"post_created_at" json:"postCreatedAt"`
+}
+
+// Session is a signed-in device. Its ID doubles as the FamilyID of the
+// refresh tokens issued to that device.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	UserAgent  string             `bson:"user_agent,omitempty" json:"userAgent"`
+	IP         string             `bson:"ip,omitempty" json:"ip"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+	LastUsedAt time.Time          `bson:"last_used_at" json:"lastUsedAt"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expiresAt"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revokedAt"`
+}
+
+// RefreshToken represents a refresh token. Only the SHA-256 hash of the
+// token is stored; tokens issued by rotating an earlier one share its FamilyID.
 type RefreshToken struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"userId"`
+	TokenHash  string              `bson:"token_hash" json:"-"`
+	FamilyID   primitive.ObjectID  `bson:"family_id" json:"familyId"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replacedBy"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expiresAt"`
+	MFA        bool                `bson:"mfa" json:"mfa"`
+	Revoked    bool                `bson:"revoked" json:"revoked"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty" json:"revokedAt"`
+	CreatedAt  time.Time           `bson:"created_at" json:"createdAt"`
+}
+
+// Purposes of single-use account tokens
+const (
+	TokenPurposePasswordReset     = "PASSWORD_RESET"
+	TokenPurposeEmailVerification = "EMAIL_VERIFICATION"
+	TokenPurposeOAuthState        = "OAUTH_STATE"
+)
+
+// AccountToken is an expiring single-use token mailed to a user, such as a
+// password reset link, or the record of a redeemed OAuth state. Only the
+// SHA-256 hash of the token is stored.
+type AccountToken struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"userId"`
-	Token     string             `bson:"token" json:"token"`
+	Purpose   string             `bson:"purpose" json:"purpose"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	Email     string             `bson:"email" json:"email"`
 	ExpiresAt time.Time          `bson:"expires_at" json:"expiresAt"`
-	Revoked   bool               `bson:"revoked" json:"revoked"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"usedAt"`
 	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
 }
 
+// PersonalAccessToken is a long-lived credential for scripts and bots. The
+// token itself is only shown once; Prefix lets users recognize it later.
+type PersonalAccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"userId"`
+	Name       string             `bson:"name" json:"name"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expiresAt"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"lastUsedAt"`
+	Revoked    bool               `bson:"revoked" json:"revoked"`
+	CreatedAt  time.Time          `bson:"created_at" json:"createdAt"`
+
+	// CreatedWithMFA records whether the creating session passed two-factor
+	// authentication, which the token then counts as for admin checks
+	CreatedWithMFA bool `bson:"created_with_mfa" json:"-"`
+}
+
 // Badge represents a user achievement badge
 type Badge struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -114,6 +324,79 @@ type Notification struct {
 	CreatedAt time.Time           `bson:"created_at" json:"createdAt"`
 }
 
+// Notification types
+const (
+	NotificationFollow = "FOLLOW"
+
+	// Sent by the system rather than by another user
+	NotificationDataExportReady  = "DATA_EXPORT_READY"
+	NotificationDataExportFailed = "DATA_EXPORT_FAILED"
+)
+
+// Statuses of a DataExport
+const (
+	DataExportPending    = "PENDING"
+	DataExportProcessing = "PROCESSING"
+	DataExportReady      = "READY"
+	DataExportFailed     = "FAILED"
+)
+
+// DataExport is a requested archive of a user's data. Exports are built in
+// the background; once ready the archive can be downloaded with a token whose
+// SHA-256 hash is stored here.
+type DataExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"userId"`
+	Status      string             `bson:"status" json:"status"`
+	FilePath    string             `bson:"file_path,omitempty" json:"-"`
+	TokenHash   string             `bson:"token_hash,omitempty" json:"-"`
+	Error       string             `bson:"error,omitempty" json:"error"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"startedAt"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completedAt"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expiresAt"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// Types of SecurityEvent
+const (
+	SecurityEventLoginSucceeded = "LOGIN_SUCCEEDED"
+	SecurityEventLoginFailed    = "LOGIN_FAILED"
+	SecurityEventLoginBlocked   = "LOGIN_BLOCKED"
+	SecurityEventLockout        = "LOCKOUT"
+)
+
+// SecurityEvent records an authentication attempt. Identifier is what the
+// client tried to sign in as, which may not match any user.
+type SecurityEvent struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Type       string              `bson:"type" json:"type"`
+	UserID     *primitive.ObjectID `bson:"user_id,omitempty" json:"userId"`
+	Identifier string              `bson:"identifier,omitempty" json:"identifier"`
+	IP         string              `bson:"ip,omitempty" json:"ip"`
+	UserAgent  string              `bson:"user_agent,omitempty" json:"userAgent"`
+	Detail     string              `bson:"detail,omitempty" json:"detail"`
+	CreatedAt  time.Time           `bson:"created_at" json:"createdAt"`
+}
+
+// Role is a custom role defined by an admin. Built-in roles are not stored.
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Permissions []string           `bson:"permissions" json:"permissions"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updatedAt"`
+}
+
+// Moderation actions recorded in ModerationLog.Action
+const (
+	ModerationBanUser       = "BAN_USER"
+	ModerationUnbanUser     = "UNBAN_USER"
+	ModerationChangeRole    = "CHANGE_ROLE"
+	ModerationDeleteComment = "DELETE_COMMENT"
+	// Recorded by counter reconciliation, without an admin
+	ModerationReconcileCounters = "RECONCILE_COUNTERS"
+)
+
 // ModerationLog represents admin moderation actions
 type ModerationLog struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -0,0 +1,209 @@
+// Package pagination pages through MongoDB queries with keyset cursors.
+// Documents are sorted on a list of keys with _id as the tie-breaker, and the
+// next page starts after the sort values of the last document seen. Unlike
+// skip, pages stay stable as documents are added and reading a page costs
+// the same however deep it is.
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Key is a field documents are sorted on. Nested fields use dots.
+type Key struct {
+	Field      string
+	Descending bool
+}
+
+// Edge is a document and the cursor pointing at it
+type Edge[T any] struct {
+	Node   *T
+	Cursor string
+}
+
+// Page is the result of Find
+type Page[T any] struct {
+	Edges       []Edge[T]
+	HasNextPage bool
+}
+
+// Nodes returns the documents of the page
+func (p *Page[T]) Nodes() []*T {
+	nodes := make([]*T, len(p.Edges))
+	for i, edge := range p.Edges {
+		nodes[i] = edge.Node
+	}
+	return nodes
+}
+
+// EndCursor returns the cursor of the last document, or nil for an empty page
+func (p *Page[T]) EndCursor() *string {
+	if len(p.Edges) == 0 {
+		return nil
+	}
+	return &p.Edges[len(p.Edges)-1].Cursor
+}
+
+// cursor is the decoded form of a cursor. The field names are kept so that a
+// cursor from one ordering is rejected by another.
+type cursor struct {
+	Fields []string        `bson:"f"`
+	Values []bson.RawValue `bson:"v"`
+}
+
+// Find returns up to limit documents matching filter in the order of keys,
+// starting after the document that after points at. An empty after starts
+// from the beginning. The collection needs an index on the filtered fields
+// followed by keys and _id for this to stay cheap.
+func Find[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, keys []Key, after string, limit int) (*Page[T], error) {
+	keys = withTieBreaker(keys)
+
+	if after != "" {
+		values, err := decode(after, keys)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.M{"$and": bson.A{filter, afterFilter(keys, values)}}
+	}
+
+	sort := make(bson.D, len(keys))
+	for i, key := range keys {
+		direction := 1
+		if key.Descending {
+			direction = -1
+		}
+		sort[i] = bson.E{Key: key.Field, Value: direction}
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit + 1)).
+		SetSort(sort)
+
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	page := &Page[T]{Edges: []Edge[T]{}}
+	for cur.Next(ctx) {
+		if len(page.Edges) == limit {
+			page.HasNextPage = true
+			break
+		}
+
+		var node T
+		if err := bson.Unmarshal(cur.Current, &node); err != nil {
+			return nil, err
+		}
+		next, err := encode(cur.Current, keys)
+		if err != nil {
+			return nil, err
+		}
+		page.Edges = append(page.Edges, Edge[T]{Node: &node, Cursor: next})
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// withTieBreaker appends _id to keys, in the direction of the last key, so
+// that every document has a distinct position
+func withTieBreaker(keys []Key) []Key {
+	if len(keys) > 0 && keys[len(keys)-1].Field == "_id" {
+		return keys
+	}
+	descending := len(keys) == 0 || keys[len(keys)-1].Descending
+	return append(append([]Key{}, keys...), Key{Field: "_id", Descending: descending})
+}
+
+// afterFilter matches the documents that sort after values. For keys a, b
+// and _id that is a past A, or a equal to A and b past B, and so on.
+func afterFilter(keys []Key, values []bson.RawValue) bson.M {
+	or := make(bson.A, len(keys))
+	for i, key := range keys {
+		condition := bson.M{}
+		for j := 0; j < i; j++ {
+			condition[keys[j].Field] = values[j]
+		}
+		op := "$gt"
+		if key.Descending {
+			op = "$lt"
+		}
+		condition[key.Field] = bson.M{op: values[i]}
+		or[i] = condition
+	}
+	return bson.M{"$or": or}
+}
+
+func encode(doc bson.Raw, keys []Key) (string, error) {
+	c := cursor{
+		Fields: make([]string, len(keys)),
+		Values: make([]bson.RawValue, len(keys)),
+	}
+	for i, key := range keys {
+		c.Fields[i] = key.Field
+		value, err := doc.LookupErr(strings.Split(key.Field, ".")...)
+		if err != nil {
+			// Missing fields sort like null
+			value = bson.RawValue{Type: bsontype.Null}
+		}
+		c.Values[i] = value
+	}
+
+	data, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decode returns the sort values of a cursor made for keys. Only scalar
+// values are accepted, since the values are spliced into the query filter.
+func decode(s string, keys []Key) ([]bson.RawValue, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := bson.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if len(c.Fields) != len(keys) || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, key := range keys {
+		if c.Fields[i] != key.Field || !scalar(c.Values[i].Type) {
+			return nil, ErrInvalidCursor
+		}
+	}
+	if _, ok := c.Values[len(keys)-1].ObjectIDOK(); !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	return c.Values, nil
+}
+
+func scalar(t bsontype.Type) bool {
+	switch t {
+	case bsontype.Double, bsontype.String, bsontype.Int32, bsontype.Int64,
+		bsontype.Decimal128, bsontype.Boolean, bsontype.DateTime,
+		bsontype.Timestamp, bsontype.ObjectID, bsontype.Null:
+		return true
+	}
+	return false
+}
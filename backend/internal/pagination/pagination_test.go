@@ -0,0 +1,204 @@
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+var hotKeys = []Key{{Field: "hot_score", Descending: true}}
+
+func TestWithTieBreaker(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []Key
+		want []Key
+	}{
+		{"no keys sorts newest _id first", nil, []Key{{Field: "_id", Descending: true}}},
+		{"follows the last key's direction", []Key{{Field: "a", Descending: true}, {Field: "b"}}, []Key{{Field: "a", Descending: true}, {Field: "b"}, {Field: "_id"}}},
+		{"_id already last", []Key{{Field: "_id"}}, []Key{{Field: "_id"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withTieBreaker(tt.keys); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("withTieBreaker() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	doc, _ := bson.Marshal(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "stats", Value: bson.D{{Key: "score", Value: 4.5}}},
+		{Key: "created_at", Value: created},
+	})
+	keys := withTieBreaker([]Key{{Field: "stats.score", Descending: true}, {Field: "missing"}, {Field: "created_at"}})
+
+	s, err := encode(doc, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := decode(s, keys)
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+
+	if score, _ := values[0].DoubleOK(); score != 4.5 {
+		t.Errorf("stats.score = %v, want 4.5", values[0])
+	}
+	if values[1].Type != bsontype.Null {
+		t.Errorf("missing field = %v, want null", values[1])
+	}
+	if at, _ := values[2].TimeOK(); !at.Equal(created) {
+		t.Errorf("created_at = %v, want %v", values[2], created)
+	}
+	if got, _ := values[3].ObjectIDOK(); got != id {
+		t.Errorf("_id = %v, want %s", values[3], id.Hex())
+	}
+}
+
+// rawCursor encodes a cursor without going through encode, as a client could
+func rawCursor(t *testing.T, fields []string, values ...interface{}) string {
+	t.Helper()
+	c := cursor{Fields: fields}
+	for _, v := range values {
+		bt, data, err := bson.MarshalValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Values = append(c.Values, bson.RawValue{Type: bt, Value: data})
+	}
+	data, err := bson.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestDecodeRejects(t *testing.T) {
+	keys := withTieBreaker(hotKeys)
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"not BSON", base64.RawURLEncoding.EncodeToString([]byte("hello"))},
+		{"another ordering", rawCursor(t, []string{"created_at", "_id"}, time.Now(), id)},
+		{"too few values", rawCursor(t, []string{"hot_score", "_id"}, 1.5)},
+		{"operator smuggled in a value", rawCursor(t, []string{"hot_score", "_id"}, bson.M{"$gt": -1}, id)},
+		{"array value", rawCursor(t, []string{"hot_score", "_id"}, bson.A{1}, id)},
+		{"tie-breaker is not an ObjectID", rawCursor(t, []string{"hot_score", "_id"}, 1.5, "abc")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decode(tt.cursor, keys); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("decode() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestAfterFilter(t *testing.T) {
+	keys := withTieBreaker([]Key{{Field: "pinned", Descending: true}, {Field: "created_at"}})
+	values := []bson.RawValue{{Type: bsontype.Boolean}, {Type: bsontype.Null}, {Type: bsontype.ObjectID}}
+
+	want := bson.M{"$or": bson.A{
+		bson.M{"pinned": bson.M{"$lt": values[0]}},
+		bson.M{"pinned": values[0], "created_at": bson.M{"$gt": values[1]}},
+		bson.M{"pinned": values[0], "created_at": values[1], "_id": bson.M{"$gt": values[2]}},
+	}}
+	if got := afterFilter(keys, values); !reflect.DeepEqual(got, want) {
+		t.Fatalf("afterFilter() = %v, want %v", got, want)
+	}
+}
+
+type item struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	HotScore float64            `bson:"hot_score"`
+}
+
+func TestFind(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	// Two items share a score, so only _id tells them apart
+	items := []item{
+		{ID: primitive.NewObjectID(), HotScore: 9},
+		{ID: primitive.NewObjectID(), HotScore: 5},
+		{ID: primitive.NewObjectID(), HotScore: 5},
+	}
+	docs := func(items ...item) []bson.D {
+		result := make([]bson.D, len(items))
+		for i, it := range items {
+			result[i] = bson.D{{Key: "_id", Value: it.ID}, {Key: "hot_score", Value: it.HotScore}}
+		}
+		return result
+	}
+
+	mt.Run("pages with a tie-breaking keyset cursor", func(mt *mtest.T) {
+		ns := mt.DB.Name() + ".items"
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs(items...)...))
+
+		first, err := Find[item](context.Background(), mt.Coll, bson.M{}, hotKeys, "", 2)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(first.Edges) != 2 || !first.HasNextPage || first.Edges[1].Node.ID != items[1].ID {
+			mt.Fatalf("first page = %d edges, hasNextPage %v", len(first.Edges), first.HasNextPage)
+		}
+
+		cmd := mt.GetStartedEvent().Command
+		if limit, _ := cmd.Lookup("limit").AsInt64OK(); limit != 3 {
+			mt.Errorf("limit = %s, want one more than the page", cmd.Lookup("limit"))
+		}
+		wantSort := bson.D{{Key: "hot_score", Value: int32(-1)}, {Key: "_id", Value: int32(-1)}}
+		var gotSort bson.D
+		cmd.Lookup("sort").Unmarshal(&gotSort)
+		if !reflect.DeepEqual(gotSort, wantSort) {
+			mt.Errorf("sort = %v, want %v", gotSort, wantSort)
+		}
+
+		// The next page starts strictly after the last item, with _id breaking
+		// the tie on its score
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs(items[2])...))
+		second, err := Find[item](context.Background(), mt.Coll, bson.M{"visible": true}, hotKeys, *first.EndCursor(), 2)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(second.Edges) != 1 || second.HasNextPage {
+			mt.Fatalf("second page = %d edges, hasNextPage %v", len(second.Edges), second.HasNextPage)
+		}
+
+		and := mt.GetStartedEvent().Command.Lookup("filter", "$and").Array()
+		if visible, _ := and.Index(0).Value().Document().Lookup("visible").BooleanOK(); !visible {
+			mt.Errorf("filter lost the caller's conditions: %s", and)
+		}
+		or := and.Index(1).Value().Document().Lookup("$or").Array()
+		tie := or.Index(1).Value().Document()
+		if score, _ := tie.Lookup("hot_score").DoubleOK(); score != 5 {
+			mt.Errorf("tie condition = %s, want hot_score 5", tie)
+		}
+		if id, _ := tie.Lookup("_id", "$lt").ObjectIDOK(); id != items[1].ID {
+			mt.Errorf("tie condition = %s, want _id $lt %s", tie, items[1].ID.Hex())
+		}
+	})
+
+	mt.Run("rejects a cursor of another ordering", func(mt *mtest.T) {
+		after := rawCursor(t, []string{"created_at", "_id"}, time.Now(), primitive.NewObjectID())
+		if _, err := Find[item](context.Background(), mt.Coll, bson.M{}, hotKeys, after, 2); !errors.Is(err, ErrInvalidCursor) {
+			mt.Fatalf("Find() error = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
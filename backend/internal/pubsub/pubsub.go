@@ -0,0 +1,63 @@
+// Package pubsub delivers events to GraphQL subscriptions. Delivery is in
+// memory, so subscribers only receive events published on their own instance.
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// subscriberBuffer is how many undelivered messages a subscriber can fall
+// behind before further messages to it are dropped
+const subscriberBuffer = 16
+
+// Broker fans messages out to the subscribers of a topic
+type Broker struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan interface{}]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{topics: make(map[string]map[chan interface{}]struct{})}
+}
+
+// Subscribe returns a channel receiving messages published to topic. The
+// channel is closed once ctx is done.
+func (b *Broker) Subscribe(ctx context.Context, topic string) <-chan interface{} {
+	ch := make(chan interface{}, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[chan interface{}]struct{})
+	}
+	b.topics[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.topics[topic], ch)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+		b.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Publish sends msg to the current subscribers of topic without blocking.
+// Subscribers whose buffer is full miss the message.
+func (b *Broker) Publish(topic string, msg interface{}) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.topics[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package ranking scores content for the TRENDING feed. A hot score is the
+// weighted engagement of a post divided by a power of its age, in the style
+// of Hacker News: engagement lifts a post, and every hour it gets older pulls
+// it back down, so old posts make way for new ones however popular they were.
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// ageOffset is added to the age in hours so brand new posts are not divided
+// by zero and their first votes don't send them off the scale
+const ageOffset = 2
+
+// Weights sets how much each kind of engagement is worth and how fast scores
+// decay with age
+type Weights struct {
+	Like    float64
+	Upvote  float64
+	Comment float64
+	View    float64
+	// Gravity is the power the age in hours is raised to. Higher values make
+	// posts fall off sooner.
+	Gravity float64
+}
+
+// Engagement is what a post or reel has received so far
+type Engagement struct {
+	Likes    int
+	Upvotes  int
+	Comments int
+	Views    int
+}
+
+// HotScore scores engagement received over age. Scores are always positive,
+// so content without any engagement still ranks newest first.
+func (w Weights) HotScore(e Engagement, age time.Duration) float64 {
+	points := 1 +
+		w.Like*float64(e.Likes) +
+		w.Upvote*float64(e.Upvotes) +
+		w.Comment*float64(e.Comments) +
+		w.View*float64(e.Views)
+	if points < 1 {
+		points = 1
+	}
+
+	hours := math.Max(age.Hours(), 0)
+	return points / math.Pow(hours+ageOffset, w.Gravity)
+}
@@ -0,0 +1,202 @@
+// Package rbac maps roles to the permissions they grant. The user, moderator
+// and admin roles are built in; admins can define custom roles, which are
+// stored in the database.
+package rbac
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+)
+
+// Permission is a privileged action. Values match the GraphQL Permission enum.
+type Permission string
+
+const (
+	PermissionViewAdminDashboard Permission = "VIEW_ADMIN_DASHBOARD"
+	PermissionViewModerationLogs Permission = "VIEW_MODERATION_LOGS"
+	PermissionModeratePosts      Permission = "MODERATE_POSTS"
+	PermissionModerateReels      Permission = "MODERATE_REELS"
+	PermissionModerateComments   Permission = "MODERATE_COMMENTS"
+	PermissionBanUsers           Permission = "BAN_USERS"
+	PermissionManageReputation   Permission = "MANAGE_REPUTATION"
+	PermissionManageRoles        Permission = "MANAGE_ROLES"
+)
+
+// AllPermissions lists every permission
+var AllPermissions = []Permission{
+	PermissionViewAdminDashboard,
+	PermissionViewModerationLogs,
+	PermissionModeratePosts,
+	PermissionModerateReels,
+	PermissionModerateComments,
+	PermissionBanUsers,
+	PermissionManageReputation,
+	PermissionManageRoles,
+}
+
+// ValidPermission reports whether p is a known permission
+func ValidPermission(p Permission) bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var builtinRoles = map[string][]Permission{
+	RoleUser: nil,
+	RoleModerator: {
+		PermissionViewModerationLogs,
+		PermissionModeratePosts,
+		PermissionModerateReels,
+		PermissionModerateComments,
+	},
+	RoleAdmin: AllPermissions,
+}
+
+// BuiltinRoleNames lists the built-in roles from least to most privileged
+var BuiltinRoleNames = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// IsBuiltin reports whether role is built in and so cannot be changed
+func IsBuiltin(role string) bool {
+	_, ok := builtinRoles[role]
+	return ok
+}
+
+// BuiltinPermissions returns the permissions of a built-in role
+func BuiltinPermissions(role string) []Permission {
+	return builtinRoles[role]
+}
+
+// RoleSource loads the custom roles
+type RoleSource interface {
+	FindAll(ctx context.Context) ([]*models.Role, error)
+}
+
+// Registry resolves role names to permissions. Custom roles are cached for
+// ttl; Invalidate reloads them after a change on this instance.
+type Registry struct {
+	source RoleSource
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	custom   map[string][]Permission
+	loadedAt time.Time
+}
+
+func NewRegistry(source RoleSource, ttl time.Duration) *Registry {
+	return &Registry{source: source, ttl: ttl}
+}
+
+// Permissions returns what role grants. Unknown roles, such as a custom role
+// that has been deleted, grant nothing.
+func (r *Registry) Permissions(ctx context.Context, role string) ([]Permission, error) {
+	if permissions, ok := builtinRoles[role]; ok {
+		return permissions, nil
+	}
+
+	custom, err := r.customRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return custom[role], nil
+}
+
+// HasPermission reports whether role grants permission
+func (r *Registry) HasPermission(ctx context.Context, role string, permission Permission) (bool, error) {
+	permissions, err := r.Permissions(ctx, role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Missing returns the permissions in want that role does not grant
+func (r *Registry) Missing(ctx context.Context, role string, want []Permission) ([]Permission, error) {
+	permissions, err := r.Permissions(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[Permission]bool, len(permissions))
+	for _, p := range permissions {
+		held[p] = true
+	}
+	var missing []Permission
+	for _, p := range want {
+		if !held[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// IsStaff reports whether role grants any permission at all
+func (r *Registry) IsStaff(ctx context.Context, role string) (bool, error) {
+	permissions, err := r.Permissions(ctx, role)
+	return len(permissions) > 0, err
+}
+
+// Exists reports whether role is built in or a defined custom role
+func (r *Registry) Exists(ctx context.Context, role string) (bool, error) {
+	if IsBuiltin(role) {
+		return true, nil
+	}
+
+	custom, err := r.customRoles(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := custom[role]
+	return ok, nil
+}
+
+// Invalidate forces custom roles to be reloaded on next use
+func (r *Registry) Invalidate() {
+	r.mu.Lock()
+	r.custom = nil
+	r.mu.Unlock()
+}
+
+func (r *Registry) customRoles(ctx context.Context) (map[string][]Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.custom != nil && time.Since(r.loadedAt) < r.ttl {
+		return r.custom, nil
+	}
+
+	roles, err := r.source.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	custom := make(map[string][]Permission, len(roles))
+	for _, role := range roles {
+		permissions := make([]Permission, 0, len(role.Permissions))
+		for _, p := range role.Permissions {
+			permissions = append(permissions, Permission(p))
+		}
+		custom[role.Name] = permissions
+	}
+
+	r.custom = custom
+	r.loadedAt = time.Now()
+	return custom, nil
+}
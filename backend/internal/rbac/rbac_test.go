@@ -0,0 +1,46 @@
+package rbac
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+)
+
+type staticRoles []*models.Role
+
+func (s staticRoles) FindAll(ctx context.Context) ([]*models.Role, error) {
+	return s, nil
+}
+
+func TestRegistryMissing(t *testing.T) {
+	registry := NewRegistry(staticRoles{
+		{Name: "role-admin", Permissions: []string{string(PermissionManageRoles)}},
+	}, time.Minute)
+
+	tests := []struct {
+		name string
+		role string
+		want []Permission
+		miss []Permission
+	}{
+		{"admin holds everything", RoleAdmin, AllPermissions, nil},
+		{"moderator lacks bans", RoleModerator, []Permission{PermissionModeratePosts, PermissionBanUsers}, []Permission{PermissionBanUsers}},
+		{"custom role", "role-admin", []Permission{PermissionManageRoles, PermissionBanUsers}, []Permission{PermissionBanUsers}},
+		{"unknown role holds nothing", "deleted", []Permission{PermissionModeratePosts}, []Permission{PermissionModeratePosts}},
+		{"nothing wanted", RoleUser, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing, err := registry.Missing(context.Background(), tt.role, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(missing, tt.miss) {
+				t.Fatalf("Missing() = %v, want %v", missing, tt.miss)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Package reconcile checks the denormalized counters on posts, reels and
+// comments against the engagements and comments they count. Likes, votes,
+// reactions, views and comments move their counters one at a time, so a
+// failed or interrupted update leaves a counter out of step until it is
+// reconciled.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxReported bounds how many discrepancies a report lists and records in the
+// moderation log; the rest are only counted
+const MaxReported = 100
+
+// Discrepancy is a counter found out of step with what it counts
+type Discrepancy struct {
+	Target string
+	ID     primitive.ObjectID
+	Field  string
+	Stored int
+	Actual int
+}
+
+// Report is the outcome of a run
+type Report struct {
+	ID primitive.ObjectID
+	// Checked is how many documents of each target were checked
+	Checked map[string]int
+	// Found is how many counters were out of step, and Fixed how many of
+	// them were corrected
+	Found int
+	Fixed int64
+	// Discrepancies lists the first MaxReported counters out of step
+	Discrepancies []Discrepancy
+}
+
+// Summary describes the report in one line
+func (r *Report) Summary() string {
+	var checked []string
+	for _, target := range targetNames {
+		checked = append(checked, fmt.Sprintf("%d %ss", r.Checked[target], target))
+	}
+	return fmt.Sprintf("checked %s: %d counters out of step, %d fixed", strings.Join(checked, ", "), r.Found, r.Fixed)
+}
+
+// counted is a document with its stored counters
+type counted struct {
+	ID       primitive.ObjectID
+	Counters map[string]int
+}
+
+// target is a kind of document whose counters are reconciled
+type target struct {
+	name string
+	// page returns up to limit documents after the given ID in ID order
+	page func(ctx context.Context, after primitive.ObjectID, limit int) ([]counted, error)
+	// count recomputes the counters of the documents from their sources
+	count func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error)
+	fix   func(ctx context.Context, fixes []repository.CounterFix) (int64, error)
+}
+
+var targetNames = []string{"post", "reel", "comment"}
+
+// Reconciler checks counters in batches and records what it finds
+type Reconciler struct {
+	targets []target
+	logs    *repository.ModerationLogRepository
+	batch   int
+}
+
+// New returns a Reconciler checking batch documents at a time
+func New(posts *repository.PostRepository, reels *repository.ReelRepository, comments *repository.CommentRepository, engagements *repository.EngagementRepository, logs *repository.ModerationLogRepository, batch int) *Reconciler {
+	if batch <= 0 {
+		batch = 500
+	}
+
+	return &Reconciler{
+		logs:  logs,
+		batch: batch,
+		targets: []target{
+			{
+				name: "post",
+				page: func(ctx context.Context, after primitive.ObjectID, limit int) ([]counted, error) {
+					found, err := posts.FindCounters(ctx, after, limit)
+					docs := make([]counted, len(found))
+					for i, post := range found {
+						docs[i] = counted{ID: post.ID, Counters: withReactions(map[string]int{
+							"likes_count":     post.LikesCount,
+							"upvotes_count":   post.UpvotesCount,
+							"downvotes_count": post.DownvotesCount,
+							"views_count":     post.ViewsCount,
+							"comments_count":  post.CommentsCount,
+						}, post.ReactionCounts)}
+					}
+					return docs, err
+				},
+				count: func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error) {
+					return countEngagements(ctx, engagements, models.TargetPost, ids, map[string]string{
+						models.EngagementLike:     "likes_count",
+						models.EngagementUpvote:   "upvotes_count",
+						models.EngagementDownvote: "downvotes_count",
+						models.EngagementView:     "views_count",
+					}, comments.CountByPosts)
+				},
+				fix: posts.FixCounts,
+			},
+			{
+				name: "reel",
+				page: func(ctx context.Context, after primitive.ObjectID, limit int) ([]counted, error) {
+					found, err := reels.FindCounters(ctx, after, limit)
+					docs := make([]counted, len(found))
+					for i, reel := range found {
+						docs[i] = counted{ID: reel.ID, Counters: withReactions(map[string]int{
+							"likes_count":    reel.LikesCount,
+							"comments_count": reel.CommentsCount,
+						}, reel.ReactionCounts)}
+					}
+					return docs, err
+				},
+				count: func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error) {
+					return countEngagements(ctx, engagements, models.TargetReel, ids, map[string]string{
+						models.EngagementLike: "likes_count",
+					}, comments.CountByReels)
+				},
+				fix: reels.FixCounts,
+			},
+			{
+				name: "comment",
+				page: func(ctx context.Context, after primitive.ObjectID, limit int) ([]counted, error) {
+					found, err := comments.FindCounters(ctx, after, limit)
+					docs := make([]counted, len(found))
+					for i, comment := range found {
+						docs[i] = counted{ID: comment.ID, Counters: withReactions(map[string]int{
+							"likes_count":     comment.LikesCount,
+							"upvotes_count":   comment.UpvotesCount,
+							"downvotes_count": comment.DownvotesCount,
+						}, comment.ReactionCounts)}
+					}
+					return docs, err
+				},
+				count: func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error) {
+					return countEngagements(ctx, engagements, models.TargetComment, ids, map[string]string{
+						models.EngagementLike:     "likes_count",
+						models.EngagementUpvote:   "upvotes_count",
+						models.EngagementDownvote: "downvotes_count",
+					}, nil)
+				},
+				fix: comments.FixCounts,
+			},
+		},
+	}
+}
+
+// withReactions adds the reaction counts to the counters, as the fields they
+// are stored in
+func withReactions(counters map[string]int, reactions map[string]int) map[string]int {
+	for emoji, n := range reactions {
+		counters[reactionField(emoji)] = n
+	}
+	return counters
+}
+
+func reactionField(emoji string) string {
+	return "reaction_counts." + emoji
+}
+
+// countEngagements recomputes counters from the engagements with the targets,
+// mapping engagement types to counter fields, from their reactions, and from
+// their comments when countComments is set. Counters with nothing to count
+// come out as 0, except for reactions, which are only there if counted.
+func countEngagements(
+	ctx context.Context,
+	engagements *repository.EngagementRepository,
+	targetType string,
+	ids []primitive.ObjectID,
+	fields map[string]string,
+	countComments func(context.Context, []primitive.ObjectID) (map[primitive.ObjectID]int, error),
+) (map[primitive.ObjectID]map[string]int, error) {
+	byType, err := engagements.CountByTargets(ctx, targetType, ids)
+	if err != nil {
+		return nil, err
+	}
+	byEmoji, err := engagements.CountReactionsByTargets(ctx, targetType, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var commentCounts map[primitive.ObjectID]int
+	if countComments != nil {
+		if commentCounts, err = countComments(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	counts := make(map[primitive.ObjectID]map[string]int, len(ids))
+	for _, id := range ids {
+		c := map[string]int{}
+		for engagementType, field := range fields {
+			c[field] = byType[id][engagementType]
+		}
+		if countComments != nil {
+			c["comments_count"] = commentCounts[id]
+		}
+		counts[id] = withReactions(c, byEmoji[id])
+	}
+	return counts, nil
+}
+
+// Run checks every counter, corrects the ones out of step if fix is set, and
+// records the discrepancies and a summary in the moderation log. Documents
+// created meanwhile may or may not be checked.
+func (r *Reconciler) Run(ctx context.Context, fix bool) (*Report, error) {
+	report := &Report{ID: primitive.NewObjectID(), Checked: map[string]int{}}
+
+	for _, t := range r.targets {
+		if err := r.reconcile(ctx, t, fix, report); err != nil {
+			return report, fmt.Errorf("%ss: %w", t.name, err)
+		}
+		log.Printf("Reconciled %d %ss", report.Checked[t.name], t.name)
+	}
+
+	r.record(ctx, report, fix)
+	return report, nil
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, t target, fix bool, report *Report) error {
+	var after primitive.ObjectID
+	for {
+		docs, err := t.page(ctx, after, r.batch)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+		after = docs[len(docs)-1].ID
+
+		ids := make([]primitive.ObjectID, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc.ID
+		}
+		actual, err := t.count(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		var fixes []repository.CounterFix
+		for _, doc := range docs {
+			// Reactions counted but missing from the document are 0
+			for field := range actual[doc.ID] {
+				if _, ok := doc.Counters[field]; !ok {
+					doc.Counters[field] = 0
+				}
+			}
+
+			for field, stored := range doc.Counters {
+				want := actual[doc.ID][field]
+				if stored == want {
+					continue
+				}
+
+				report.Found++
+				if len(report.Discrepancies) < MaxReported {
+					report.Discrepancies = append(report.Discrepancies, Discrepancy{
+						Target: t.name,
+						ID:     doc.ID,
+						Field:  field,
+						Stored: stored,
+						Actual: want,
+					})
+				}
+				fixes = append(fixes, repository.CounterFix{ID: doc.ID, Field: field, Was: stored, Count: want})
+			}
+		}
+
+		if fix && len(fixes) > 0 {
+			fixed, err := t.fix(ctx, fixes)
+			if err != nil {
+				return err
+			}
+			report.Fixed += fixed
+		}
+
+		report.Checked[t.name] += len(docs)
+		if len(docs) < r.batch {
+			return nil
+		}
+	}
+}
+
+// record writes the reported discrepancies and a summary to the moderation
+// log. Entries have no admin since no one took the action. A failure is
+// logged rather than returned because the counters are already fixed.
+func (r *Reconciler) record(ctx context.Context, report *Report, fix bool) {
+	outcome := "not fixed"
+	if fix {
+		outcome = "fixed"
+	}
+
+	entries := make([]*models.ModerationLog, 0, len(report.Discrepancies)+1)
+	for _, d := range report.Discrepancies {
+		entries = append(entries, &models.ModerationLog{
+			Action:     models.ModerationReconcileCounters,
+			TargetType: d.Target,
+			TargetID:   d.ID,
+			Reason:     fmt.Sprintf("%s was %d, counted %d; %s", d.Field, d.Stored, d.Actual, outcome),
+		})
+	}
+	entries = append(entries, &models.ModerationLog{
+		Action:     models.ModerationReconcileCounters,
+		TargetType: "reconciliation",
+		TargetID:   report.ID,
+		Reason:     report.Summary(),
+	})
+
+	for _, entry := range entries {
+		if err := r.logs.Create(ctx, entry); err != nil {
+			log.Printf("Failed to record counter reconciliation %s: %v", report.ID.Hex(), err)
+			return
+		}
+	}
+	log.Printf("Counter reconciliation %s %s", report.ID.Hex(), report.Summary())
+}
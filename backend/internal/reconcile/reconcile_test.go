@@ -0,0 +1,212 @@
+package reconcile
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/devthreads/backend/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// store is an in-memory target: the counters stored on each document and
+// what their sources actually count
+type store struct {
+	ids    []primitive.ObjectID
+	stored map[primitive.ObjectID]map[string]int
+	actual map[primitive.ObjectID]map[string]int
+	pages  int
+}
+
+func newStore(docs ...[2]map[string]int) *store {
+	s := &store{stored: map[primitive.ObjectID]map[string]int{}, actual: map[primitive.ObjectID]map[string]int{}}
+	for _, doc := range docs {
+		id := primitive.NewObjectID()
+		s.ids = append(s.ids, id)
+		s.stored[id] = doc[0]
+		s.actual[id] = doc[1]
+	}
+	return s
+}
+
+func (s *store) target() target {
+	return target{
+		name: "post",
+		page: func(ctx context.Context, after primitive.ObjectID, limit int) ([]counted, error) {
+			s.pages++
+			var docs []counted
+			for _, id := range s.ids {
+				if id.Hex() <= after.Hex() || len(docs) == limit {
+					continue
+				}
+				counters := map[string]int{}
+				for field, n := range s.stored[id] {
+					counters[field] = n
+				}
+				docs = append(docs, counted{ID: id, Counters: counters})
+			}
+			return docs, nil
+		},
+		count: func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error) {
+			counts := map[primitive.ObjectID]map[string]int{}
+			for _, id := range ids {
+				counts[id] = s.actual[id]
+			}
+			return counts, nil
+		},
+		fix: func(ctx context.Context, fixes []repository.CounterFix) (int64, error) {
+			var fixed int64
+			for _, f := range fixes {
+				if s.stored[f.ID][f.Field] == f.Was {
+					s.stored[f.ID][f.Field] = f.Count
+					fixed++
+				}
+			}
+			return fixed, nil
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name string
+		docs [][2]map[string]int
+		fix  bool
+		// want lists the counters out of step, without their target and ID
+		want      []Discrepancy
+		wantFixed int64
+	}{
+		{
+			name: "counters in step",
+			docs: [][2]map[string]int{
+				{{"likes_count": 2, "comments_count": 1}, {"likes_count": 2, "comments_count": 1}},
+			},
+		},
+		{
+			name: "drift is reported but left alone without fix",
+			docs: [][2]map[string]int{
+				{{"likes_count": 3}, {"likes_count": 2}},
+			},
+			want: []Discrepancy{{Field: "likes_count", Stored: 3, Actual: 2}},
+		},
+		{
+			name: "drift is fixed",
+			docs: [][2]map[string]int{
+				{{"likes_count": 3, "views_count": 7}, {"likes_count": 2, "views_count": 7}},
+				{{"likes_count": 0, "views_count": 1}, {"likes_count": 1, "views_count": 0}},
+			},
+			fix: true,
+			want: []Discrepancy{
+				{Field: "likes_count", Stored: 3, Actual: 2},
+				{Field: "likes_count", Stored: 0, Actual: 1},
+				{Field: "views_count", Stored: 1, Actual: 0},
+			},
+			wantFixed: 3,
+		},
+		{
+			name: "reaction counted but missing from the document",
+			docs: [][2]map[string]int{
+				{{"likes_count": 0}, {"likes_count": 0, "reaction_counts.🎉": 2}},
+			},
+			fix:       true,
+			want:      []Discrepancy{{Field: "reaction_counts.🎉", Stored: 0, Actual: 2}},
+			wantFixed: 1,
+		},
+		{
+			name: "stored reaction no longer counted",
+			docs: [][2]map[string]int{
+				{{"likes_count": 0, "reaction_counts.🎉": 1}, {"likes_count": 0}},
+			},
+			fix:       true,
+			want:      []Discrepancy{{Field: "reaction_counts.🎉", Stored: 1, Actual: 0}},
+			wantFixed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(tt.docs...)
+			r := &Reconciler{batch: 2}
+			report := &Report{Checked: map[string]int{}}
+
+			if err := r.reconcile(context.Background(), s.target(), tt.fix, report); err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Checked["post"] != len(tt.docs) {
+				t.Errorf("checked %d posts, want %d", report.Checked["post"], len(tt.docs))
+			}
+			got := report.Discrepancies
+			for i := range got {
+				got[i].Target, got[i].ID = "", primitive.NilObjectID
+			}
+			sortDiscrepancies(got)
+			sortDiscrepancies(tt.want)
+			if report.Found != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("found %d: %+v, want %+v", report.Found, got, tt.want)
+			}
+			if report.Fixed != tt.wantFixed {
+				t.Errorf("fixed %d, want %d", report.Fixed, tt.wantFixed)
+			}
+
+			for i, id := range s.ids {
+				for field, n := range s.stored[id] {
+					want := tt.docs[i][0][field]
+					if tt.fix {
+						want = s.actual[id][field]
+					}
+					if n != want {
+						t.Errorf("doc %d %s = %d after the run, want %d", i, field, n, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileBatches(t *testing.T) {
+	var docs [][2]map[string]int
+	for i := 0; i < 5; i++ {
+		docs = append(docs, [2]map[string]int{{"likes_count": i}, {"likes_count": 0}})
+	}
+	s := newStore(docs...)
+	r := &Reconciler{batch: 2}
+	report := &Report{Checked: map[string]int{}}
+
+	if err := r.reconcile(context.Background(), s.target(), true, report); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages of 2, 2 and 1; the short page ends the run
+	if s.pages != 3 {
+		t.Errorf("read %d pages, want 3", s.pages)
+	}
+	if report.Checked["post"] != 5 || report.Found != 4 || report.Fixed != 4 {
+		t.Errorf("report = %d checked, %d found, %d fixed; want 5, 4, 4", report.Checked["post"], report.Found, report.Fixed)
+	}
+}
+
+func TestReconcileCapsDiscrepancies(t *testing.T) {
+	var docs [][2]map[string]int
+	for i := 0; i < MaxReported+10; i++ {
+		docs = append(docs, [2]map[string]int{{"likes_count": 1}, {"likes_count": 0}})
+	}
+	r := &Reconciler{batch: 50}
+	report := &Report{Checked: map[string]int{}}
+
+	if err := r.reconcile(context.Background(), newStore(docs...).target(), false, report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Found != MaxReported+10 || len(report.Discrepancies) != MaxReported {
+		t.Fatalf("found %d, listed %d; want %d and %d", report.Found, len(report.Discrepancies), MaxReported+10, MaxReported)
+	}
+}
+
+func sortDiscrepancies(ds []Discrepancy) {
+	sort.Slice(ds, func(i, j int) bool {
+		if ds[i].Field != ds[j].Field {
+			return ds[i].Field < ds[j].Field
+		}
+		return ds[i].Stored < ds[j].Stored
+	})
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AccountTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAccountTokenRepository(db *mongo.Database) *AccountTokenRepository {
+	return &AccountTokenRepository{
+		collection: db.Collection("account_tokens"),
+	}
+}
+
+// EnsureIndexes creates the lookup index on token_hash and a TTL index that
+// removes tokens once they have expired
+func (r *AccountTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (r *AccountTokenRepository) Create(ctx context.Context, token *models.AccountToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+	token.UsedAt = nil
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// Consume atomically marks an unused, unexpired token as used and returns it
+func (r *AccountTokenRepository) Consume(ctx context.Context, tokenHash, purpose string) (*models.AccountToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"purpose":    purpose,
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var token models.AccountToken
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("invalid or expired token")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Redeem records that a token which is not stored when issued, such as an
+// OAuth state, has been used. It reports false if it already was. The record
+// is kept until expiresAt, after which the token is rejected anyway.
+func (r *AccountTokenRepository) Redeem(ctx context.Context, userID primitive.ObjectID, tokenHash, purpose string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	_, err := r.collection.InsertOne(ctx, &models.AccountToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		UsedAt:    &now,
+		CreatedAt: now,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// InvalidateForUser marks every outstanding token of a purpose as used, so
+// only the most recently mailed link works
+func (r *AccountTokenRepository) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "purpose": purpose, "used_at": nil},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *AccountTokenRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAccountTokenConsume(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("consumes an unused, unexpired token", func(mt *mtest.T) {
+		repo := NewAccountTokenRepository(mt.DB)
+		userID := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "user_id", Value: userID},
+			{Key: "purpose", Value: "PASSWORD_RESET"},
+			{Key: "used_at", Value: time.Now()},
+		}}))
+
+		before := time.Now()
+		token, err := repo.Consume(context.Background(), "hash", "PASSWORD_RESET")
+		if err != nil {
+			mt.Fatalf("Consume() error = %v", err)
+		}
+		if token.UserID != userID {
+			mt.Fatalf("Consume() user = %s, want %s", token.UserID.Hex(), userID.Hex())
+		}
+
+		cmd := mt.GetStartedEvent().Command
+		query := cmd.Lookup("query").Document()
+		if query.Lookup("token_hash").StringValue() != "hash" || query.Lookup("purpose").StringValue() != "PASSWORD_RESET" {
+			mt.Errorf("query = %s", query)
+		}
+		// Single use: only tokens not yet used match, and using one marks it
+		if v := query.Lookup("used_at"); v.Type != bson.TypeNull {
+			mt.Errorf("query used_at = %s, want null", v)
+		}
+		if _, ok := cmd.Lookup("update", "$set", "used_at").TimeOK(); !ok {
+			mt.Errorf("update = %s, want used_at set", cmd.Lookup("update"))
+		}
+		// Expiry: only tokens expiring after now match
+		gt, ok := query.Lookup("expires_at", "$gt").TimeOK()
+		if !ok || gt.Before(before.Truncate(time.Millisecond)) {
+			mt.Errorf("query expires_at = %s, want $gt now", query.Lookup("expires_at"))
+		}
+	})
+
+	mt.Run("rejects used, expired or unknown tokens", func(mt *mtest.T) {
+		repo := NewAccountTokenRepository(mt.DB)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		if _, err := repo.Consume(context.Background(), "hash", "EMAIL_VERIFICATION"); err == nil || err.Error() != "invalid or expired token" {
+			mt.Fatalf("Consume() error = %v, want invalid or expired token", err)
+		}
+	})
+}
+
+func TestAccountTokenRedeem(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("accepts a token once", func(mt *mtest.T) {
+		repo := NewAccountTokenRepository(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key error"}),
+		)
+		expiresAt := time.Now().Add(10 * time.Minute)
+
+		first, err := repo.Redeem(context.Background(), primitive.NilObjectID, "hash", "OAUTH_STATE", expiresAt)
+		if err != nil || !first {
+			mt.Fatalf("first Redeem() = %v, %v; want true", first, err)
+		}
+		second, err := repo.Redeem(context.Background(), primitive.NilObjectID, "hash", "OAUTH_STATE", expiresAt)
+		if err != nil || second {
+			mt.Fatalf("second Redeem() = %v, %v; want false", second, err)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,6 +23,28 @@ func NewCommentRepository(db *mongo.Database) *CommentRepository {
 	}
 }
 
+// EnsureIndexes backs paging through the comments of a post or reel, loading
+// replies, and restoring and purging deleted comments
+func (r *CommentRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "reel_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "parent_comment_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "deleted_with", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		deletedAtIndex(),
+	})
+	return err
+}
+
 func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
 	comment.ID = primitive.NewObjectID()
 	comment.CreatedAt = time.Now()
@@ -44,12 +67,24 @@ func (r *CommentRepository) FindByID(ctx context.Context, id primitive.ObjectID)
 	return &comment, nil
 }
 
-func (r *CommentRepository) FindByPost(ctx context.Context, postID primitive.ObjectID, limit int) ([]*models.Comment, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+// FindByPost returns a page of the post's top-level comments, newest first
+func (r *CommentRepository) FindByPost(ctx context.Context, postID primitive.ObjectID, first int, after string) (*pagination.Page[models.Comment], error) {
+	filter := bson.M{"post_id": postID, "deleted": false, "parent_comment_id": nil}
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Comment](ctx, r.collection, filter, keys, after, first)
+}
+
+// FindByReel returns a page of the reel's top-level comments, newest first
+func (r *CommentRepository) FindByReel(ctx context.Context, reelID primitive.ObjectID, first int, after string) (*pagination.Page[models.Comment], error) {
+	filter := bson.M{"reel_id": reelID, "deleted": false, "parent_comment_id": nil}
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Comment](ctx, r.collection, filter, keys, after, first)
+}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"post_id": postID, "deleted": false, "parent_comment_id": nil}, opts)
+func (r *CommentRepository) FindReplies(ctx context.Context, parentID primitive.ObjectID) ([]*models.Comment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"parent_comment_id": parentID, "deleted": false}, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -63,51 +98,156 @@ func (r *CommentRepository) FindByPost(ctx context.Context, postID primitive.Obj
 	return comments, nil
 }
 
-func (r *CommentRepository) FindByReel(ctx context.Context, reelID primitive.ObjectID, limit int) ([]*models.Comment, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+// SoftDelete marks the comments deleted by the given user, as taken along by
+// the deletion of with unless it is nil, and returns how many were not
+// deleted already
+func (r *CommentRepository) SoftDelete(ctx context.Context, ids []primitive.ObjectID, by primitive.ObjectID, with *primitive.ObjectID) (int64, error) {
+	set := bson.M{}
+	if with != nil {
+		set["deleted_with"] = *with
+	}
+	return softDelete(ctx, r.collection, bson.M{"_id": bson.M{"$in": ids}}, by, set)
+}
+
+// SoftDeleteByPost marks the post's comments deleted along with it
+func (r *CommentRepository) SoftDeleteByPost(ctx context.Context, postID, by primitive.ObjectID) error {
+	_, err := softDelete(ctx, r.collection, bson.M{"post_id": postID}, by, bson.M{"deleted_with": postID})
+	return err
+}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"reel_id": reelID, "deleted": false, "parent_comment_id": nil}, opts)
+// SoftDeleteByReel marks the reel's comments deleted along with it
+func (r *CommentRepository) SoftDeleteByReel(ctx context.Context, reelID, by primitive.ObjectID) error {
+	_, err := softDelete(ctx, r.collection, bson.M{"reel_id": reelID}, by, bson.M{"deleted_with": reelID})
+	return err
+}
+
+// FindDeleted returns the comment if it is soft-deleted
+func (r *CommentRepository) FindDeleted(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	comment, err := findDeleted[models.Comment](ctx, r.collection, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("comment not found")
+	}
+	return comment, err
+}
+
+// Restore undoes SoftDelete for one comment and reports whether it was
+// deleted
+func (r *CommentRepository) Restore(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	n, err := restore(ctx, r.collection, bson.M{"_id": id})
+	return n > 0, err
+}
+
+// RestoreDeletedWith restores the comments deleted along with a post, reel or
+// comment and returns how many there were
+func (r *CommentRepository) RestoreDeletedWith(ctx context.Context, withID primitive.ObjectID) (int64, error) {
+	return restore(ctx, r.collection, bson.M{"deleted_with": withID})
+}
+
+// FindDeletedBefore returns up to limit comments soft-deleted before the
+// given time. Comments blanked by an account purge have no deletion time and
+// are kept.
+func (r *CommentRepository) FindDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*models.Comment, error) {
+	return findDeletedBefore[models.Comment](ctx, r.collection, before, limit)
+}
+
+// CountByPosts counts the comments that are not deleted on each of the posts
+func (r *CommentRepository) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	return r.countBy(ctx, "post_id", postIDs)
+}
+
+// CountByReels counts the comments that are not deleted on each of the reels
+func (r *CommentRepository) CountByReels(ctx context.Context, reelIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	return r.countBy(ctx, "reel_id", reelIDs)
+}
+
+func (r *CommentRepository) countBy(ctx context.Context, field string, ids []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{field: bson.M{"$in": ids}, "deleted": false}}},
+		{{Key: "$group", Value: bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var comments []*models.Comment
-	if err = cursor.All(ctx, &comments); err != nil {
-		return nil, err
+	counts := make(map[primitive.ObjectID]int, len(ids))
+	for cursor.Next(ctx) {
+		var group struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Count int                `bson:"count"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		counts[group.ID] = group.Count
 	}
+	return counts, cursor.Err()
+}
 
-	return comments, nil
+// FindIDsByPost returns the IDs of every comment on the post, deleted or not
+func (r *CommentRepository) FindIDsByPost(ctx context.Context, postID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.findIDs(ctx, bson.M{"post_id": postID})
 }
 
-func (r *CommentRepository) FindReplies(ctx context.Context, parentID primitive.ObjectID) ([]*models.Comment, error) {
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+// FindIDsByReel returns the IDs of every comment on the reel, deleted or not
+func (r *CommentRepository) FindIDsByReel(ctx context.Context, reelID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.findIDs(ctx, bson.M{"reel_id": reelID})
+}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"parent_comment_id": parentID, "deleted": false}, opts)
+func (r *CommentRepository) findIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "_id", filter)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var comments []*models.Comment
-	if err = cursor.All(ctx, &comments); err != nil {
-		return nil, err
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
 	}
-
-	return comments, nil
+	return ids, nil
 }
 
-func (r *CommentRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"deleted": true}},
-	)
+// DeleteByIDs removes the comments for good
+func (r *CommentRepository) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	return err
 }
 
+// AdjustCounts atomically adds deltas to the comment's counters and returns
+// their new values
+func (r *CommentRepository) AdjustCounts(ctx context.Context, id primitive.ObjectID, deltas map[string]int) (map[string]int, error) {
+	counts, err := adjustCounts(ctx, r.collection, id, deltas)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("comment not found")
+	}
+	return counts, err
+}
+
+// AdjustReaction atomically adds delta to the comment's count of reactions with
+// the emoji and returns all its reaction counts
+func (r *CommentRepository) AdjustReaction(ctx context.Context, id primitive.ObjectID, emoji string, delta int) (map[string]int, error) {
+	counts, err := adjustReaction(ctx, r.collection, id, emoji, delta)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("comment not found")
+	}
+	return counts, err
+}
+
+// AdjustCount atomically adds delta to one of the comment's counters and
+// returns its new value
+func (r *CommentRepository) AdjustCount(ctx context.Context, id primitive.ObjectID, field string, delta int) (int, error) {
+	n, err := adjustCount(ctx, r.collection, id, field, delta)
+	if err == mongo.ErrNoDocuments {
+		return 0, errors.New("comment not found")
+	}
+	return n, err
+}
+
 func (r *CommentRepository) IncrementLikes(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
@@ -117,6 +257,46 @@ func (r *CommentRepository) IncrementLikes(ctx context.Context, id primitive.Obj
 	return err
 }
 
+// FindCounters returns up to limit comments that are not deleted after the
+// given ID, in ID order with only their counters loaded
+func (r *CommentRepository) FindCounters(ctx context.Context, after primitive.ObjectID, limit int) ([]*models.Comment, error) {
+	return findCounters[models.Comment](ctx, r.collection, after, limit, "likes_count", "upvotes_count", "downvotes_count", "reaction_counts")
+}
+
+// FixCounts corrects counters found out of step and returns how many it
+// changed
+func (r *CommentRepository) FixCounts(ctx context.Context, fixes []CounterFix) (int64, error) {
+	return fixCounts(ctx, r.collection, fixes)
+}
+
 func (r *CommentRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
+
+// FindAllByAuthor returns every comment of the author, including deleted ones
+func (r *CommentRepository) FindAllByAuthor(ctx context.Context, authorID primitive.ObjectID) ([]*models.Comment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"author_id": authorID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var comments []*models.Comment
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// AnonymizeByAuthor deletes the author's comments and blanks their content.
+// The documents are kept so replies stay attached to their thread.
+func (r *CommentRepository) AnonymizeByAuthor(ctx context.Context, authorID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"author_id": authorID},
+		bson.M{"$set": bson.M{"deleted": true, "content": ""}},
+	)
+	return err
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// adjustCount atomically adds delta to a counter of the document and returns
+// its new value. A delta of 0 reads the counter. It fails with
+// mongo.ErrNoDocuments if there is no such document.
+func adjustCount(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, field string, delta int) (int, error) {
+	counts, err := adjustCounts(ctx, collection, id, map[string]int{field: delta})
+	return counts[field], err
+}
+
+// adjustCounts atomically adds deltas to counters of the document and returns
+// their new values, like adjustCount
+func adjustCounts(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, deltas map[string]int) (map[string]int, error) {
+	inc := bson.M{}
+	projection := bson.M{}
+	for field, delta := range deltas {
+		inc[field] = delta
+		projection[field] = 1
+	}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(projection)
+
+	var doc bson.M
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": inc}, opts).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(deltas))
+	for field := range deltas {
+		switch n := doc[field].(type) {
+		case int32:
+			counts[field] = int(n)
+		case int64:
+			counts[field] = int(n)
+		case float64:
+			counts[field] = int(n)
+		}
+	}
+	return counts, nil
+}
+
+// adjustReaction atomically adds delta to the document's count of reactions
+// with the emoji and returns all its reaction counts. A delta of 0 only reads
+// them. Counts that drop to 0 are kept; readers skip them.
+func adjustReaction(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, emoji string, delta int) (map[string]int, error) {
+	var doc struct {
+		ReactionCounts map[string]int `bson:"reaction_counts"`
+	}
+	projection := bson.M{"reaction_counts": 1}
+
+	var err error
+	if delta == 0 {
+		opts := options.FindOne().SetProjection(projection)
+		err = collection.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&doc)
+	} else {
+		opts := options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(projection)
+		update := bson.M{"$inc": bson.M{"reaction_counts." + emoji: delta}}
+		err = collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.ReactionCounts == nil {
+		doc.ReactionCounts = map[string]int{}
+	}
+	return doc.ReactionCounts, nil
+}
+
+// CounterFix sets a counter of a document found at Was to Count
+type CounterFix struct {
+	ID    primitive.ObjectID
+	Field string
+	Was   int
+	Count int
+}
+
+// fixCounts applies the fixes to counters that still hold the value they were
+// found with and returns how many it changed. A counter that moved meanwhile
+// is left for the next check.
+func fixCounts(ctx context.Context, collection *mongo.Collection, fixes []CounterFix) (int64, error) {
+	if len(fixes) == 0 {
+		return 0, nil
+	}
+
+	writes := make([]mongo.WriteModel, len(fixes))
+	for i, fix := range fixes {
+		var was interface{} = fix.Was
+		if fix.Was == 0 {
+			// Counters that were never set are missing rather than 0
+			was = bson.M{"$in": bson.A{0, nil}}
+		}
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": fix.ID, fix.Field: was}).
+			SetUpdate(bson.M{"$set": bson.M{fix.Field: fix.Count}})
+	}
+
+	result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// findCounters returns up to limit documents that are not deleted with IDs
+// after the given one, in ID order, with only the given counters decoded.
+// Deleted documents keep their counters for when they are restored.
+func findCounters[T any](ctx context.Context, collection *mongo.Collection, after primitive.ObjectID, limit int, fields ...string) ([]*T, error) {
+	projection := bson.M{}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(projection)
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$gt": after}, "deleted": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []*T
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
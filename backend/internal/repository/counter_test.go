@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestFixCounts(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("only fixes counters still at the value found", func(mt *mtest.T) {
+		id := primitive.NewObjectID()
+		// One counter moved since it was checked, so only one matches
+		mt.AddMockResponses(updated(1))
+
+		fixed, err := fixCounts(context.Background(), mt.Coll, []CounterFix{
+			{ID: id, Field: "likes_count", Was: 3, Count: 2},
+			{ID: id, Field: "reaction_counts.🎉", Was: 0, Count: 1},
+		})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if fixed != 1 {
+			mt.Fatalf("fixCounts() = %d, want 1", fixed)
+		}
+
+		updates := mt.GetStartedEvent().Command.Lookup("updates").Array()
+		likes := updates.Index(0).Value().Document()
+		if was, _ := likes.Lookup("q", "likes_count").AsInt64OK(); was != 3 {
+			mt.Errorf("filter = %s, want likes_count 3", likes.Lookup("q"))
+		}
+		if count, _ := likes.Lookup("u", "$set", "likes_count").AsInt64OK(); count != 2 {
+			mt.Errorf("update = %s, want likes_count 2", likes.Lookup("u"))
+		}
+
+		// A counter found at 0 may never have been set
+		reaction := updates.Index(1).Value().Document()
+		in, ok := reaction.Lookup("q", "reaction_counts.🎉", "$in").ArrayOK()
+		if !ok {
+			mt.Fatalf("filter = %s, want reaction_counts.🎉 $in [0, null]", reaction.Lookup("q"))
+		}
+		values, _ := in.Values()
+		if len(values) != 2 || values[1].Type != bson.TypeNull {
+			mt.Errorf("filter = %s, want reaction_counts.🎉 $in [0, null]", reaction.Lookup("q"))
+		}
+	})
+
+	mt.Run("nothing to fix", func(mt *mtest.T) {
+		if fixed, err := fixCounts(context.Background(), mt.Coll, nil); err != nil || fixed != 0 {
+			mt.Fatalf("fixCounts(nil) = %d, %v; want 0", fixed, err)
+		}
+	})
+}
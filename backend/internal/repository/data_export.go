@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DataExportRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDataExportRepository(db *mongo.Database) *DataExportRepository {
+	return &DataExportRepository{
+		collection: db.Collection("data_exports"),
+	}
+}
+
+// EnsureIndexes indexes exports by user and the queue by status
+func (r *DataExportRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	})
+	return err
+}
+
+func (r *DataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	export.ID = primitive.NewObjectID()
+	export.Status = models.DataExportPending
+	export.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, export)
+	return err
+}
+
+// FindByUser returns the user's exports, newest first
+func (r *DataExportRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.DataExport, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*models.DataExport
+	if err = cursor.All(ctx, &exports); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// FindLatestByUser returns the user's most recent export, or nil
+func (r *DataExportRepository) FindLatestByUser(ctx context.Context, userID primitive.ObjectID) (*models.DataExport, error) {
+	var export models.DataExport
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&export)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// ClaimNext marks the oldest pending export as processing and returns it, or
+// nil when the queue is empty. Exports stuck in processing for longer than
+// staleAfter, e.g. because their server stopped, are claimed again.
+func (r *DataExportRepository) ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.DataExport, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": []bson.M{
+			{"status": models.DataExportPending},
+			{"status": models.DataExportProcessing, "started_at": bson.M{"$lt": now.Add(-staleAfter)}},
+		},
+	}
+
+	var export models.DataExport
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"status": models.DataExportProcessing, "started_at": now}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&export)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// MarkReady records the finished archive and its download token
+func (r *DataExportRepository) MarkReady(ctx context.Context, id primitive.ObjectID, filePath, tokenHash string, expiresAt time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"status":       models.DataExportReady,
+			"file_path":    filePath,
+			"token_hash":   tokenHash,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		}},
+	)
+	return err
+}
+
+func (r *DataExportRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"status":       models.DataExportFailed,
+			"error":        reason,
+			"completed_at": time.Now(),
+		}},
+	)
+	return err
+}
+
+// FindDownload returns a ready, unexpired export matching the token hash
+func (r *DataExportRepository) FindDownload(ctx context.Context, id primitive.ObjectID, tokenHash string) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.collection.FindOne(ctx, bson.M{
+		"_id":        id,
+		"token_hash": tokenHash,
+		"status":     models.DataExportReady,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&export)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("export not found or expired")
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindExpired returns ready exports whose download link has expired
+func (r *DataExportRepository) FindExpired(ctx context.Context, now time.Time) ([]*models.DataExport, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"status":     models.DataExportReady,
+		"expires_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var exports []*models.DataExport
+	if err = cursor.All(ctx, &exports); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+func (r *DataExportRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
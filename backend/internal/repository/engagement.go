@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/devthreads/backend/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type EngagementRepository struct {
@@ -20,42 +22,405 @@ func NewEngagementRepository(db *mongo.Database) *EngagementRepository {
 	}
 }
 
-func (r *EngagementRepository) Create(ctx context.Context, engagement *models.Engagement) error {
+// legacyEngagementIndexes are the names of the indexes engagementIndex
+// replaces: the non-unique one, and the unique one from before reactions
+var legacyEngagementIndexes = []string{"user_id_1_target_type_1_target_id_1_type_1", "engagement_unique"}
+
+// engagementIndex makes each (user, target, type, emoji) engagement unique.
+// Only reactions have an emoji.
+const engagementIndex = "engagement_emoji_unique"
+
+// voteIndex allows a user one vote, up or down, per target
+const voteIndex = "vote_unique"
+
+// EnsureIndexes makes each engagement and each vote unique, backs lookups of
+// a user's recent engagements and votes on an author, and removing the
+// engagements of a target. Duplicates left over from before the unique index
+// are removed first, and upvotes from before downvotes are marked as votes.
+func (r *EngagementRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.removeDuplicates(ctx); err != nil {
+		return err
+	}
+	for _, name := range legacyEngagementIndexes {
+		if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return err
+		}
+	}
+	if _, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"type": bson.M{"$in": []string{models.EngagementUpvote, models.EngagementDownvote}}, "vote": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"vote": true}},
+	); err != nil {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "type", Value: 1}, {Key: "emoji", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName(engagementIndex),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "target_type", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetName(voteIndex).
+				SetPartialFilterExpression(bson.M{"vote": true}),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "target_author_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"vote": true}),
+		},
+	})
+	return err
+}
+
+// removeDuplicates keeps the oldest of each user's engagements of a type, and
+// emoji for reactions, with a target and deletes the rest
+func (r *EngagementRepository) removeDuplicates(ctx context.Context) error {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"user_id": "$user_id", "target_type": "$target_type", "target_id": "$target_id", "type": "$type", "emoji": "$emoji"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var duplicates []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var group struct {
+			IDs []primitive.ObjectID `bson:"ids"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+		duplicates = append(duplicates, group.IDs[1:]...)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+	_, err = r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}})
+	return err
+}
+
+// Create stores the engagement and reports false if the user already had one
+// of the type with the target
+func (r *EngagementRepository) Create(ctx context.Context, engagement *models.Engagement) (bool, error) {
 	engagement.ID = primitive.NewObjectID()
 	engagement.CreatedAt = time.Now()
 
 	_, err := r.collection.InsertOne(ctx, engagement)
-	return err
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (r *EngagementRepository) Exists(ctx context.Context, userID, targetID primitive.ObjectID, targetType, engagementType string) (bool, error) {
+// Exists reports whether the user has the engagement with the target
+func (r *EngagementRepository) Exists(ctx context.Context, engagement *models.Engagement) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, engagementFilter(engagement))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Delete removes the engagement and reports whether there was one
+func (r *EngagementRepository) Delete(ctx context.Context, engagement *models.Engagement) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, engagementFilter(engagement))
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// engagementFilter matches the user's engagement of the same type, and emoji
+// for reactions, with the same target
+func engagementFilter(engagement *models.Engagement) bson.M {
 	filter := bson.M{
+		"user_id":     engagement.UserID,
+		"target_id":   engagement.TargetID,
+		"target_type": engagement.TargetType,
+		"type":        engagement.Type,
+	}
+	if engagement.Emoji != "" {
+		filter["emoji"] = engagement.Emoji
+	}
+	return filter
+}
+
+// FindReactions returns the emoji the user reacted to the target with
+func (r *EngagementRepository) FindReactions(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "emoji", bson.M{
 		"user_id":     userID,
+		"target_type": targetType,
 		"target_id":   targetID,
+		"type":        models.EngagementReaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	emoji := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			emoji = append(emoji, s)
+		}
+	}
+	return emoji, nil
+}
+
+// FindVote returns the user's vote on the target, or nil if they have none
+func (r *EngagementRepository) FindVote(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (*models.Engagement, error) {
+	var vote models.Engagement
+	err := r.collection.FindOne(ctx, voteFilter(userID, targetType, targetID)).Decode(&vote)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// CastVote stores the vote, replacing the user's earlier vote on the target,
+// and returns the vote it replaced or nil if there was none
+func (r *EngagementRepository) CastVote(ctx context.Context, vote *models.Engagement) (*models.Engagement, error) {
+	vote.Vote = true
+	vote.CreatedAt = time.Now()
+	set := bson.M{
+		"type":             vote.Type,
+		"target_author_id": vote.TargetAuthorID,
+		"reputation":       vote.Reputation,
+		"created_at":       vote.CreatedAt,
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.Before)
+
+	var previous models.Engagement
+	err := r.collection.FindOneAndUpdate(ctx, voteFilter(vote.UserID, vote.TargetType, vote.TargetID), bson.M{"$set": set}, opts).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent call inserted the first vote; replace it instead
+		err = r.collection.FindOneAndUpdate(ctx, voteFilter(vote.UserID, vote.TargetType, vote.TargetID), bson.M{"$set": set}, opts).Decode(&previous)
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// RemoveVote deletes the user's vote on the target and returns it, or nil if
+// they had none
+func (r *EngagementRepository) RemoveVote(ctx context.Context, userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) (*models.Engagement, error) {
+	var previous models.Engagement
+	err := r.collection.FindOneAndDelete(ctx, voteFilter(userID, targetType, targetID)).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// CountReputationVotes counts the voter's votes on the author's posts and
+// comments since the given time that changed the author's reputation,
+// leaving out votes on the given target
+func (r *EngagementRepository) CountReputationVotes(ctx context.Context, voterID, authorID primitive.ObjectID, since time.Time, exceptTargetID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"user_id":          voterID,
+		"target_author_id": authorID,
+		"vote":             true,
+		"created_at":       bson.M{"$gte": since},
+		"reputation":       bson.M{"$exists": true, "$ne": 0},
+		"target_id":        bson.M{"$ne": exceptTargetID},
+	})
+}
+
+func voteFilter(userID primitive.ObjectID, targetType string, targetID primitive.ObjectID) bson.M {
+	return bson.M{"user_id": userID, "target_type": targetType, "target_id": targetID, "vote": true}
+}
+
+// FindRecentByUser returns up to limit of the user's engagements with
+// targets of a type made between since and until, newest first
+func (r *EngagementRepository) FindRecentByUser(ctx context.Context, userID primitive.ObjectID, targetType string, since, until time.Time, limit int) ([]*models.Engagement, error) {
+	filter := bson.M{
+		"user_id":     userID,
 		"target_type": targetType,
-		"type":        engagementType,
+		"created_at":  bson.M{"$gte": since, "$lte": until},
 	}
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
 
-	count, err := r.collection.CountDocuments(ctx, filter)
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	return count > 0, nil
+	var engagements []*models.Engagement
+	if err = cursor.All(ctx, &engagements); err != nil {
+		return nil, err
+	}
+
+	return engagements, nil
 }
 
-func (r *EngagementRepository) Delete(ctx context.Context, userID, targetID primitive.ObjectID, targetType, engagementType string) error {
+// FindEngagedTargets returns which of targetIDs the user has engaged with in
+// any way
+func (r *EngagementRepository) FindEngagedTargets(ctx context.Context, userID primitive.ObjectID, targetType string, targetIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if len(targetIDs) == 0 {
+		return nil, nil
+	}
+
 	filter := bson.M{
 		"user_id":     userID,
-		"target_id":   targetID,
 		"target_type": targetType,
-		"type":        engagementType,
+		"target_id":   bson.M{"$in": targetIDs},
+	}
+	values, err := r.collection.Distinct(ctx, "target_id", filter)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := r.collection.DeleteOne(ctx, filter)
-	return err
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// CountByTargets counts the engagements with each of the targets by type
+func (r *EngagementRepository) CountByTargets(ctx context.Context, targetType string, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"target_type": targetType, "target_id": bson.M{"$in": targetIDs}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"target_id": "$target_id", "type": "$type"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[primitive.ObjectID]map[string]int, len(targetIDs))
+	for cursor.Next(ctx) {
+		var group struct {
+			ID struct {
+				TargetID primitive.ObjectID `bson:"target_id"`
+				Type     string             `bson:"type"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		if counts[group.ID.TargetID] == nil {
+			counts[group.ID.TargetID] = map[string]int{}
+		}
+		counts[group.ID.TargetID][group.ID.Type] = group.Count
+	}
+	return counts, cursor.Err()
+}
+
+// CountReactionsByTargets counts the reactions with each of the targets by
+// emoji
+func (r *EngagementRepository) CountReactionsByTargets(ctx context.Context, targetType string, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]map[string]int, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"target_type": targetType, "target_id": bson.M{"$in": targetIDs}, "type": models.EngagementReaction}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"target_id": "$target_id", "emoji": "$emoji"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[primitive.ObjectID]map[string]int, len(targetIDs))
+	for cursor.Next(ctx) {
+		var group struct {
+			ID struct {
+				TargetID primitive.ObjectID `bson:"target_id"`
+				Emoji    string             `bson:"emoji"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		if counts[group.ID.TargetID] == nil {
+			counts[group.ID.TargetID] = map[string]int{}
+		}
+		counts[group.ID.TargetID][group.ID.Emoji] = group.Count
+	}
+	return counts, cursor.Err()
 }
 
 func (r *EngagementRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
+
+// FindAllByUser returns every engagement of the user
+func (r *EngagementRepository) FindAllByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Engagement, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var engagements []*models.Engagement
+	if err = cursor.All(ctx, &engagements); err != nil {
+		return nil, err
+	}
+
+	return engagements, nil
+}
+
+// DeleteByTargets removes every engagement with the targets
+func (r *EngagementRepository) DeleteByTargets(ctx context.Context, targetType string, targetIDs []primitive.ObjectID) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{"target_type": targetType, "target_id": bson.M{"$in": targetIDs}})
+	return err
+}
+
+// isIndexNotFound reports whether dropping an index failed because there was
+// no such index or no collection yet
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 26 || cmdErr.Code == 27
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FollowRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFollowRepository(db *mongo.Database) *FollowRepository {
+	return &FollowRepository{
+		collection: db.Collection("follows"),
+	}
+}
+
+// EnsureIndexes makes each follow unique and indexes both directions newest
+// first, so follower and following lists page without scanning
+func (r *FollowRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "followee_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	})
+	return err
+}
+
+// Create stores the follow and reports false if it already existed
+func (r *FollowRepository) Create(ctx context.Context, follow *models.Follow) (bool, error) {
+	follow.ID = primitive.NewObjectID()
+	follow.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, follow)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes the follow and reports whether there was one
+func (r *FollowRepository) Delete(ctx context.Context, followerID, followeeID primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"follower_id": followerID, "followee_id": followeeID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (r *FollowRepository) Exists(ctx context.Context, followerID, followeeID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(
+		ctx,
+		bson.M{"follower_id": followerID, "followee_id": followeeID},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindFollowers returns a page of the follows of the user, newest first
+func (r *FollowRepository) FindFollowers(ctx context.Context, followeeID primitive.ObjectID, first int, after string) (*pagination.Page[models.Follow], error) {
+	return r.page(ctx, bson.M{"followee_id": followeeID}, first, after)
+}
+
+// FindFollowing returns a page of the follows made by the user, newest first
+func (r *FollowRepository) FindFollowing(ctx context.Context, followerID primitive.ObjectID, first int, after string) (*pagination.Page[models.Follow], error) {
+	return r.page(ctx, bson.M{"follower_id": followerID}, first, after)
+}
+
+// FindFolloweeIDs returns up to limit users the user had followed by until
+// and still follows, most recently followed first
+func (r *FollowRepository) FindFolloweeIDs(ctx context.Context, followerID primitive.ObjectID, until time.Time, limit int) ([]primitive.ObjectID, error) {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"followee_id": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"follower_id": followerID, "created_at": bson.M{"$lte": until}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var follows []*models.Follow
+	if err = cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(follows))
+	for i, f := range follows {
+		ids[i] = f.FolloweeID
+	}
+	return ids, nil
+}
+
+func (r *FollowRepository) page(ctx context.Context, filter bson.M, first int, after string) (*pagination.Page[models.Follow], error) {
+	keys := []pagination.Key{{Field: "_id", Descending: true}}
+	return pagination.Find[models.Follow](ctx, r.collection, filter, keys, after, first)
+}
+
+// DeleteByIDs removes the given follows
+func (r *FollowRepository) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// hotScoreIndexes are the indexes posts and reels need to be ranked by hot
+// score, for reels TRENDING and FOR_YOU candidates. The ranking index carries
+// created_at so the window is checked on index keys;
+// the partial index finds the few documents that still hold a score once
+// they fall out of every window.
+func hotScoreIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "deleted", Value: 1}, {Key: "visibility", Value: 1},
+				{Key: "hot_score", Value: -1}, {Key: "_id", Value: -1}, {Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().
+				SetName("hot_score_created_at").
+				SetPartialFilterExpression(bson.M{"hot_score": bson.M{"$gt": 0}}),
+		},
+	}
+}
+
+// setHotScores writes the scores in one bulk write
+func setHotScores(ctx context.Context, collection *mongo.Collection, scores map[primitive.ObjectID]float64) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(scores))
+	for id, score := range scores {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": bson.M{"hot_score": score}}))
+	}
+
+	_, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// clearHotScores zeroes the scores of documents created before the given
+// time, which no TRENDING window reaches any more
+func clearHotScores(ctx context.Context, collection *mongo.Collection, before time.Time) error {
+	_, err := collection.UpdateMany(
+		ctx,
+		bson.M{"created_at": bson.M{"$lt": before}, "hot_score": bson.M{"$gt": 0}},
+		bson.M{"$set": bson.M{"hot_score": 0}},
+	)
+	return err
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ModerationLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewModerationLogRepository(db *mongo.Database) *ModerationLogRepository {
+	return &ModerationLogRepository{
+		collection: db.Collection("moderation_logs"),
+	}
+}
+
+// EnsureIndexes orders the log newest first
+func (r *ModerationLogRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	return err
+}
+
+func (r *ModerationLogRepository) Create(ctx context.Context, entry *models.ModerationLog) error {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+// List returns a page of log entries, newest first
+func (r *ModerationLogRepository) List(ctx context.Context, first int, after string) (*pagination.Page[models.ModerationLog], error) {
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.ModerationLog](ctx, r.collection, bson.M{}, keys, after, first)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lastUsedResolution limits how often LastUsedAt is written for busy tokens
+const lastUsedResolution = time.Minute
+
+type PersonalAccessTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPersonalAccessTokenRepository(db *mongo.Database) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{
+		collection: db.Collection("personal_access_tokens"),
+	}
+}
+
+func (r *PersonalAccessTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	return err
+}
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+	token.Revoked = false
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// FindActiveByHash returns a token that is neither revoked nor expired
+func (r *PersonalAccessTokenRepository) FindActiveByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"revoked":    false,
+		"$or": []bson.M{
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+
+	var token models.PersonalAccessToken
+	err := r.collection.FindOne(ctx, filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("personal access token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PersonalAccessTokenRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "revoked": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.PersonalAccessToken
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// TouchLastUsed records token usage at most once per lastUsedResolution
+func (r *PersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+			"$or": []bson.M{
+				{"last_used_at": nil},
+				{"last_used_at": bson.M{"$lt": now.Add(-lastUsedResolution)}},
+			},
+		},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	)
+	return err
+}
+
+// Revoke revokes a token owned by the given user
+func (r *PersonalAccessTokenRepository) Revoke(ctx context.Context, id, userID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "user_id": userID, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("personal access token not found")
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"user_id": userID, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	return err
+}
+
+func (r *PersonalAccessTokenRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
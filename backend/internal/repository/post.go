@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,6 +23,24 @@ func NewPostRepository(db *mongo.Database) *PostRepository {
 	}
 }
 
+// EnsureIndexes backs the keyset pagination of feeds, author pages and the
+// admin list, the hot score refresh and purging deleted posts
+func (r *PostRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, append([]mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "deleted", Value: 1}, {Key: "visibility", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		deletedAtIndex(),
+	}, hotScoreIndexes()...))
+	return err
+}
+
 func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
 	post.ID = primitive.NewObjectID()
 	post.CreatedAt = time.Now()
@@ -48,14 +67,128 @@ func (r *PostRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*
 	return &post, nil
 }
 
+// FindByIDs returns the posts among ids that are not deleted, in no
+// particular order
+func (r *PostRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Post, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "deleted": false})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var posts []*models.Post
+	if err = cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
 func (r *PostRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
 	update["updated_at"] = time.Now()
 	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
 	return err
 }
 
-func (r *PostRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return r.Update(ctx, id, bson.M{"deleted": true})
+// ErrEditConflict is returned when a post changed between being read and
+// edited
+var ErrEditConflict = errors.New("the post was edited meanwhile, reload it and try again")
+
+// ApplyEdit saves an edit made to version of the post. update holds the
+// changed fields; an edit of the content bumps the version and marks the post
+// edited.
+func (r *PostRepository) ApplyEdit(ctx context.Context, id primitive.ObjectID, version int, update bson.M, contentChanged bool) error {
+	now := time.Now()
+	update["updated_at"] = now
+	changes := bson.M{"$set": update}
+	if contentChanged {
+		update["edited"] = true
+		update["edited_at"] = now
+		changes["$inc"] = bson.M{"version": 1}
+	}
+
+	// Posts from before versioning have no version field
+	versionFilter := interface{}(version)
+	if version == 0 {
+		versionFilter = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted": false, "version": versionFilter}, changes)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+// SoftDelete marks the post deleted by the given user and reports whether
+// it was not deleted already
+func (r *PostRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, by primitive.ObjectID) (bool, error) {
+	n, err := softDelete(ctx, r.collection, bson.M{"_id": id}, by, bson.M{"updated_at": time.Now()})
+	return n > 0, err
+}
+
+// FindDeleted returns the post if it is soft-deleted
+func (r *PostRepository) FindDeleted(ctx context.Context, id primitive.ObjectID) (*models.Post, error) {
+	post, err := findDeleted[models.Post](ctx, r.collection, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("post not found")
+	}
+	return post, err
+}
+
+// Restore undoes SoftDelete and reports whether the post was deleted
+func (r *PostRepository) Restore(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	n, err := restore(ctx, r.collection, bson.M{"_id": id})
+	return n > 0, err
+}
+
+// FindDeletedBefore returns up to limit posts soft-deleted before the given
+// time. Content blanked by an account purge has no deletion time and is kept.
+func (r *PostRepository) FindDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*models.Post, error) {
+	return findDeletedBefore[models.Post](ctx, r.collection, before, limit)
+}
+
+// HardDelete removes the post for good
+func (r *PostRepository) HardDelete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// AdjustCounts atomically adds deltas to the post's counters and returns
+// their new values
+func (r *PostRepository) AdjustCounts(ctx context.Context, id primitive.ObjectID, deltas map[string]int) (map[string]int, error) {
+	counts, err := adjustCounts(ctx, r.collection, id, deltas)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("post not found")
+	}
+	return counts, err
+}
+
+// AdjustReaction atomically adds delta to the post's count of reactions with
+// the emoji and returns all its reaction counts
+func (r *PostRepository) AdjustReaction(ctx context.Context, id primitive.ObjectID, emoji string, delta int) (map[string]int, error) {
+	counts, err := adjustReaction(ctx, r.collection, id, emoji, delta)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("post not found")
+	}
+	return counts, err
+}
+
+// AdjustCount atomically adds delta to one of the post's counters and
+// returns its new value
+func (r *PostRepository) AdjustCount(ctx context.Context, id primitive.ObjectID, field string, delta int) (int, error) {
+	n, err := adjustCount(ctx, r.collection, id, field, delta)
+	if err == mongo.ErrNoDocuments {
+		return 0, errors.New("post not found")
+	}
+	return n, err
 }
 
 func (r *PostRepository) IncrementCount(ctx context.Context, id primitive.ObjectID, field string) error {
@@ -76,28 +209,38 @@ func (r *PostRepository) DecrementCount(ctx context.Context, id primitive.Object
 	return err
 }
 
-func (r *PostRepository) Feed(ctx context.Context, filter string, limit int, skip int) ([]*models.Post, error) {
-	var sortField bson.D
-	findFilter := bson.M{"deleted": false, "visibility": "PUBLIC"}
+// Feed returns a page of public posts, newest first
+func (r *PostRepository) Feed(ctx context.Context, first int, after string) (*pagination.Page[models.Post], error) {
+	filter := bson.M{"deleted": false, "visibility": models.VisibilityPublic}
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Post](ctx, r.collection, filter, keys, after, first)
+}
 
-	switch filter {
-	case "TRENDING":
-		// Sort by engagement score (likes + comments + upvotes)
-		sortField = bson.D{
-			{Key: "likes_count", Value: -1},
-			{Key: "upvotes_count", Value: -1},
-			{Key: "comments_count", Value: -1},
-		}
-	default: // LATEST
-		sortField = bson.D{{Key: "created_at", Value: -1}}
+// FindCandidates returns up to limit public posts created between since and
+// until, hottest first, that are by one of authors, have one of tags or are
+// in one of languages. With none given every post matches.
+func (r *PostRepository) FindCandidates(ctx context.Context, since, until time.Time, authors []primitive.ObjectID, tags, languages []string, limit int) ([]*models.Post, error) {
+	filter := bson.M{"deleted": false, "visibility": models.VisibilityPublic, "created_at": bson.M{"$gte": since, "$lte": until}}
+
+	var or []bson.M
+	if len(authors) > 0 {
+		or = append(or, bson.M{"author_id": bson.M{"$in": authors}})
+	}
+	if len(tags) > 0 {
+		or = append(or, bson.M{"tags": bson.M{"$in": tags}})
+	}
+	if len(languages) > 0 {
+		or = append(or, bson.M{"language": bson.M{"$in": languages}})
+	}
+	if len(or) > 0 {
+		filter["$or"] = or
 	}
 
 	opts := options.Find().
 		SetLimit(int64(limit)).
-		SetSkip(int64(skip)).
-		SetSort(sortField)
+		SetSort(bson.D{{Key: "hot_score", Value: -1}, {Key: "_id", Value: -1}})
 
-	cursor, err := r.collection.Find(ctx, findFilter, opts)
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -111,29 +254,40 @@ func (r *PostRepository) Feed(ctx context.Context, filter string, limit int, ski
 	return posts, nil
 }
 
-func (r *PostRepository) FindByAuthor(ctx context.Context, authorID primitive.ObjectID, limit int) ([]*models.Post, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+// FindCreatedSince returns a page of posts created since the given time,
+// deleted ones included, newest first
+func (r *PostRepository) FindCreatedSince(ctx context.Context, since time.Time, first int, after string) (*pagination.Page[models.Post], error) {
+	filter := bson.M{"created_at": bson.M{"$gte": since}}
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Post](ctx, r.collection, filter, keys, after, first)
+}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"author_id": authorID, "deleted": false}, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+// SetHotScores stores recomputed hot scores by post ID
+func (r *PostRepository) SetHotScores(ctx context.Context, scores map[primitive.ObjectID]float64) error {
+	return setHotScores(ctx, r.collection, scores)
+}
 
-	var posts []*models.Post
-	if err = cursor.All(ctx, &posts); err != nil {
-		return nil, err
+// ClearHotScores zeroes the hot scores of posts created before the given time
+func (r *PostRepository) ClearHotScores(ctx context.Context, before time.Time) error {
+	return clearHotScores(ctx, r.collection, before)
+}
+
+// FindByAuthor returns a page of the author's posts, newest first. Only posts
+// with one of visibilities are included unless it is empty.
+func (r *PostRepository) FindByAuthor(ctx context.Context, authorID primitive.ObjectID, visibilities []string, first int, after string) (*pagination.Page[models.Post], error) {
+	filter := bson.M{"author_id": authorID, "deleted": false}
+	if len(visibilities) > 0 {
+		filter["visibility"] = bson.M{"$in": visibilities}
 	}
 
-	return posts, nil
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Post](ctx, r.collection, filter, keys, after, first)
 }
 
 func (r *PostRepository) Search(ctx context.Context, query string, limit int) ([]*models.Post, error) {
 	filter := bson.M{
-		"deleted": false,
-		"visibility": "PUBLIC",
+		"deleted":    false,
+		"visibility": models.VisibilityPublic,
 		"$or": []bson.M{
 			{"content": bson.M{"$regex": query, "$options": "i"}},
 			{"tags": bson.M{"$in": []string{query}}},
@@ -158,17 +312,38 @@ func (r *PostRepository) Search(ctx context.Context, query string, limit int) ([
 	return posts, nil
 }
 
+// FindCounters returns up to limit posts that are not deleted after the
+// given ID, in ID order with only their counters loaded
+func (r *PostRepository) FindCounters(ctx context.Context, after primitive.ObjectID, limit int) ([]*models.Post, error) {
+	return findCounters[models.Post](ctx, r.collection, after, limit, "likes_count", "comments_count", "upvotes_count", "downvotes_count", "views_count", "reaction_counts")
+}
+
+// FixCounts corrects counters found out of step and returns how many it
+// changed
+func (r *PostRepository) FixCounts(ctx context.Context, fixes []CounterFix) (int64, error) {
+	return fixCounts(ctx, r.collection, fixes)
+}
+
 func (r *PostRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
 
-func (r *PostRepository) List(ctx context.Context, limit int, skip int) ([]*models.Post, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSkip(int64(skip)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+// List returns a page of all posts, deleted ones included, newest first. A
+// non-empty query only matches posts containing it.
+func (r *PostRepository) List(ctx context.Context, query string, first int, after string) (*pagination.Page[models.Post], error) {
+	filter := bson.M{}
+	if query != "" {
+		filter["content"] = bson.M{"$regex": query, "$options": "i"}
+	}
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Post](ctx, r.collection, filter, keys, after, first)
+}
+
+// FindAllByAuthor returns every post of the author, including deleted ones
+func (r *PostRepository) FindAllByAuthor(ctx context.Context, authorID primitive.ObjectID) ([]*models.Post, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"author_id": authorID}, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -181,3 +356,16 @@ func (r *PostRepository) List(ctx context.Context, limit int, skip int) ([]*mode
 
 	return posts, nil
 }
+
+// AnonymizeByAuthor deletes the author's posts and blanks their content
+func (r *PostRepository) AnonymizeByAuthor(ctx context.Context, authorID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"author_id": authorID},
+		bson.M{
+			"$set":   bson.M{"deleted": true, "content": "", "updated_at": time.Now()},
+			"$unset": bson.M{"code_snippet": "", "language": "", "tags": ""},
+		},
+	)
+	return err
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PostRevisionRepository keeps the earlier versions of edited posts
+type PostRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPostRevisionRepository(db *mongo.Database) *PostRevisionRepository {
+	return &PostRevisionRepository{
+		collection: db.Collection("post_revisions"),
+	}
+}
+
+// EnsureIndexes keeps one revision per version of a post and finds an
+// author's revisions for export and deletion
+func (r *PostRevisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "version", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "author_id", Value: 1}},
+		},
+	})
+	return err
+}
+
+// Create stores the revision. A revision of the same version that is already
+// stored is left as it is: a version's content never changes, so it matches.
+func (r *PostRevisionRepository) Create(ctx context.Context, revision *models.PostRevision) error {
+	revision.ID = primitive.NewObjectID()
+	revision.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, revision)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// FindRecentByPost returns the latest limit revisions of a post, oldest
+// first
+func (r *PostRevisionRepository) FindRecentByPost(ctx context.Context, postID primitive.ObjectID, limit int) ([]*models.PostRevision, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{"post_id": postID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []*models.PostRevision
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(revisions)
+	return revisions, nil
+}
+
+// FindAllByAuthor returns every revision of the author's posts
+func (r *PostRevisionRepository) FindAllByAuthor(ctx context.Context, authorID primitive.ObjectID) ([]*models.PostRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "post_id", Value: 1}, {Key: "version", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"author_id": authorID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []*models.PostRevision
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// DeleteByPost removes the revisions of the post
+func (r *PostRevisionRepository) DeleteByPost(ctx context.Context, postID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+func (r *PostRevisionRepository) DeleteAllForAuthor(ctx context.Context, authorID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"author_id": authorID})
+	return err
+}
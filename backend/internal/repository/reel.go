@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,6 +23,24 @@ func NewReelRepository(db *mongo.Database) *ReelRepository {
 	}
 }
 
+// EnsureIndexes backs the keyset pagination of reel lists, the hot score
+// refresh and purging deleted reels
+func (r *ReelRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, append([]mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "deleted", Value: 1}, {Key: "visibility", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		deletedAtIndex(),
+	}, hotScoreIndexes()...))
+	return err
+}
+
 func (r *ReelRepository) Create(ctx context.Context, reel *models.Reel) error {
 	reel.ID = primitive.NewObjectID()
 	reel.CreatedAt = time.Now()
@@ -47,51 +66,111 @@ func (r *ReelRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*
 	return &reel, nil
 }
 
-func (r *ReelRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+// SoftDelete marks the reel deleted by the given user and reports whether
+// it was not deleted already
+func (r *ReelRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, by primitive.ObjectID) (bool, error) {
+	n, err := softDelete(ctx, r.collection, bson.M{"_id": id}, by, bson.M{"updated_at": time.Now()})
+	return n > 0, err
+}
+
+// FindDeleted returns the reel if it is soft-deleted
+func (r *ReelRepository) FindDeleted(ctx context.Context, id primitive.ObjectID) (*models.Reel, error) {
+	reel, err := findDeleted[models.Reel](ctx, r.collection, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("reel not found")
+	}
+	return reel, err
+}
+
+// Restore undoes SoftDelete and reports whether the reel was deleted
+func (r *ReelRepository) Restore(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	n, err := restore(ctx, r.collection, bson.M{"_id": id})
+	return n > 0, err
+}
+
+// FindDeletedBefore returns up to limit reels soft-deleted before the given
+// time. Content blanked by an account purge has no deletion time and is kept.
+func (r *ReelRepository) FindDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*models.Reel, error) {
+	return findDeletedBefore[models.Reel](ctx, r.collection, before, limit)
+}
+
+// HardDelete removes the reel for good
+func (r *ReelRepository) HardDelete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// AdjustReaction atomically adds delta to the reel's count of reactions with
+// the emoji and returns all its reaction counts
+func (r *ReelRepository) AdjustReaction(ctx context.Context, id primitive.ObjectID, emoji string, delta int) (map[string]int, error) {
+	counts, err := adjustReaction(ctx, r.collection, id, emoji, delta)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("reel not found")
+	}
+	return counts, err
+}
+
+// AdjustCount atomically adds delta to one of the reel's counters and
+// returns its new value
+func (r *ReelRepository) AdjustCount(ctx context.Context, id primitive.ObjectID, field string, delta int) (int, error) {
+	n, err := adjustCount(ctx, r.collection, id, field, delta)
+	if err == mongo.ErrNoDocuments {
+		return 0, errors.New("reel not found")
+	}
+	return n, err
+}
+
+func (r *ReelRepository) IncrementCount(ctx context.Context, id primitive.ObjectID, field string) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
 		bson.M{"_id": id},
-		bson.M{"$set": bson.M{"deleted": true, "updated_at": time.Now()}},
+		bson.M{"$inc": bson.M{field: 1}},
 	)
 	return err
 }
 
-func (r *ReelRepository) IncrementCount(ctx context.Context, id primitive.ObjectID, field string) error {
+func (r *ReelRepository) DecrementCount(ctx context.Context, id primitive.ObjectID, field string) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
 		bson.M{"_id": id},
-		bson.M{"$inc": bson.M{field: 1}},
+		bson.M{"$inc": bson.M{field: -1}},
 	)
 	return err
 }
 
-func (r *ReelRepository) List(ctx context.Context, limit int, skip int) ([]*models.Reel, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSkip(int64(skip)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+// List returns a page of public reels, newest first
+func (r *ReelRepository) List(ctx context.Context, first int, after string) (*pagination.Page[models.Reel], error) {
+	filter := bson.M{"deleted": false, "visibility": models.VisibilityPublic}
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Reel](ctx, r.collection, filter, keys, after, first)
+}
 
-	filter := bson.M{"deleted": false, "visibility": "PUBLIC"}
-	cursor, err := r.collection.Find(ctx, filter, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
+// ListAll returns a page of all reels, deleted ones included, newest first
+func (r *ReelRepository) ListAll(ctx context.Context, first int, after string) (*pagination.Page[models.Reel], error) {
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Reel](ctx, r.collection, bson.M{}, keys, after, first)
+}
 
-	var reels []*models.Reel
-	if err = cursor.All(ctx, &reels); err != nil {
-		return nil, err
+// FindByAuthor returns a page of the author's reels, newest first. Only reels
+// with one of visibilities are included unless it is empty.
+func (r *ReelRepository) FindByAuthor(ctx context.Context, authorID primitive.ObjectID, visibilities []string, first int, after string) (*pagination.Page[models.Reel], error) {
+	filter := bson.M{"author_id": authorID, "deleted": false}
+	if len(visibilities) > 0 {
+		filter["visibility"] = bson.M{"$in": visibilities}
 	}
 
-	return reels, nil
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Reel](ctx, r.collection, filter, keys, after, first)
 }
 
-func (r *ReelRepository) FindByAuthor(ctx context.Context, authorID primitive.ObjectID, limit int) ([]*models.Reel, error) {
+// Trending returns the hottest public reels created since the given time
+func (r *ReelRepository) Trending(ctx context.Context, since time.Time, limit int) ([]*models.Reel, error) {
 	opts := options.Find().
 		SetLimit(int64(limit)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+		SetSort(bson.D{{Key: "hot_score", Value: -1}, {Key: "_id", Value: -1}})
 
-	cursor, err := r.collection.Find(ctx, bson.M{"author_id": authorID, "deleted": false}, opts)
+	filter := bson.M{"deleted": false, "visibility": models.VisibilityPublic, "created_at": bson.M{"$gte": since}}
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -105,16 +184,44 @@ func (r *ReelRepository) FindByAuthor(ctx context.Context, authorID primitive.Ob
 	return reels, nil
 }
 
-func (r *ReelRepository) Trending(ctx context.Context, limit int) ([]*models.Reel, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSort(bson.D{
-			{Key: "likes_count", Value: -1},
-			{Key: "views_count", Value: -1},
-		})
+// FindCreatedSince returns a page of reels created since the given time,
+// deleted ones included, newest first
+func (r *ReelRepository) FindCreatedSince(ctx context.Context, since time.Time, first int, after string) (*pagination.Page[models.Reel], error) {
+	filter := bson.M{"created_at": bson.M{"$gte": since}}
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.Reel](ctx, r.collection, filter, keys, after, first)
+}
 
-	filter := bson.M{"deleted": false, "visibility": "PUBLIC"}
-	cursor, err := r.collection.Find(ctx, filter, opts)
+// SetHotScores stores recomputed hot scores by reel ID
+func (r *ReelRepository) SetHotScores(ctx context.Context, scores map[primitive.ObjectID]float64) error {
+	return setHotScores(ctx, r.collection, scores)
+}
+
+// ClearHotScores zeroes the hot scores of reels created before the given time
+func (r *ReelRepository) ClearHotScores(ctx context.Context, before time.Time) error {
+	return clearHotScores(ctx, r.collection, before)
+}
+
+// FindCounters returns up to limit reels that are not deleted after the
+// given ID, in ID order with only their counters loaded
+func (r *ReelRepository) FindCounters(ctx context.Context, after primitive.ObjectID, limit int) ([]*models.Reel, error) {
+	return findCounters[models.Reel](ctx, r.collection, after, limit, "likes_count", "comments_count", "reaction_counts")
+}
+
+// FixCounts corrects counters found out of step and returns how many it
+// changed
+func (r *ReelRepository) FixCounts(ctx context.Context, fixes []CounterFix) (int64, error) {
+	return fixCounts(ctx, r.collection, fixes)
+}
+
+func (r *ReelRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+// FindAllByAuthor returns every reel of the author, including deleted ones
+func (r *ReelRepository) FindAllByAuthor(ctx context.Context, authorID primitive.ObjectID) ([]*models.Reel, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"author_id": authorID}, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +235,15 @@ func (r *ReelRepository) Trending(ctx context.Context, limit int) ([]*models.Ree
 	return reels, nil
 }
 
-func (r *ReelRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
-	return r.collection.CountDocuments(ctx, filter)
+// AnonymizeByAuthor deletes the author's reels and drops their media
+func (r *ReelRepository) AnonymizeByAuthor(ctx context.Context, authorID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"author_id": authorID},
+		bson.M{
+			"$set":   bson.M{"deleted": true, "video_url": "", "updated_at": time.Now()},
+			"$unset": bson.M{"title": "", "description": "", "thumbnail_url": "", "tags": ""},
+		},
+	)
+	return err
 }
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRefreshTokenRepository(db *mongo.Database) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		collection: db.Collection("refresh_tokens"),
+	}
+}
+
+// EnsureIndexes creates the lookup index on token_hash and a TTL index that
+// lets MongoDB drop tokens once they have expired.
+func (r *RefreshTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+	token.Revoked = false
+	if token.FamilyID.IsZero() {
+		token.FamilyID = token.ID
+	}
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// FindByHash returns the token with the given hash, including revoked ones so
+// callers can detect reuse of a rotated token.
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRotated revokes the token and records its successor. It reports false
+// when the token had already been revoked, e.g. by a concurrent refresh.
+func (r *RefreshTokenRepository) MarkRotated(ctx context.Context, id, replacedBy primitive.ObjectID) (bool, error) {
+	now := time.Now()
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true, "revoked_at": now, "replaced_by": replacedBy}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	return r.revokeMany(ctx, bson.M{"_id": id})
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, userID, familyID primitive.ObjectID) error {
+	return r.revokeMany(ctx, bson.M{"user_id": userID, "family_id": familyID})
+}
+
+func (r *RefreshTokenRepository) RevokeFamilies(ctx context.Context, familyIDs []primitive.ObjectID) error {
+	if len(familyIDs) == 0 {
+		return nil
+	}
+	return r.revokeMany(ctx, bson.M{"family_id": bson.M{"$in": familyIDs}})
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	return r.revokeMany(ctx, bson.M{"user_id": userID})
+}
+
+func (r *RefreshTokenRepository) revokeMany(ctx context.Context, filter bson.M) error {
+	filter["revoked"] = false
+	_, err := r.collection.UpdateMany(
+		ctx,
+		filter,
+		bson.M{"$set": bson.M{"revoked": true, "revoked_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *RefreshTokenRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
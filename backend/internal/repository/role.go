@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRoleRepository(db *mongo.Database) *RoleRepository {
+	return &RoleRepository{
+		collection: db.Collection("roles"),
+	}
+}
+
+// EnsureIndexes makes role names unique
+func (r *RoleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
+	role.ID = primitive.NewObjectID()
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = role.CreatedAt
+
+	_, err := r.collection.InsertOne(ctx, role)
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("a role with this name already exists")
+	}
+	return err
+}
+
+func (r *RoleRepository) FindAll(ctx context.Context) ([]*models.Role, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []*models.Role
+	if err = cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// UpdatePermissions replaces the permissions of a role and returns it
+func (r *RoleRepository) UpdatePermissions(ctx context.Context, name string, permissions []string) (*models.Role, error) {
+	var role models.Role
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"name": name},
+		bson.M{"$set": bson.M{"permissions": permissions, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&role)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("role not found")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("role not found")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// securityEventRetention is how long security events are kept
+const securityEventRetention = 90 * 24 * time.Hour
+
+type SecurityEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSecurityEventRepository(db *mongo.Database) *SecurityEventRepository {
+	return &SecurityEventRepository{
+		collection: db.Collection("security_events"),
+	}
+}
+
+// EnsureIndexes indexes events by user and by IP for investigations and lets
+// MongoDB drop them after the retention period
+func (r *SecurityEventRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "ip", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(securityEventRetention.Seconds())),
+		},
+	})
+	return err
+}
+
+func (r *SecurityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (r *SecurityEventRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSessionNotFound is returned for sessions that do not exist, have been
+// revoked or have expired
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) *SessionRepository {
+	return &SessionRepository{
+		collection: db.Collection("sessions"),
+	}
+}
+
+// EnsureIndexes indexes sessions by user and lets MongoDB drop them once
+// their last refresh token has expired
+func (r *SessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_used_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = time.Now()
+	session.LastUsedAt = session.CreatedAt
+	session.RevokedAt = nil
+
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// FindActiveByID returns the session unless it has been revoked or expired
+func (r *SessionRepository) FindActiveByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	filter := bson.M{"_id": id, "revoked_at": nil, "expires_at": bson.M{"$gt": time.Now()}}
+
+	var session models.Session
+	err := r.collection.FindOne(ctx, filter).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) FindActiveByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Session, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+	filter := bson.M{"user_id": userID, "revoked_at": nil, "expires_at": bson.M{"$gt": time.Now()}}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*models.Session
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Touch records a refresh from the session's device and extends its expiry
+func (r *SessionRepository) Touch(ctx context.Context, id primitive.ObjectID, userAgent, ip string, expiresAt time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{
+			"user_agent":   userAgent,
+			"ip":           ip,
+			"last_used_at": time.Now(),
+			"expires_at":   expiresAt,
+		}},
+	)
+	return err
+}
+
+// Revoke revokes a session owned by the given user
+func (r *SessionRepository) Revoke(ctx context.Context, id, userID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "user_id": userID, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every session of the user except the one given,
+// which may be primitive.NilObjectID to revoke them all. It returns the IDs
+// of the revoked sessions.
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID, except primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": nil}
+	if !except.IsZero() {
+		filter["_id"] = bson.M{"$ne": except}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []*models.Session
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+
+	_, err = r.collection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	return ids, err
+}
+
+func (r *SessionRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deletedAtIndex finds soft-deleted documents due for purging. It is sparse
+// since most documents are never deleted.
+func deletedAtIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	}
+}
+
+// softDelete marks the documents matching filter that are not deleted yet as
+// deleted by the given user and returns how many it marked
+func softDelete(ctx context.Context, collection *mongo.Collection, filter bson.M, by primitive.ObjectID, set bson.M) (int64, error) {
+	filter["deleted"] = false
+	update := bson.M{"deleted": true, "deleted_at": time.Now(), "deleted_by": by}
+	for k, v := range set {
+		update[k] = v
+	}
+
+	result, err := collection.UpdateMany(ctx, filter, bson.M{"$set": update})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// restore undoes softDelete for the deleted documents matching filter and
+// returns how many it restored
+func restore(ctx context.Context, collection *mongo.Collection, filter bson.M) (int64, error) {
+	filter["deleted"] = true
+	result, err := collection.UpdateMany(ctx, filter, bson.M{
+		"$set":   bson.M{"deleted": false},
+		"$unset": bson.M{"deleted_at": "", "deleted_by": "", "deleted_with": ""},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// findDeletedBefore decodes up to limit documents soft-deleted before the
+// given time, oldest deletion first
+func findDeletedBefore[T any](ctx context.Context, collection *mongo.Collection, before time.Time, limit int) ([]*T, error) {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "deleted_at", Value: 1}})
+
+	cursor, err := collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []*T
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// findDeleted returns the document with the given ID if it is soft-deleted
+func findDeleted[T any](ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) (*T, error) {
+	var doc T
+	err := collection.FindOne(ctx, bson.M{"_id": id, "deleted": true}).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
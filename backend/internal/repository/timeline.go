@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// timelineRetention is how long a post stays in its followers' timelines
+const timelineRetention = 90 * 24 * time.Hour
+
+// TimelineRepository stores each user's FOLLOWING feed as one entry per post
+type TimelineRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTimelineRepository(db *mongo.Database) *TimelineRepository {
+	return &TimelineRepository{
+		collection: db.Collection("timelines"),
+	}
+}
+
+// EnsureIndexes keeps a post at most once per timeline, orders timelines
+// newest first, finds the entries of a post and expires entries after
+// timelineRetention
+func (r *TimelineRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "post_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(timelineRetention.Seconds())),
+		},
+	})
+	return err
+}
+
+// Add inserts the entries, skipping posts that are already in a timeline
+func (r *TimelineRepository) Add(ctx context.Context, entries []*models.TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		entry.ID = primitive.NewObjectID()
+		docs[i] = entry
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// Find returns a page of the user's timeline, newest first
+func (r *TimelineRepository) Find(ctx context.Context, userID primitive.ObjectID, first int, after string) (*pagination.Page[models.TimelineEntry], error) {
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.TimelineEntry](ctx, r.collection, bson.M{"user_id": userID}, keys, after, first)
+}
+
+// RemoveAuthor takes the author's posts out of the user's timeline
+func (r *TimelineRepository) RemoveAuthor(ctx context.Context, userID, authorID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID, "author_id": authorID})
+	return err
+}
+
+// DeleteByPost takes the post out of every timeline
+func (r *TimelineRepository) DeleteByPost(ctx context.Context, postID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"post_id": postID})
+	return err
+}
+
+// DeleteAllForUser removes the user's timeline. Entries for the user's own
+// posts in other timelines are left to expire; the posts themselves are
+// deleted, so feeds skip them.
+func (r *TimelineRepository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrTrendingSnapshotExpired is returned for TRENDING cursors into a snapshot
+// that has been pruned
+var ErrTrendingSnapshotExpired = fmt.Errorf("%w: the trending ranking it points into has expired, reload the feed", pagination.ErrInvalidCursor)
+
+// TrendingRepository stores snapshots of the TRENDING post ranking. Entries
+// are written first and the snapshot is published after, so readers never
+// see one half written.
+type TrendingRepository struct {
+	entries   *mongo.Collection
+	snapshots *mongo.Collection
+}
+
+func NewTrendingRepository(db *mongo.Database) *TrendingRepository {
+	return &TrendingRepository{
+		entries:   db.Collection("trending_entries"),
+		snapshots: db.Collection("trending_snapshots"),
+	}
+}
+
+// EnsureIndexes ranks each snapshot by score, with the post's creation time
+// in the index so the window is checked on index keys
+func (r *TrendingRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.entries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "snapshot", Value: 1}, {Key: "hot_score", Value: -1},
+			{Key: "_id", Value: -1}, {Key: "post_created_at", Value: -1},
+		},
+	})
+	return err
+}
+
+// trendingCursor is a position in a snapshot: when the snapshot was taken and
+// where its window starts, in Unix milliseconds, and the keyset cursor of the
+// last entry returned
+type trendingCursor struct {
+	Snapshot int64  `json:"t"`
+	Since    int64  `json:"s"`
+	After    string `json:"a"`
+}
+
+// Add writes entries of a snapshot that has not been published yet
+func (r *TrendingRepository) Add(ctx context.Context, snapshot time.Time, entries []*models.TrendingEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		entry.ID = primitive.NewObjectID()
+		entry.Snapshot = snapshot
+		docs[i] = entry
+	}
+
+	_, err := r.entries.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// Publish makes a fully written snapshot the one new TRENDING feeds start
+// from
+func (r *TrendingRepository) Publish(ctx context.Context, snapshot time.Time) error {
+	_, err := r.snapshots.InsertOne(ctx, bson.M{"_id": snapshot})
+	return err
+}
+
+// Prune deletes the snapshots taken before the given time, and what failed
+// runs left unpublished, but always keeps the latest published snapshot
+func (r *TrendingRepository) Prune(ctx context.Context, before time.Time) error {
+	latest, err := r.latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	older := bson.M{"$lt": before}
+	if !latest.IsZero() {
+		older["$ne"] = latest
+	}
+	if _, err := r.snapshots.DeleteMany(ctx, bson.M{"_id": older}); err != nil {
+		return err
+	}
+	_, err = r.entries.DeleteMany(ctx, bson.M{"snapshot": older})
+	return err
+}
+
+// Find returns a page of the posts created within window of a snapshot,
+// hottest first. The first page reads the latest snapshot; later pages stay
+// in the snapshot and window of the first, so refreshes in between neither
+// repeat nor skip posts. Cursors into a pruned snapshot fail with
+// ErrTrendingSnapshotExpired.
+func (r *TrendingRepository) Find(ctx context.Context, window time.Duration, first int, after string) (*pagination.Page[models.TrendingEntry], error) {
+	var c trendingCursor
+	if after == "" {
+		latest, err := r.latest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if latest.IsZero() {
+			// Nothing has been ranked yet
+			return &pagination.Page[models.TrendingEntry]{Edges: []pagination.Edge[models.TrendingEntry]{}}, nil
+		}
+		c = trendingCursor{Snapshot: latest.UnixMilli(), Since: latest.Add(-window).UnixMilli()}
+	} else {
+		decoded, err := decodeTrendingCursor(after)
+		if err != nil {
+			return nil, err
+		}
+		c = *decoded
+
+		err = r.snapshots.FindOne(ctx, bson.M{"_id": time.UnixMilli(c.Snapshot)}).Err()
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTrendingSnapshotExpired
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter := bson.M{
+		"snapshot":        time.UnixMilli(c.Snapshot),
+		"post_created_at": bson.M{"$gte": time.UnixMilli(c.Since)},
+	}
+	keys := []pagination.Key{{Field: "hot_score", Descending: true}}
+	page, err := pagination.Find[models.TrendingEntry](ctx, r.entries, filter, keys, c.After, first)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range page.Edges {
+		c.After = page.Edges[i].Cursor
+		if page.Edges[i].Cursor, err = encodeTrendingCursor(&c); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// latest returns when the latest published snapshot was taken, or the zero
+// time when there is none
+func (r *TrendingRepository) latest(ctx context.Context) (time.Time, error) {
+	var snapshot struct {
+		TakenAt time.Time `bson:"_id"`
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	err := r.snapshots.FindOne(ctx, bson.M{}, opts).Decode(&snapshot)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	return snapshot.TakenAt, err
+}
+
+func encodeTrendingCursor(c *trendingCursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeTrendingCursor(s string) (*trendingCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, pagination.ErrInvalidCursor
+	}
+
+	var c trendingCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Snapshot <= 0 || c.After == "" {
+		return nil, pagination.ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devthreads/backend/internal/pagination"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestTrendingFind(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	snapshot := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := func(ns string, scores ...float64) bson.D {
+		docs := make([]bson.D, len(scores))
+		for i, score := range scores {
+			docs[i] = bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "snapshot", Value: snapshot},
+				{Key: "post_id", Value: primitive.NewObjectID()},
+				{Key: "hot_score", Value: score},
+				{Key: "post_created_at", Value: snapshot.Add(-time.Hour)},
+			}
+		}
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...)
+	}
+	published := func(ns string, taken ...time.Time) bson.D {
+		docs := make([]bson.D, len(taken))
+		for i, at := range taken {
+			docs[i] = bson.D{{Key: "_id", Value: at}}
+		}
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...)
+	}
+
+	// checkFilter asserts a page was read from the given snapshot and window
+	checkFilter := func(mt *mtest.T, filter bson.Raw, since time.Time) {
+		mt.Helper()
+		if got, _ := filter.Lookup("snapshot").TimeOK(); !got.Equal(snapshot) {
+			mt.Errorf("filter = %s, want snapshot %s", filter, snapshot)
+		}
+		if got, _ := filter.Lookup("post_created_at", "$gte").TimeOK(); !got.Equal(since) {
+			mt.Errorf("filter = %s, want post_created_at $gte %s", filter, since)
+		}
+	}
+
+	mt.Run("later pages stay in the first page's snapshot and window", func(mt *mtest.T) {
+		repo := NewTrendingRepository(mt.DB)
+		ns := mt.DB.Name()
+		mt.AddMockResponses(
+			published(ns+".trending_snapshots", snapshot),
+			entries(ns+".trending_entries", 9, 5, 3),
+		)
+
+		first, err := repo.Find(context.Background(), 24*time.Hour, 2, "")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(first.Edges) != 2 || !first.HasNextPage {
+			mt.Fatalf("first page = %d edges, hasNextPage %v", len(first.Edges), first.HasNextPage)
+		}
+		mt.GetStartedEvent()
+		checkFilter(mt, mt.GetStartedEvent().Command.Lookup("filter").Document(), snapshot.Add(-24*time.Hour))
+
+		// The snapshot is still published even though a newer one may be, and
+		// the window no longer moves with the clock
+		mt.AddMockResponses(
+			published(ns+".trending_snapshots", snapshot),
+			entries(ns+".trending_entries", 3),
+		)
+		second, err := repo.Find(context.Background(), time.Hour, 2, *first.EndCursor())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(second.Edges) != 1 || second.HasNextPage {
+			mt.Fatalf("second page = %d edges, hasNextPage %v", len(second.Edges), second.HasNextPage)
+		}
+
+		if got, _ := mt.GetStartedEvent().Command.Lookup("filter", "_id").TimeOK(); !got.Equal(snapshot) {
+			mt.Errorf("looked up snapshot %s, want %s", got, snapshot)
+		}
+		and := mt.GetStartedEvent().Command.Lookup("filter", "$and").Array()
+		checkFilter(mt, and.Index(0).Value().Document(), snapshot.Add(-24*time.Hour))
+	})
+
+	mt.Run("no snapshot yet is an empty page", func(mt *mtest.T) {
+		repo := NewTrendingRepository(mt.DB)
+		mt.AddMockResponses(published(mt.DB.Name() + ".trending_snapshots"))
+
+		page, err := repo.Find(context.Background(), time.Hour, 2, "")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(page.Edges) != 0 || page.HasNextPage {
+			mt.Fatalf("page = %d edges, hasNextPage %v", len(page.Edges), page.HasNextPage)
+		}
+	})
+
+	mt.Run("pruned snapshot", func(mt *mtest.T) {
+		repo := NewTrendingRepository(mt.DB)
+		mt.AddMockResponses(published(mt.DB.Name() + ".trending_snapshots"))
+
+		after, err := encodeTrendingCursor(&trendingCursor{Snapshot: snapshot.UnixMilli(), Since: snapshot.UnixMilli(), After: "x"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		_, err = repo.Find(context.Background(), time.Hour, 2, after)
+		if !errors.Is(err, ErrTrendingSnapshotExpired) || !errors.Is(err, pagination.ErrInvalidCursor) {
+			mt.Fatalf("Find() error = %v, want ErrTrendingSnapshotExpired", err)
+		}
+	})
+
+	mt.Run("rejects malformed cursors", func(mt *mtest.T) {
+		repo := NewTrendingRepository(mt.DB)
+		for _, after := range []string{"not a cursor!", "e30"} {
+			if _, err := repo.Find(context.Background(), time.Hour, 2, after); !errors.Is(err, pagination.ErrInvalidCursor) {
+				mt.Errorf("Find(%q) error = %v, want ErrInvalidCursor", after, err)
+			}
+		}
+	})
+}
+
+func TestTrendingPrune(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("keeps the latest snapshot", func(mt *mtest.T) {
+		repo := NewTrendingRepository(mt.DB)
+		latest := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".trending_snapshots", mtest.FirstBatch, bson.D{{Key: "_id", Value: latest}}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 300}),
+		)
+
+		before := latest.Add(time.Hour)
+		if err := repo.Prune(context.Background(), before); err != nil {
+			mt.Fatal(err)
+		}
+
+		mt.GetStartedEvent()
+		for _, field := range []string{"_id", "snapshot"} {
+			event := mt.GetStartedEvent()
+			q := event.Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q", field).Document()
+			if got, _ := q.Lookup("$lt").TimeOK(); !got.Equal(before) {
+				mt.Errorf("%s delete = %s, want %s $lt %s", event.CommandName, q, field, before)
+			}
+			if got, _ := q.Lookup("$ne").TimeOK(); !got.Equal(latest) {
+				mt.Errorf("%s delete = %s, want %s $ne %s", event.CommandName, q, field, latest)
+			}
+		}
+	})
+}
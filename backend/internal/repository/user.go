@@ -6,12 +6,17 @@ import (
 	"time"
 
 	"github.com/devthreads/backend/internal/models"
+	"github.com/devthreads/backend/internal/pagination"
+	"github.com/devthreads/backend/internal/rbac"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrUserNotFound is returned by lookups that find no user
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
 	collection *mongo.Collection
 }
@@ -22,12 +27,56 @@ func NewUserRepository(db *mongo.Database) *UserRepository {
 	}
 }
 
+// EnsureIndexes guarantees a GitHub account is linked to at most one user
+// and indexes users by role, by scheduled deletion and newest first
+func (r *UserRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "github_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"github_id": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{{Key: "role", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "deletion_scheduled_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+	})
+	return err
+}
+
+// MigrateRoles gives users created before roles existed a role based on the
+// is_admin flag they had, and drops the flag
+func (r *UserRepository) MigrateRoles(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"role": bson.M{"$exists": false}, "is_admin": true},
+		bson.M{"$set": bson.M{"role": rbac.RoleAdmin}, "$unset": bson.M{"is_admin": ""}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateMany(
+		ctx,
+		bson.M{"role": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"role": rbac.RoleUser}, "$unset": bson.M{"is_admin": ""}},
+	)
+	return err
+}
+
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	user.ID = primitive.NewObjectID()
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
 	user.Reputation = 0
-	user.IsAdmin = false
+	user.Role = rbac.RoleUser
 
 	_, err := r.collection.InsertOne(ctx, user)
 	return err
@@ -50,7 +99,7 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models
 	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -87,6 +136,201 @@ func (r *UserRepository) Update(ctx context.Context, id primitive.ObjectID, upda
 	return err
 }
 
+// UpdateAndRevokeTokens applies the update and bumps the token version in the
+// same write, so access tokens issued before the change are rejected
+func (r *UserRepository) UpdateAndRevokeTokens(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	update["updated_at"] = time.Now()
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": update,
+			"$inc": bson.M{"token_version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// Unban lifts a ban. Tokens were revoked by the ban, so nothing else changes.
+func (r *UserRepository) Unban(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$unset": bson.M{"banned_until": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// CancelDeletion clears a scheduled deletion, reporting whether one was set
+func (r *UserRepository) CancelDeletion(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "deletion_scheduled_at": bson.M{"$exists": true}},
+		bson.M{
+			"$unset": bson.M{"deletion_scheduled_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// FindDueForDeletion returns up to limit users whose grace period has ended
+func (r *UserRepository) FindDueForDeletion(ctx context.Context, now time.Time, limit int) ([]*models.User, error) {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "deletion_scheduled_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"deletion_scheduled_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// SetGithubID links a GitHub account to the user
+func (r *UserRepository) SetGithubID(ctx context.Context, id primitive.ObjectID, githubID string) error {
+	return r.Update(ctx, id, bson.M{"github_id": githubID})
+}
+
+// UnsetGithubID removes the GitHub link from the user
+func (r *UserRepository) UnsetGithubID(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$unset": bson.M{"github_id": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// UpdatePrivacy replaces the user's privacy settings
+func (r *UserRepository) UpdatePrivacy(ctx context.Context, id primitive.ObjectID, settings models.PrivacySettings) error {
+	return r.Update(ctx, id, bson.M{"privacy": settings})
+}
+
+// SetMuted adds value to, or with mute false removes it from, one of the
+// user's mute lists
+func (r *UserRepository) SetMuted(ctx context.Context, id primitive.ObjectID, field string, value interface{}, mute bool) error {
+	op := "$addToSet"
+	if !mute {
+		op = "$pull"
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{op: bson.M{field: value}})
+	return err
+}
+
+// SetPendingTOTPSecret stores a TOTP secret awaiting confirmation
+func (r *UserRepository) SetPendingTOTPSecret(ctx context.Context, id primitive.ObjectID, secret string) error {
+	return r.Update(ctx, id, bson.M{"totp_pending_secret": secret})
+}
+
+// EnableTOTP promotes the pending secret and stores hashed recovery codes
+func (r *UserRepository) EnableTOTP(ctx context.Context, id primitive.ObjectID, secret string, step int64, recoveryCodeHashes []string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{
+				"totp_enabled":   true,
+				"totp_secret":    secret,
+				"totp_last_step": step,
+				"recovery_codes": recoveryCodeHashes,
+				"updated_at":     time.Now(),
+			},
+			"$unset": bson.M{"totp_pending_secret": ""},
+		},
+	)
+	return err
+}
+
+// DisableTOTP removes every two-factor setting from the user
+func (r *UserRepository) DisableTOTP(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{"totp_enabled": false, "updated_at": time.Now()},
+			"$unset": bson.M{
+				"totp_secret":         "",
+				"totp_pending_secret": "",
+				"totp_last_step":      "",
+				"recovery_codes":      "",
+			},
+		},
+	)
+	return err
+}
+
+// RecordTOTPStep stores the time step of an accepted code. It reports false
+// when that step or a later one was already used, i.e. the code is replayed.
+func (r *UserRepository) RecordTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+			"$or": []bson.M{
+				{"totp_last_step": bson.M{"$lt": step}},
+				{"totp_last_step": bson.M{"$exists": false}},
+			},
+		},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes a recovery code hash, reporting whether it existed
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "recovery_codes": codeHash},
+		bson.M{"$pull": bson.M{"recovery_codes": codeHash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ReplaceRecoveryCodes swaps the stored recovery code hashes
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, id primitive.ObjectID, recoveryCodeHashes []string) error {
+	return r.Update(ctx, id, bson.M{"recovery_codes": recoveryCodeHashes})
+}
+
 func (r *UserRepository) UpdateReputation(ctx context.Context, id primitive.ObjectID, delta int) error {
 	_, err := r.collection.UpdateOne(
 		ctx,
@@ -99,16 +343,27 @@ func (r *UserRepository) UpdateReputation(ctx context.Context, id primitive.Obje
 	return err
 }
 
-func (r *UserRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
-	filter := bson.M{
-		"$or": []bson.M{
-			{"username": bson.M{"$regex": query, "$options": "i"}},
-			{"display_name": bson.M{"$regex": query, "$options": "i"}},
-		},
+// UpdateFollowCounts adds delta to field ("followers_count" or
+// "following_count") of each user
+func (r *UserRepository) UpdateFollowCounts(ctx context.Context, ids []primitive.ObjectID, field string, delta int) error {
+	if len(ids) == 0 {
+		return nil
 	}
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"$inc": bson.M{field: delta}},
+	)
+	return err
+}
 
-	opts := options.Find().SetLimit(int64(limit))
-	cursor, err := r.collection.Find(ctx, filter, opts)
+// FindByIDs returns the users that exist among ids, in no particular order
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
 		return nil, err
 	}
@@ -122,13 +377,16 @@ func (r *UserRepository) Search(ctx context.Context, query string, limit int) ([
 	return users, nil
 }
 
-func (r *UserRepository) List(ctx context.Context, limit int, skip int) ([]*models.User, error) {
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSkip(int64(skip)).
-		SetSort(bson.D{{Key: "created_at", Value: -1}})
+func (r *UserRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"username": bson.M{"$regex": query, "$options": "i"}},
+			{"display_name": bson.M{"$regex": query, "$options": "i"}},
+		},
+	}
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	opts := options.Find().SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -142,6 +400,21 @@ func (r *UserRepository) List(ctx context.Context, limit int, skip int) ([]*mode
 	return users, nil
 }
 
+// List returns a page of users, newest first. A non-empty query only matches
+// users whose username or email contains it.
+func (r *UserRepository) List(ctx context.Context, query string, first int, after string) (*pagination.Page[models.User], error) {
+	filter := bson.M{}
+	if query != "" {
+		filter["$or"] = []bson.M{
+			{"username": bson.M{"$regex": query, "$options": "i"}},
+			{"email": bson.M{"$regex": query, "$options": "i"}},
+		}
+	}
+
+	keys := []pagination.Key{{Field: "created_at", Descending: true}}
+	return pagination.Find[models.User](ctx, r.collection, filter, keys, after, first)
+}
+
 func (r *UserRepository) Count(ctx context.Context, filter bson.M) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func updated(n int32) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+func TestRecordTOTPStep(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("accepts a step only once", func(mt *mtest.T) {
+		repo := NewUserRepository(mt.DB)
+		id := primitive.NewObjectID()
+		// The stored step now equals 100, so the filter no longer matches
+		mt.AddMockResponses(updated(1), updated(0))
+
+		for i, want := range []bool{true, false} {
+			fresh, err := repo.RecordTOTPStep(context.Background(), id, 100)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if fresh != want {
+				mt.Fatalf("RecordTOTPStep() call %d = %v, want %v", i+1, fresh, want)
+			}
+
+			update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+			or := update.Lookup("q", "$or").Array()
+			if lt, ok := or.Index(0).Value().Document().Lookup("totp_last_step", "$lt").AsInt64OK(); !ok || lt != 100 {
+				mt.Fatalf("filter = %s, want totp_last_step $lt 100", update.Lookup("q"))
+			}
+			if step, _ := update.Lookup("u", "$set", "totp_last_step").AsInt64OK(); step != 100 {
+				mt.Fatalf("update = %s, want totp_last_step 100", update.Lookup("u"))
+			}
+		}
+	})
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("removes a code as it is used", func(mt *mtest.T) {
+		repo := NewUserRepository(mt.DB)
+		id := primitive.NewObjectID()
+		// The first use pulls the hash, so the second finds nothing to match
+		mt.AddMockResponses(updated(1), updated(0))
+
+		for i, want := range []bool{true, false} {
+			used, err := repo.UseRecoveryCode(context.Background(), id, "hash")
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if used != want {
+				mt.Fatalf("UseRecoveryCode() call %d = %v, want %v", i+1, used, want)
+			}
+
+			update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+			if update.Lookup("q", "recovery_codes").StringValue() != "hash" {
+				mt.Fatalf("filter = %s, want recovery_codes hash", update.Lookup("q"))
+			}
+			if update.Lookup("u", "$pull", "recovery_codes").StringValue() != "hash" {
+				mt.Fatalf("update = %s, want $pull of hash", update.Lookup("u"))
+			}
+		}
+	})
+}
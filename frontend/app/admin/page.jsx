@@ -39,13 +39,13 @@ export default function AdminPage() {
   const { data: userData } = useQuery(ME_QUERY)
   const { data: statsData, loading: statsLoading } = useQuery(ADMIN_STATS_QUERY)
   const { data: usersData, loading: usersLoading, refetch: refetchUsers } = useQuery(ADMIN_USERS_QUERY, {
-    variables: { limit: 50 },
+    variables: { first: 50 },
   })
   const { data: postsData, loading: postsLoading, refetch: refetchPosts } = useQuery(ADMIN_POSTS_QUERY, {
-    variables: { limit: 50 },
+    variables: { first: 50 },
   })
   const { data: logsData } = useQuery(ADMIN_MODERATION_LOGS_QUERY, {
-    variables: { limit: 20 },
+    variables: { first: 20 },
   })
 
   const [banUser] = useMutation(ADMIN_BAN_USER_MUTATION, {
@@ -112,9 +112,9 @@ export default function AdminPage() {
   }
 
   const stats = statsData?.adminStats
-  const users = usersData?.adminUsers || []
-  const posts = postsData?.adminPosts || []
-  const logs = logsData?.adminModerationLogs || []
+  const users = usersData?.adminUsers?.edges.map((edge) => edge.node) || []
+  const posts = postsData?.adminPosts?.edges.map((edge) => edge.node) || []
+  const logs = logsData?.adminModerationLogs?.edges.map((edge) => edge.node) || []
 
   if (!userData?.me?.isAdmin) {
     return null
@@ -25,17 +25,27 @@ export default function FeedPage() {
     }
   }, [router])
 
-  const { data: postsData, loading: postsLoading, refetch: refetchPosts } = useQuery(FEED_QUERY, {
+  const {
+    data: postsData,
+    loading: postsLoading,
+    refetch: refetchPosts,
+    fetchMore: fetchMorePosts,
+  } = useQuery(FEED_QUERY, {
     variables: {
       filter: activeFilter === "trending" ? "TRENDING" : "LATEST",
-      limit: 20,
+      first: 20,
     },
     skip: contentType !== "posts",
   })
 
-  const { data: reelsData, loading: reelsLoading, refetch: refetchReels } = useQuery(REELS_QUERY, {
+  const {
+    data: reelsData,
+    loading: reelsLoading,
+    refetch: refetchReels,
+    fetchMore: fetchMoreReels,
+  } = useQuery(REELS_QUERY, {
     variables: {
-      limit: 20,
+      first: 20,
     },
     skip: contentType !== "reels",
   })
@@ -55,10 +65,16 @@ export default function FeedPage() {
     setShowPostModal(true)
   }
 
-  const posts = postsData?.feed?.posts || []
-  const reels = reelsData?.reels || []
+  const posts = postsData?.feed?.edges.map((edge) => edge.node) || []
+  const reels = reelsData?.reels?.edges.map((edge) => edge.node) || []
+  const pageInfo = contentType === "posts" ? postsData?.feed?.pageInfo : reelsData?.reels?.pageInfo
   const loading = postsLoading || reelsLoading
 
+  const handleLoadMore = () => {
+    const fetchMore = contentType === "posts" ? fetchMorePosts : fetchMoreReels
+    fetchMore({ variables: { after: pageInfo.endCursor } })
+  }
+
   return (
     <div className="min-h-screen bg-background flex">
       {/* Left Sidebar */}
@@ -162,6 +178,17 @@ export default function FeedPage() {
             )
           )}
         </div>
+
+        {!loading && pageInfo?.hasNextPage && (
+          <div className="flex justify-center py-6">
+            <button
+              onClick={handleLoadMore}
+              className="px-4 py-2 rounded-lg border border-border text-sm font-medium text-muted-foreground hover:text-foreground hover:bg-muted transition-all"
+            >
+              Load more
+            </button>
+          </div>
+        )}
       </main>
 
       {/* Right Sidebar - Tags & Suggestions */}
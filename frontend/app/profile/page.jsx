@@ -25,7 +25,7 @@ export default function ProfilePage() {
   const { data: postsData, loading: postsLoading } = useQuery(USER_POSTS_QUERY, {
     variables: {
       userId: user?.id || "",
-      limit: 20,
+      first: 20,
     },
     skip: !user?.id,
   })
@@ -62,7 +62,7 @@ export default function ProfilePage() {
     })
   }
 
-  const posts = postsData?.userPosts?.posts || []
+  const posts = postsData?.userPosts?.edges.map((edge) => edge.node) || []
 
   if (userLoading) {
     return (
@@ -1,7 +1,7 @@
 import { ApolloClient, InMemoryCache, createHttpLink, split } from '@apollo/client'
 import { setContext } from '@apollo/client/link/context'
 import { WebSocketLink } from '@apollo/client/link/ws'
-import { getMainDefinition } from '@apollo/client/utilities'
+import { getMainDefinition, relayStylePagination } from '@apollo/client/utilities'
 
 const httpLink = createHttpLink({
   uri: process.env.NEXT_PUBLIC_GRAPHQL_URL || 'http://localhost:8080/graphql',
@@ -24,9 +24,12 @@ const wsLink =
         uri: process.env.NEXT_PUBLIC_GRAPHQL_WS_URL || 'ws://localhost:8080/graphql',
         options: {
           reconnect: true,
-          connectionParams: () => ({
-            authorization: `Bearer ${localStorage.getItem('accessToken')}`,
-          }),
+          // Sent in connection_init; the server closes the connection when
+          // the token expires, and reconnecting picks up the refreshed one
+          connectionParams: () => {
+            const token = localStorage.getItem('accessToken')
+            return token ? { authorization: `Bearer ${token}` } : {}
+          },
         },
       })
     : null
@@ -50,18 +53,17 @@ const client = new ApolloClient({
     typePolicies: {
       Query: {
         fields: {
-          feed: {
-            keyArgs: ['filter'],
-            merge(existing, incoming, { args }) {
-              if (!existing) return incoming
-              if (!args?.cursor) return incoming // Fresh query
-
-              return {
-                ...incoming,
-                posts: [...existing.posts, ...incoming.posts],
-              }
-            },
-          },
+          // Connections are cached per set of non-paging arguments, and
+          // fetchMore with the previous endCursor appends the next page
+          feed: relayStylePagination(['filter']),
+          userPosts: relayStylePagination(['userId']),
+          reels: relayStylePagination(),
+          userReels: relayStylePagination(['userId']),
+          comments: relayStylePagination(['postId', 'reelId']),
+          adminUsers: relayStylePagination(['filter']),
+          adminPosts: relayStylePagination(['filter']),
+          adminReels: relayStylePagination(),
+          adminModerationLogs: relayStylePagination(),
         },
       },
     },
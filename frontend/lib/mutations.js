@@ -22,24 +22,31 @@ export const SIGNUP_MUTATION = gql`
 export const LOGIN_MUTATION = gql`
   mutation Login($input: LoginInput!) {
     login(input: $input) {
-      accessToken
-      refreshToken
-      user {
-        id
-        username
-        email
-        displayName
-        avatarUrl
-        reputation
-        isAdmin
+      __typename
+      ... on AuthPayload {
+        accessToken
+        refreshToken
+        user {
+          id
+          username
+          email
+          displayName
+          avatarUrl
+          reputation
+          isAdmin
+        }
+      }
+      ... on MfaChallenge {
+        challengeToken
+        expiresAt
       }
     }
   }
 `
 
-export const LOGIN_WITH_GITHUB_MUTATION = gql`
-  mutation LoginWithGithub($code: String!) {
-    loginWithGithub(code: $code) {
+export const VERIFY_MFA_MUTATION = gql`
+  mutation VerifyMfa($challengeToken: String!, $code: String!) {
+    verifyMfa(challengeToken: $challengeToken, code: $code) {
       accessToken
       refreshToken
       user {
@@ -49,11 +56,36 @@ export const LOGIN_WITH_GITHUB_MUTATION = gql`
         displayName
         avatarUrl
         reputation
+        isAdmin
       }
     }
   }
 `
 
+export const GITHUB_LINK_URL_MUTATION = gql`
+  mutation GithubLinkUrl {
+    githubLinkUrl
+  }
+`
+
+export const LINK_GITHUB_MUTATION = gql`
+  mutation LinkGithub($code: String!, $state: String!) {
+    linkGithub(code: $code, state: $state) {
+      id
+      githubLinked
+    }
+  }
+`
+
+export const UNLINK_GITHUB_MUTATION = gql`
+  mutation UnlinkGithub {
+    unlinkGithub {
+      id
+      githubLinked
+    }
+  }
+`
+
 export const LOGOUT_MUTATION = gql`
   mutation Logout {
     logout
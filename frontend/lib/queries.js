@@ -28,31 +28,36 @@ export const ME_QUERY = gql`
 // ========== Post Queries ==========
 
 export const FEED_QUERY = gql`
-  query Feed($filter: FeedFilter, $limit: Int, $cursor: ID) {
-    feed(filter: $filter, limit: $limit, cursor: $cursor) {
-      posts {
-        id
-        content
-        codeSnippet
-        language
-        tags
-        createdAt
-        likesCount
-        commentsCount
-        viewsCount
-        upvotesCount
-        viewerLiked
-        viewerUpvoted
-        author {
+  query Feed($filter: FeedFilter, $first: Int, $after: String) {
+    feed(filter: $filter, first: $first, after: $after) {
+      edges {
+        cursor
+        node {
           id
-          username
-          displayName
-          avatarUrl
-          reputation
+          content
+          codeSnippet
+          language
+          tags
+          createdAt
+          likesCount
+          commentsCount
+          viewsCount
+          upvotesCount
+          viewerLiked
+          viewerUpvoted
+          author {
+            id
+            username
+            displayName
+            avatarUrl
+            reputation
+          }
         }
       }
-      hasMore
-      cursor
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
     }
   }
 `
@@ -109,22 +114,27 @@ export const POST_QUERY = gql`
 `
 
 export const USER_POSTS_QUERY = gql`
-  query UserPosts($userId: ID!, $limit: Int, $cursor: ID) {
-    userPosts(userId: $userId, limit: $limit, cursor: $cursor) {
-      posts {
-        id
-        content
-        codeSnippet
-        language
-        tags
-        createdAt
-        likesCount
-        commentsCount
-        viewsCount
-        upvotesCount
+  query UserPosts($userId: ID!, $first: Int, $after: String) {
+    userPosts(userId: $userId, first: $first, after: $after) {
+      edges {
+        cursor
+        node {
+          id
+          content
+          codeSnippet
+          language
+          tags
+          createdAt
+          likesCount
+          commentsCount
+          viewsCount
+          upvotesCount
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
       }
-      hasMore
-      cursor
     }
   }
 `
@@ -152,26 +162,35 @@ export const SEARCH_POSTS_QUERY = gql`
 // ========== Reel Queries ==========
 
 export const REELS_QUERY = gql`
-  query Reels($limit: Int, $cursor: ID) {
-    reels(limit: $limit, cursor: $cursor) {
-      id
-      title
-      description
-      videoUrl
-      thumbnailUrl
-      duration
-      tags
-      createdAt
-      likesCount
-      commentsCount
-      viewsCount
-      viewerLiked
-      author {
-        id
-        username
-        displayName
-        avatarUrl
-        reputation
+  query Reels($first: Int, $after: String) {
+    reels(first: $first, after: $after) {
+      edges {
+        cursor
+        node {
+          id
+          title
+          description
+          videoUrl
+          thumbnailUrl
+          duration
+          tags
+          createdAt
+          likesCount
+          commentsCount
+          viewsCount
+          viewerLiked
+          author {
+            id
+            username
+            displayName
+            avatarUrl
+            reputation
+          }
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
       }
     }
   }
@@ -275,29 +294,38 @@ export const SEARCH_USERS_QUERY = gql`
 // ========== Comment Queries ==========
 
 export const COMMENTS_QUERY = gql`
-  query Comments($postId: ID, $reelId: ID, $limit: Int) {
-    comments(postId: $postId, reelId: $reelId, limit: $limit) {
-      id
-      content
-      createdAt
-      likesCount
-      author {
-        id
-        username
-        displayName
-        avatarUrl
-      }
-      replies {
-        id
-        content
-        createdAt
-        author {
+  query Comments($postId: ID, $reelId: ID, $first: Int, $after: String) {
+    comments(postId: $postId, reelId: $reelId, first: $first, after: $after) {
+      edges {
+        cursor
+        node {
           id
-          username
-          displayName
-          avatarUrl
+          content
+          createdAt
+          likesCount
+          author {
+            id
+            username
+            displayName
+            avatarUrl
+          }
+          replies {
+            id
+            content
+            createdAt
+            author {
+              id
+              username
+              displayName
+              avatarUrl
+            }
+          }
         }
       }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
     }
   }
 `
@@ -360,48 +388,75 @@ export const ADMIN_STATS_QUERY = gql`
 `
 
 export const ADMIN_USERS_QUERY = gql`
-  query AdminUsers($limit: Int, $cursor: ID, $filter: String) {
-    adminUsers(limit: $limit, cursor: $cursor, filter: $filter) {
-      id
-      username
-      email
-      displayName
-      reputation
-      isAdmin
-      bannedUntil
-      createdAt
+  query AdminUsers($first: Int, $after: String, $filter: String) {
+    adminUsers(first: $first, after: $after, filter: $filter) {
+      edges {
+        cursor
+        node {
+          id
+          username
+          email
+          displayName
+          reputation
+          isAdmin
+          bannedUntil
+          createdAt
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
     }
   }
 `
 
 export const ADMIN_POSTS_QUERY = gql`
-  query AdminPosts($limit: Int, $cursor: ID, $filter: String) {
-    adminPosts(limit: $limit, cursor: $cursor, filter: $filter) {
-      id
-      content
-      author {
-        id
-        username
+  query AdminPosts($first: Int, $after: String, $filter: String) {
+    adminPosts(first: $first, after: $after, filter: $filter) {
+      edges {
+        cursor
+        node {
+          id
+          content
+          author {
+            id
+            username
+          }
+          createdAt
+          likesCount
+          commentsCount
+          viewsCount
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
       }
-      createdAt
-      likesCount
-      commentsCount
-      viewsCount
     }
   }
 `
 
 export const ADMIN_MODERATION_LOGS_QUERY = gql`
-  query AdminModerationLogs($limit: Int) {
-    adminModerationLogs(limit: $limit) {
-      id
-      action
-      targetType
-      targetId
-      reason
-      createdAt
-      admin {
-        username
+  query AdminModerationLogs($first: Int, $after: String) {
+    adminModerationLogs(first: $first, after: $after) {
+      edges {
+        cursor
+        node {
+          id
+          action
+          targetType
+          targetId
+          reason
+          createdAt
+          admin {
+            username
+          }
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
       }
     }
   }
@@ -30,8 +30,8 @@ export const POST_LIKED_SUBSCRIPTION = gql`
 // ========== Notification Subscriptions ==========
 
 export const NOTIFICATION_RECEIVED_SUBSCRIPTION = gql`
-  subscription NotificationReceived($userId: ID!) {
-    notificationReceived(userId: $userId) {
+  subscription NotificationReceived {
+    notificationReceived {
       id
       type
       content